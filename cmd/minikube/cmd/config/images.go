@@ -17,27 +17,50 @@ limitations under the License.
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"k8s.io/minikube/pkg/minikube/assets"
+	bsimages "k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
 )
 
+var (
+	addonImagesOutput     string
+	addonImagesRepository string
+)
+
 var addonsImagesCmd = &cobra.Command{
-	Use:     "images ADDON_NAME",
-	Short:   "List image names the addon w/ADDON_NAME used. For a list of available addons use: minikube addons list",
-	Long:    "List image names the addon w/ADDON_NAME used. For a list of available addons use: minikube addons list",
-	Example: "minikube addons images ingress",
+	Use:   "images ADDON_NAME",
+	Short: "List image names the addon w/ADDON_NAME used. For a list of available addons use: minikube addons list",
+	Long:  "List image names the addon w/ADDON_NAME used. For a list of available addons use: minikube addons list",
+	Example: `minikube addons images ingress
+
+minikube addons images ingress --output=list --image-repository=registry.corp`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			exit.Message(reason.Usage, "usage: minikube addons images ADDON_NAME")
 		}
 
 		addon := args[0]
+		switch addonImagesOutput {
+		case "table":
+		case "list":
+			imgs, err := bsimages.AddonImages(addon, addonImagesRepository)
+			if err != nil {
+				exit.Error(reason.Usage, "Failed to list addon images", err)
+			}
+			fmt.Println(strings.Join(imgs, "\n"))
+			return
+		default:
+			exit.Message(reason.Usage, "invalid output format: {{.output}}. Valid values: 'table', 'list'", out.V{"output": addonImagesOutput})
+		}
+
 		// allows for additional prompting of information when enabling addons
 		if conf, ok := assets.Addons[addon]; ok {
 			if conf.Images != nil {
@@ -66,5 +89,7 @@ var addonsImagesCmd = &cobra.Command{
 }
 
 func init() {
+	addonsImagesCmd.Flags().StringVarP(&addonImagesOutput, "output", "o", "table", "minikube addons images --output OUTPUT. table, list. list prints the full reference of every image, to pull or mirror them ahead of time")
+	addonsImagesCmd.Flags().StringVar(&addonImagesRepository, "image-repository", "", "Mirror the images are listed from with --output=list, instead of their default registries")
 	AddonsCmd.AddCommand(addonsImagesCmd)
 }
@@ -17,6 +17,9 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/url"
 	"os"
@@ -24,12 +27,17 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/detect"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/image"
 	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
 	docker "k8s.io/minikube/third_party/go-dockerclient"
 )
@@ -55,6 +63,14 @@ var (
 	buildEnv   []string
 	buildOpt   []string
 	format     string
+	required   bool
+
+	diffFormat        string
+	auditFormat       string
+	planFormat        string
+	previewRepository string
+	previewVersion    string
+	previewCNI        string
 )
 
 func saveFile(r io.Reader) (string, error) {
@@ -324,9 +340,39 @@ var listImageCmd = &cobra.Command{
 	Short: "List images",
 	Example: `
 $ minikube image ls
+
+$ minikube image ls --required
+
+$ minikube image ls --required --format=hash
+
+$ minikube image ls --required --format=kustomize
 `,
 	Aliases: []string{"list"},
 	Run: func(cmd *cobra.Command, args []string) {
+		if required {
+			if format == "kustomize" {
+				block, err := requiredKustomizeImages(viper.GetString(config.ProfileName))
+				if err != nil {
+					exit.Error(reason.Usage, "Failed to list required images", err)
+				}
+				fmt.Print(string(block))
+				return
+			}
+			if format != "short" && format != "hash" {
+				exit.Message(reason.Usage, "--required only supports --format=short|hash|kustomize")
+			}
+			imgs, err := images.ImagesForProfile(viper.GetString(config.ProfileName))
+			if err != nil {
+				exit.Error(reason.Usage, "Failed to list required images", err)
+			}
+			if format == "hash" {
+				fmt.Println(images.ImageSetHash(imgs))
+				return
+			}
+			fmt.Println(strings.Join(imgs, "\n"))
+			return
+		}
+
 		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
 		if err != nil {
 			exit.Error(reason.Usage, "loading profile", err)
@@ -338,6 +384,246 @@ $ minikube image ls
 	},
 }
 
+var verifyImageCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the image repository has every image the cluster requires",
+	Long:  "Verify the image repository has every image the cluster requires for its Kubernetes version and image settings, listing the missing images.",
+	Example: `
+$ minikube image verify
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		imgs, err := images.ImagesForProfile(viper.GetString(config.ProfileName))
+		if err != nil {
+			exit.Error(reason.Usage, "Failed to list required images", err)
+		}
+		missing, err := images.VerifyImagesExist(context.Background(), imgs)
+		if err != nil {
+			exit.Error(reason.InetRepo, "Failed to verify required images", err)
+		}
+		if len(missing) > 0 {
+			fmt.Println(strings.Join(missing, "\n"))
+			exit.Message(reason.InetRepo, "{{.missing}} of the {{.required}} required images are missing from the image repository", out.V{"missing": len(missing), "required": len(imgs)})
+		}
+	},
+}
+
+// requiredKustomizeImages returns the images block of a kustomization file rewriting the upstream images
+// to the images required by the cluster of profile, with the image settings recorded in its config
+func requiredKustomizeImages(profile string) ([]byte, error) {
+	cc, err := config.Load(profile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load profile %s", profile)
+	}
+	v, err := images.ClusterVersion(*cc)
+	if err != nil {
+		return nil, err
+	}
+	if err := images.Configure(*cc); err != nil {
+		return nil, errors.Wrapf(err, "profile %s", profile)
+	}
+	return images.KustomizeImagesYAML(cc.KubernetesConfig.ImageRepository, v, images.ClusterImageOptions(*cc))
+}
+
+var diffImageCmd = &cobra.Command{
+	Use:   "diff FROM_VERSION TO_VERSION",
+	Short: "Show how the required images change between two Kubernetes versions",
+	Long:  "Show the required images added, removed and retagged between two Kubernetes versions, so that a mirror can be updated ahead of an upgrade. The image settings of the profile are used if it exists.",
+	Example: `
+$ minikube image diff v1.20.0 v1.21.0
+
+$ minikube image diff v1.23.0 stable --format=json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			exit.Message(reason.Usage, "Please provide the Kubernetes versions to compare")
+		}
+		if diffFormat != "short" && diffFormat != "json" {
+			exit.Message(reason.Usage, "invalid output format: {{.format}}. Valid values: 'short', 'json'", out.V{"format": diffFormat})
+		}
+		diff, err := diffRequiredImages(viper.GetString(config.ProfileName), args[0], args[1])
+		if err != nil {
+			exit.Error(reason.Usage, "Failed to compare required images", err)
+		}
+		if diffFormat == "json" {
+			b, err := json.Marshal(diff)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal image diff", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		for _, img := range diff.Removed {
+			fmt.Printf("- %s\n", img)
+		}
+		for _, img := range diff.Added {
+			fmt.Printf("+ %s\n", img)
+		}
+		for _, r := range diff.Retagged {
+			fmt.Printf("~ %s -> %s\n", r.From, r.To)
+		}
+	},
+}
+
+// diffRequiredImages returns how the required images change from Kubernetes version from to version to,
+// with the image settings recorded in the config of profile if it exists
+func diffRequiredImages(profile string, from string, to string) (images.ImageDiff, error) {
+	fromVersion, err := images.ResolveVersion(from)
+	if err != nil {
+		return images.ImageDiff{}, err
+	}
+	toVersion, err := images.ResolveVersion(to)
+	if err != nil {
+		return images.ImageDiff{}, err
+	}
+	repo := ""
+	opts := images.ImageOptions{}
+	cc, err := config.Load(profile)
+	switch {
+	case err == nil:
+		if err := images.Configure(*cc); err != nil {
+			return images.ImageDiff{}, errors.Wrapf(err, "profile %s", profile)
+		}
+		repo = cc.KubernetesConfig.ImageRepository
+		opts = images.ClusterImageOptions(*cc)
+	case !config.IsNotExist(err):
+		return images.ImageDiff{}, errors.Wrapf(err, "load profile %s", profile)
+	}
+	return images.DiffVersions(repo, fromVersion, toVersion, opts)
+}
+
+var previewImageCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Preview the images a cluster would require from an image repository, without network access",
+	Long:  "Preview the images a cluster would require for a Kubernetes version, image repository and CNI, without network access, to inspect how the image repository rewrites them before starting a cluster with it.",
+	Example: `
+$ minikube image preview --image-repository=registry.corp
+
+$ minikube image preview --image-repository=registry.corp --kubernetes-version=v1.22.0 --cni=calico
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		v, err := images.ResolveVersion(previewVersion)
+		if err != nil {
+			exit.Error(reason.Usage, "Invalid --kubernetes-version", err)
+		}
+		imgs, err := images.PreviewImages(previewRepository, v, previewCNI)
+		if err != nil {
+			exit.Error(reason.Usage, "Failed to preview required images", err)
+		}
+		fmt.Println(strings.Join(imgs, "\n"))
+	},
+}
+
+var auditImageCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Report the role and registry status of every image the cluster requires",
+	Long:  "Report, for every image the cluster requires, its role, the upstream image it is mirrored from and whether the image repository serves it, with its digest. Only checking the images reaches the network, which is skipped with --offline.",
+	Example: `
+$ minikube image audit
+
+$ minikube image audit --format=json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if auditFormat != "table" && auditFormat != "json" {
+			exit.Message(reason.Usage, "invalid output format: {{.format}}. Valid values: 'table', 'json'", out.V{"format": auditFormat})
+		}
+		audit, err := auditRequiredImages(viper.GetString(config.ProfileName))
+		if err != nil {
+			exit.Error(reason.InetRepo, "Failed to audit required images", err)
+		}
+		if auditFormat == "json" {
+			b, err := json.Marshal(audit)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal image audit", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Image", "Upstream", "Role", "Status", "Digest"})
+		table.SetAutoFormatHeaders(true)
+		table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+		table.SetCenterSeparator("|")
+		for _, img := range audit.Images {
+			status := string(img.Status)
+			if img.Error != "" {
+				status += ": " + img.Error
+			}
+			table.Append([]string{img.Image, img.Upstream, string(img.Role), status, img.Digest})
+		}
+		table.Render()
+	},
+}
+
+// auditRequiredImages returns the audit of the images required by the cluster of profile, with the image settings recorded in its config
+func auditRequiredImages(profile string) (images.ImageAudit, error) {
+	cc, err := config.Load(profile)
+	if err != nil {
+		return images.ImageAudit{}, errors.Wrapf(err, "load profile %s", profile)
+	}
+	v, err := images.ClusterVersion(*cc)
+	if err != nil {
+		return images.ImageAudit{}, err
+	}
+	if err := images.Configure(*cc); err != nil {
+		return images.ImageAudit{}, errors.Wrapf(err, "profile %s", profile)
+	}
+	return images.AuditImages(context.Background(), cc.KubernetesConfig.ImageRepository, v, images.ClusterImageOptions(*cc))
+}
+
+var planImageCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show how starting the cluster would obtain every image it requires",
+	Long:  "Show how starting the cluster would obtain every image it requires: from the image cache, its upstream repository or a mirror. --format=schema prints the JSON schema of --format=json, for tooling to validate the plan against.",
+	Example: `
+$ minikube image plan
+
+$ minikube image plan --format=json
+
+$ minikube image plan --format=schema
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		switch planFormat {
+		case "schema":
+			fmt.Print(string(images.ImagePlanSchema()))
+			return
+		case "short", "json":
+		default:
+			exit.Message(reason.Usage, "invalid output format: {{.format}}. Valid values: 'short', 'json', 'schema'", out.V{"format": planFormat})
+		}
+		plan, err := planRequiredImages(viper.GetString(config.ProfileName))
+		if err != nil {
+			exit.Error(reason.Usage, "Failed to plan required images", err)
+		}
+		if planFormat == "json" {
+			b, err := json.Marshal(plan)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to marshal image plan", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+		for _, img := range plan.Images {
+			fmt.Printf("%s: %s\n", img.Image, img.Status)
+		}
+	},
+}
+
+// planRequiredImages returns the plan of the images required by the cluster of profile, with the image settings recorded in its config
+func planRequiredImages(profile string) (images.ImagePlan, error) {
+	cc, err := config.Load(profile)
+	if err != nil {
+		return images.ImagePlan{}, errors.Wrapf(err, "load profile %s", profile)
+	}
+	v, err := images.ClusterVersion(*cc)
+	if err != nil {
+		return images.ImagePlan{}, err
+	}
+	if err := images.Configure(*cc); err != nil {
+		return images.ImagePlan{}, errors.Wrapf(err, "profile %s", profile)
+	}
+	return images.PlanImages(cc.KubernetesConfig.ImageRepository, v, images.ClusterImageOptions(*cc), detect.ImageCacheDir())
+}
+
 var tagImageCmd = &cobra.Command{
 	Use:   "tag",
 	Short: "Tag images",
@@ -397,8 +683,20 @@ func init() {
 	saveImageCmd.Flags().BoolVar(&imgDaemon, "daemon", false, "Cache image to docker daemon")
 	saveImageCmd.Flags().BoolVar(&imgRemote, "remote", false, "Cache image to remote registry")
 	imageCmd.AddCommand(saveImageCmd)
-	listImageCmd.Flags().StringVar(&format, "format", "short", "Format output. One of: short|table|json|yaml")
+	listImageCmd.Flags().StringVar(&format, "format", "short", "Format output. One of: short|table|json|yaml. With --required, one of: short|hash|kustomize, hash printing a stable SHA256 digest of the required images, suitable as a cache key, and kustomize the images block of a kustomization file rewriting the upstream images to the required ones")
+	listImageCmd.Flags().BoolVar(&required, "required", false, "List the images the cluster requires for its Kubernetes version and image settings instead of the images on its nodes")
 	imageCmd.AddCommand(listImageCmd)
+	imageCmd.AddCommand(verifyImageCmd)
+	diffImageCmd.Flags().StringVar(&diffFormat, "format", "short", "Format output. One of: short|json")
+	imageCmd.AddCommand(diffImageCmd)
+	previewImageCmd.Flags().StringVar(&previewRepository, "image-repository", "", "Image repository the images would be pulled from, as given to minikube start --image-repository")
+	previewImageCmd.Flags().StringVar(&previewVersion, "kubernetes-version", "stable", "Kubernetes version the images are required for, or stable or latest")
+	previewImageCmd.Flags().StringVar(&previewCNI, "cni", "", "CNI plug-in whose images are required too, as given to minikube start --cni")
+	imageCmd.AddCommand(previewImageCmd)
+	auditImageCmd.Flags().StringVar(&auditFormat, "format", "table", "Format output. One of: table|json")
+	imageCmd.AddCommand(auditImageCmd)
+	planImageCmd.Flags().StringVar(&planFormat, "format", "short", "Format output. One of: short|json|schema")
+	imageCmd.AddCommand(planImageCmd)
 	imageCmd.AddCommand(tagImageCmd)
 	imageCmd.AddCommand(pushImageCmd)
 }
@@ -33,6 +33,7 @@ import (
 	configCmd "k8s.io/minikube/cmd/minikube/cmd/config"
 	"k8s.io/minikube/pkg/drivers/kic/oci"
 	"k8s.io/minikube/pkg/minikube/audit"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/detect"
@@ -78,6 +79,8 @@ var RootCmd = &cobra.Command{
 		if viper.GetBool(config.Rootless) {
 			os.Setenv(constants.MinikubeRootlessEnv, "true")
 		}
+		images.SetOffline(viper.GetBool(config.Offline))
+		images.SetPinnedOnly(viper.GetBool(config.PinnedVersionsOnly))
 	},
 }
 
@@ -213,6 +216,8 @@ func init() {
 	RootCmd.PersistentFlags().StringP(configCmd.Bootstrapper, "b", "kubeadm", "The name of the cluster bootstrapper that will set up the Kubernetes cluster.")
 	RootCmd.PersistentFlags().String(config.UserFlag, "", "Specifies the user executing the operation. Useful for auditing operations executed by 3rd party tools. Defaults to the operating system username.")
 	RootCmd.PersistentFlags().Bool(config.Rootless, false, "Force to use rootless driver (docker and podman driver only)")
+	RootCmd.PersistentFlags().Bool(config.Offline, false, "Never look up the latest image versions in registries, using the versions known to this release instead")
+	RootCmd.PersistentFlags().Bool(config.PinnedVersionsOnly, false, "Forbid resolving image versions dynamically by policy, using only the versions pinned by this release or by flags. Every suppressed lookup is logged.")
 
 	groups := templates.CommandGroups{
 		{
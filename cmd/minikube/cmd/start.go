@@ -35,6 +35,7 @@ import (
 	"github.com/Delta456/box-cli-maker/v2"
 	"github.com/blang/semver/v4"
 	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -136,15 +137,32 @@ func platform() string {
 	return s.String()
 }
 
+// startImageTrace writes the decisions made resolving the images of the cluster to the file minikube logs includes,
+// as they are made so that failed starts keep it. Without --trace-images, the trace of a previous start is removed.
+func startImageTrace() {
+	if !viper.GetBool(traceImages) {
+		if err := os.Remove(localpath.ImageTraceLog()); err != nil && !os.IsNotExist(err) {
+			klog.Warningf("unable to remove the previous image resolution trace: %v", err)
+		}
+		return
+	}
+	images.SetTracing(true)
+	if err := images.SetTraceFile(localpath.ImageTraceLog()); err != nil {
+		klog.Warningf("unable to write the image resolution trace: %v", err)
+	}
+}
+
 // runStart handles the executes the flow of "minikube start"
 func runStart(cmd *cobra.Command, args []string) {
 	register.SetEventLogPath(localpath.EventLog(ClusterFlagValue()))
-	ctx := context.Background()
+	ctx := cmd.Context()
+	images.SetLookupContext(ctx)
 	out.SetJSON(outputFormat == "json")
 	if err := pkgtrace.Initialize(viper.GetString(trace)); err != nil {
 		exit.Message(reason.Usage, "error initializing tracing: {{.Error}}", out.V{"Error": err.Error()})
 	}
 	defer pkgtrace.Cleanup()
+	startImageTrace()
 	displayVersion(version.GetVersion())
 	go download.CleanUpOlderPreloads()
 
@@ -319,6 +337,7 @@ func provisionWithDriver(cmd *cobra.Command, ds registry.DriverState, existing *
 
 	// This is about as far as we can go without overwriting config files
 	if viper.GetBool(dryRun) {
+		showImagePlan(cc)
 		out.Step(style.DryRun, `dry-run validation complete!`)
 		os.Exit(0)
 	}
@@ -450,6 +469,40 @@ func displayEnviron(env []string) {
 	}
 }
 
+// showImagePlan reports which images the cluster would load from the cache and which it would pull
+func showImagePlan(cc config.ClusterConfig) {
+	if cc.KubernetesConfig.KubernetesVersion == constants.NoKubernetesVersion {
+		return
+	}
+	v, err := util.ParseKubernetesVersion(cc.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		klog.Warningf("unable to plan images: %v", err)
+		return
+	}
+	node.ConfigureImages(cc)
+	plan, err := images.PlanImages(cc.KubernetesConfig.ImageRepository, v, images.ImageOptions{Gvisor: cc.Addons["gvisor"], MetricsServer: cc.Addons["metrics-server"]}, detect.ImageCacheDir())
+	if err != nil {
+		klog.Warningf("unable to plan images: %v", err)
+		return
+	}
+	out.Step(style.Caching, "Images required by Kubernetes {{.version}}:", out.V{"version": cc.KubernetesConfig.KubernetesVersion})
+	pulls := []string{}
+	for _, img := range plan.Images {
+		out.Infof("{{.image}}: {{.status}}", out.V{"image": img.Image, "status": img.Status})
+		if img.Status != images.PlanCached {
+			pulls = append(pulls, img.Image)
+		}
+	}
+	size, err := images.EstimatedDownloadSize(pulls, false)
+	if err != nil {
+		klog.Warningf("unable to estimate the download size of images: %v", err)
+		return
+	}
+	if size > 0 {
+		out.Infof("Pulling the uncached images downloads about {{.size}}", out.V{"size": units.HumanSize(float64(size))})
+	}
+}
+
 func showKubectlInfo(kcs *kubeconfig.Settings, k8sVersion, rtime, machineName string) error {
 	if k8sVersion == constants.NoKubernetesVersion {
 		register.Reg.SetStep(register.Done)
@@ -1173,6 +1226,57 @@ func validateFlags(cmd *cobra.Command, drvName string) {
 	if cmd.Flags().Changed(imageRepository) {
 		viper.Set(imageRepository, validateImageRepository(viper.GetString(imageRepository)))
 	}
+
+	if cmd.Flags().Changed(pauseImageRepository) {
+		if err := images.ValidateMirror(viper.GetString(pauseImageRepository)); err != nil {
+			exit.Message(reason.Usage, "Invalid --pause-image-repository: {{.err}}", out.V{"err": err})
+		}
+	}
+
+	if cmd.Flags().Changed(allowedRegistries) || cmd.Flags().Changed(deniedRegistries) {
+		if err := images.SetRegistryPolicy(viper.GetStringSlice(allowedRegistries), viper.GetStringSlice(deniedRegistries)); err != nil {
+			exit.Message(reason.Usage, "Invalid registry policy: {{.err}}", out.V{"err": err})
+		}
+	}
+
+	for _, flag := range []string{pauseImageVersion, etcdVersion, coreDNSVersion} {
+		if cmd.Flags().Changed(flag) {
+			if err := images.ValidateTag(viper.GetString(flag)); err != nil {
+				exit.Message(reason.Usage, "Invalid --{{.flag}}: {{.err}}", out.V{"flag": flag, "err": err})
+			}
+		}
+	}
+
+	if cmd.Flags().Changed(registryClientCert) || cmd.Flags().Changed(registryClientKey) || cmd.Flags().Changed(registryCACert) {
+		if err := images.SetRegistryTLS(viper.GetString(registryClientCert), viper.GetString(registryClientKey), viper.GetString(registryCACert)); err != nil {
+			exit.Message(reason.Usage, "Invalid image repository TLS flags: {{.err}}", out.V{"err": err})
+		}
+	}
+	if cmd.Flags().Changed(registryDockerConfig) {
+		if _, err := os.Stat(viper.GetString(registryDockerConfig)); err != nil {
+			exit.Message(reason.Usage, "Invalid --{{.flag}}: {{.err}}", out.V{"flag": registryDockerConfig, "err": err})
+		}
+	}
+	if cmd.Flags().Changed(storageProvisionerImage) {
+		if err := images.SetStorageProvisionerImage(viper.GetString(storageProvisionerImage)); err != nil {
+			exit.Message(reason.Usage, "Invalid --storage-provisioner-image: {{.err}}", out.V{"err": err})
+		}
+	}
+	if cmd.Flags().Changed(registryTimeout) {
+		if err := images.SetTagLookupTimeout(viper.GetDuration(registryTimeout)); err != nil {
+			exit.Message(reason.Usage, "Invalid --registry-timeout: {{.err}}", out.V{"err": err})
+		}
+	}
+	if cmd.Flags().Changed(registryRateLimit) {
+		if err := images.SetRegistryRateLimit(viper.GetFloat64(registryRateLimit)); err != nil {
+			exit.Message(reason.Usage, "Invalid --registry-rate-limit: {{.err}}", out.V{"err": err})
+		}
+	}
+	if cmd.Flags().Changed(imageCompression) {
+		if err := images.SetCompression(viper.GetString(imageCompression)); err != nil {
+			exit.Message(reason.Usage, "Invalid --image-compression: {{.err}}", out.V{"err": err})
+		}
+	}
 	if cmd.Flags().Changed(ports) {
 		err := validatePorts(viper.GetStringSlice(ports))
 		if err != nil {
@@ -1587,6 +1691,10 @@ func validateKubernetesVersion(old *config.ClusterConfig) {
 	if nvs.GT(newestVersion) {
 		out.WarningT("Specified Kubernetes version {{.specified}} is newer than the newest supported version: {{.newest}}. Use `minikube config defaults kubernetes-version` for details.", out.V{"specified": nvs, "newest": constants.NewestKubernetesVersion})
 	}
+	if err := images.CheckKnownVersion(nvs); err != nil && nvs.GTE(oldestVersion) {
+		out.WarningT("Unable to determine the images of Kubernetes {{.version}} from this release of minikube: {{.error}}. Their versions will be looked up in the image repository.", out.V{"version": nvs, "error": err})
+		images.SetAllowUnknownVersions(true)
+	}
 	if nvs.LT(oldestVersion) {
 		out.WarningT("Specified Kubernetes version {{.specified}} is less than the oldest supported version: {{.oldest}}. Use `minikube config defaults kubernetes-version` for details.", out.V{"specified": nvs, "oldest": constants.OldestKubernetesVersion})
 		if !viper.GetBool(force) {
@@ -31,6 +31,7 @@ import (
 	"k8s.io/minikube/pkg/drivers/kic/oci"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil/kverify"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/cni"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
@@ -81,6 +82,28 @@ const (
 	dnsDomain               = "dns-domain"
 	serviceCIDR             = "service-cluster-ip-range"
 	imageRepository         = "image-repository"
+	pauseImageVersion       = "pause-image-version"
+	pauseImageRepository    = "pause-image-repository"
+	etcdVersion             = "etcd-version"
+	coreDNSVersion          = "coredns-version"
+	coreDNSFlatPath         = "coredns-flat-path"
+	coreDNSLatestPatch      = "coredns-latest-patch"
+	registryClientCert      = "registry-client-cert"
+	registryClientKey       = "registry-client-key"
+	registryCACert          = "registry-ca-cert"
+	registryDockerConfig    = "registry-docker-config"
+	imageDigests            = "image-digests"
+	storageProvisionerImage = "storage-provisioner-image"
+	registryTimeout         = "registry-timeout"
+	registryRateLimit       = "registry-rate-limit"
+	pinnedImageVersions     = "pinned-image-versions"
+	imageTagCacheTTL        = "image-tag-cache-ttl"
+	preReleaseImageTags     = "pre-release-image-tags"
+	strictImages            = "strict-images"
+	imageCompression        = "image-compression"
+	imagesFromFile          = "images-from-file"
+	allowedRegistries       = "allowed-registries"
+	deniedRegistries        = "denied-registries"
 	imageMirrorCountry      = "image-mirror-country"
 	mountString             = "mount-string"
 	mount9PVersion          = "mount-9p-version"
@@ -124,6 +147,7 @@ const (
 	subnet                  = "subnet"
 	startNamespace          = "namespace"
 	trace                   = "trace"
+	traceImages             = "trace-images"
 	sshIPAddress            = "ssh-ip-address"
 	sshSSHUser              = "ssh-user"
 	sshSSHKey               = "ssh-key"
@@ -192,6 +216,7 @@ func initMinikubeFlags() {
 	startCmd.Flags().StringP(network, "", "", "network to run minikube with. Now it is used by docker/podman and KVM drivers. If left empty, minikube will create a new network.")
 	startCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
 	startCmd.Flags().StringP(trace, "", "", "Send trace events. Options include: [gcp]")
+	startCmd.Flags().Bool(traceImages, false, "Record every decision made resolving the images of the cluster, such as the tags looked up or pinned, to a file included by 'minikube logs'.")
 	startCmd.Flags().Int(extraDisks, 0, "Number of extra disks created and attached to the minikube VM (currently only implemented for hyperkit and kvm2 drivers)")
 	startCmd.Flags().Duration(certExpiration, constants.DefaultCertExpiration, "Duration until minikube certificate expiration, defaults to three years (26280h).")
 	startCmd.Flags().String(binaryMirror, "", "Location to fetch kubectl, kubelet, & kubeadm binaries from.")
@@ -264,6 +289,28 @@ func initNetworkingFlags() {
 	startCmd.Flags().StringSliceVar(&insecureRegistry, "insecure-registry", nil, "Insecure Docker registries to pass to the Docker daemon.  The default service CIDR range will automatically be added.")
 	startCmd.Flags().StringSliceVar(&registryMirror, "registry-mirror", nil, "Registry mirrors to pass to the Docker daemon")
 	startCmd.Flags().String(imageRepository, "", "Alternative image repository to pull docker images from. This can be used when you have limited access to gcr.io. Set it to \"auto\" to let minikube decide one for you. For Chinese mainland users, you may use local gcr.io mirrors such as registry.cn-hangzhou.aliyuncs.com/google_containers")
+	startCmd.Flags().String(pauseImageVersion, "", "Override the pause image version used by the cluster (ex: 3.6). Defaults to the version matching the Kubernetes version.")
+	startCmd.Flags().String(pauseImageRepository, "", "Alternative image repository to pull only the pause image from (ex: registry.k8s.io). Defaults to --image-repository, or the repository matching the Kubernetes version.")
+	startCmd.Flags().String(etcdVersion, "", "Override the etcd image version used by the cluster (ex: 3.5.3-0). Defaults to the version matching the Kubernetes version.")
+	startCmd.Flags().String(coreDNSVersion, "", "Override the coredns image version used by the cluster (ex: v1.8.6). Defaults to the version matching the Kubernetes version.")
+	startCmd.Flags().Bool(coreDNSFlatPath, false, "Pull coredns from <image-repository>/coredns rather than <image-repository>/coredns/coredns, for image repositories which don't support nested paths.")
+	startCmd.Flags().Bool(coreDNSLatestPatch, false, "Pull the latest patch release of the coredns minor version matching the Kubernetes version, rather than the exact version. Ignored if --coredns-version is set.")
+	startCmd.Flags().String(registryClientCert, "", "Path to a client certificate presented to the image repository when looking up image versions. Requires --registry-client-key.")
+	startCmd.Flags().String(registryClientKey, "", "Path to the key of --registry-client-cert.")
+	startCmd.Flags().String(registryCACert, "", "Path to a CA bundle trusted when looking up image versions in the image repository.")
+	startCmd.Flags().String(registryDockerConfig, "", "Path to a docker config.json holding the credentials used to look up image versions in the image repository and check its images. Defaults to ~/.docker/config.json.")
+	startCmd.Flags().String(imageDigests, "", "Path to a JSON file mapping image repositories to the digest to pin their image to (ex: {\"k8s.gcr.io/etcd\": \"sha256:...\"}). Images without a digest use their tag.")
+	startCmd.Flags().String(storageProvisionerImage, "", "Replaces the storage-provisioner image pulled for the cluster, including its repository (ex: myrepo/storage-provisioner:v1). Use --addon-images to change the image the storage-provisioner addon deploys.")
+	startCmd.Flags().Duration(registryTimeout, 0, "How long every image version lookup in the image repository may take before falling back to the known good version (ex: 30s). Defaults to 10s when 0.")
+	startCmd.Flags().Float64(registryRateLimit, 0, "How many requests per second looking up and checking images may send to each registry host, to stay below the rate limits of registries such as Docker Hub. Defaults to 10 when 0.")
+	startCmd.Flags().Duration(imageTagCacheTTL, 0, "How long the latest image versions looked up in the image repository are cached for before being looked up again (ex: 1h). Defaults to 24h when 0, a negative duration disables the cache.")
+	startCmd.Flags().Bool(preReleaseImageTags, false, "Allow pre-release versions, such as v1.9.0-beta.1, to be picked when looking up the latest image versions in the image repository.")
+	startCmd.Flags().Bool(pinnedImageVersions, false, "Use only the image versions pinned by this minikube release or by flags for this profile, never looking up the latest version in the image repository. The pinned-versions-only config does the same for every profile.")
+	startCmd.Flags().Bool(strictImages, false, "Fail before caching or pulling any image if a required image has no explicit version tag, or is tagged latest.")
+	startCmd.Flags().String(imageCompression, "", "Layer compression to ask the image repository for when checking and caching images, gzip or zstd. Leave empty to let the image repository choose.")
+	startCmd.Flags().String(imagesFromFile, "", "Path to a file listing, one per line, every image to cache and pull for the cluster instead of the images minikube computes. Lines starting with # are ignored.")
+	startCmd.Flags().StringSlice(allowedRegistries, nil, "Registries, as host[:port], the only ones required images may be hosted on (ex: registry.corp,k8s.gcr.io). Start fails if an image is hosted elsewhere, configure --image-repository to mirror it instead.")
+	startCmd.Flags().StringSlice(deniedRegistries, nil, "Registries, as host[:port], required images may never be hosted on, even if also given to --allowed-registries (ex: docker.io). Start fails if an image is hosted on one.")
 	startCmd.Flags().String(imageMirrorCountry, "", "Country code of the image mirror to be used. Leave empty to use the global one. For Chinese mainland users, set it to cn.")
 	startCmd.Flags().String(serviceCIDR, constants.DefaultServiceCIDR, "The CIDR to be used for service cluster IPs.")
 	startCmd.Flags().StringArrayVar(&config.DockerEnv, "docker-env", nil, "Environment variables to pass to the Docker daemon. (format: key=value)")
@@ -441,6 +488,35 @@ func getRepository(cmd *cobra.Command, k8sVersion string) string {
 	return repository
 }
 
+// getImageList returns the images of the --images-from-file file, if any
+func getImageList() []string {
+	path := viper.GetString(imagesFromFile)
+	if path == "" {
+		return nil
+	}
+	imgs, err := images.LoadImageList(path)
+	if err != nil {
+		exit.Message(reason.Usage, "Invalid --images-from-file: {{.err}}", out.V{"err": err})
+	}
+	return imgs
+}
+
+// getImageDigests returns the image digests of the --image-digests file, if any
+func getImageDigests() map[string]string {
+	path := viper.GetString(imageDigests)
+	if path == "" {
+		return nil
+	}
+	digests, err := images.LoadImageDigests(path)
+	if err != nil {
+		exit.Message(reason.Usage, "Failed to load --image-digests: {{.err}}", out.V{"err": err})
+	}
+	if err := images.SetImageDigests(digests); err != nil {
+		exit.Message(reason.Usage, "Invalid --image-digests: {{.err}}", out.V{"err": err})
+	}
+	return digests
+}
+
 func getCNIConfig(cmd *cobra.Command) string {
 	// Backwards compatibility with --enable-default-cni
 	chosenCNI := viper.GetString(cniFlag)
@@ -525,6 +601,22 @@ func generateNewConfigFromFlags(cmd *cobra.Command, k8sVersion string, rtime str
 		MountType:               viper.GetString(mountTypeFlag),
 		MountUID:                viper.GetString(mountUID),
 		BinaryMirror:            viper.GetString(binaryMirror),
+		RegistryClientCert:      viper.GetString(registryClientCert),
+		RegistryClientKey:       viper.GetString(registryClientKey),
+		RegistryCACert:          viper.GetString(registryCACert),
+		RegistryDockerConfig:    viper.GetString(registryDockerConfig),
+		ImageDigests:            getImageDigests(),
+		StorageProvisionerImage: viper.GetString(storageProvisionerImage),
+		RegistryTimeout:         viper.GetDuration(registryTimeout),
+		RegistryRateLimit:       viper.GetFloat64(registryRateLimit),
+		ImageTagCacheTTL:        viper.GetDuration(imageTagCacheTTL),
+		PreReleaseImageTags:     viper.GetBool(preReleaseImageTags),
+		PinnedImageVersions:     viper.GetBool(pinnedImageVersions),
+		StrictImages:            viper.GetBool(strictImages),
+		ImageCompression:        viper.GetString(imageCompression),
+		ImagesFromFile:          getImageList(),
+		AllowedRegistries:       viper.GetStringSlice(allowedRegistries),
+		DeniedRegistries:        viper.GetStringSlice(deniedRegistries),
 		DisableOptimizations:    viper.GetBool(disableOptimizations),
 		DisableMetrics:          viper.GetBool(disableMetrics),
 		CustomQemuFirmwarePath:  viper.GetString(qemuFirmwarePath),
@@ -542,6 +634,12 @@ func generateNewConfigFromFlags(cmd *cobra.Command, k8sVersion string, rtime str
 			NetworkPlugin:          chosenNetworkPlugin,
 			ServiceCIDR:            viper.GetString(serviceCIDR),
 			ImageRepository:        getRepository(cmd, k8sVersion),
+			PauseImageVersion:      viper.GetString(pauseImageVersion),
+			PauseImageRepository:   viper.GetString(pauseImageRepository),
+			EtcdVersion:            viper.GetString(etcdVersion),
+			CoreDNSVersion:         viper.GetString(coreDNSVersion),
+			CoreDNSFlatPath:        viper.GetBool(coreDNSFlatPath),
+			CoreDNSLatestPatch:     viper.GetBool(coreDNSLatestPatch),
 			ExtraOptions:           getExtraOptions(),
 			ShouldLoadCachedImages: viper.GetBool(cacheImages),
 			CNI:                    getCNIConfig(cmd),
@@ -731,6 +829,12 @@ func updateExistingConfigFromFlags(cmd *cobra.Command, existing *config.ClusterC
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.CRISocket, criSocket)
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.NetworkPlugin, networkPlugin)
 	updateStringFromFlag(cmd, &cc.KubernetesConfig.ServiceCIDR, serviceCIDR)
+	updateStringFromFlag(cmd, &cc.KubernetesConfig.PauseImageVersion, pauseImageVersion)
+	updateStringFromFlag(cmd, &cc.KubernetesConfig.PauseImageRepository, pauseImageRepository)
+	updateStringFromFlag(cmd, &cc.KubernetesConfig.EtcdVersion, etcdVersion)
+	updateStringFromFlag(cmd, &cc.KubernetesConfig.CoreDNSVersion, coreDNSVersion)
+	updateBoolFromFlag(cmd, &cc.KubernetesConfig.CoreDNSFlatPath, coreDNSFlatPath)
+	updateBoolFromFlag(cmd, &cc.KubernetesConfig.CoreDNSLatestPatch, coreDNSLatestPatch)
 	updateBoolFromFlag(cmd, &cc.KubernetesConfig.ShouldLoadCachedImages, cacheImages)
 	updateIntFromFlag(cmd, &cc.KubernetesConfig.NodePort, apiServerPort)
 	updateDurationFromFlag(cmd, &cc.CertExpiration, certExpiration)
@@ -745,6 +849,20 @@ func updateExistingConfigFromFlags(cmd *cobra.Command, existing *config.ClusterC
 	updateStringFromFlag(cmd, &cc.MountType, mountTypeFlag)
 	updateStringFromFlag(cmd, &cc.MountUID, mountUID)
 	updateStringFromFlag(cmd, &cc.BinaryMirror, binaryMirror)
+	updateStringFromFlag(cmd, &cc.RegistryClientCert, registryClientCert)
+	updateStringFromFlag(cmd, &cc.RegistryClientKey, registryClientKey)
+	updateStringFromFlag(cmd, &cc.RegistryCACert, registryCACert)
+	updateStringFromFlag(cmd, &cc.RegistryDockerConfig, registryDockerConfig)
+	updateStringFromFlag(cmd, &cc.StorageProvisionerImage, storageProvisionerImage)
+	updateDurationFromFlag(cmd, &cc.RegistryTimeout, registryTimeout)
+	updateFloat64FromFlag(cmd, &cc.RegistryRateLimit, registryRateLimit)
+	updateDurationFromFlag(cmd, &cc.ImageTagCacheTTL, imageTagCacheTTL)
+	updateBoolFromFlag(cmd, &cc.PreReleaseImageTags, preReleaseImageTags)
+	updateBoolFromFlag(cmd, &cc.PinnedImageVersions, pinnedImageVersions)
+	updateBoolFromFlag(cmd, &cc.StrictImages, strictImages)
+	updateStringSliceFromFlag(cmd, &cc.AllowedRegistries, allowedRegistries)
+	updateStringSliceFromFlag(cmd, &cc.DeniedRegistries, deniedRegistries)
+	updateStringFromFlag(cmd, &cc.ImageCompression, imageCompression)
 	updateBoolFromFlag(cmd, &cc.DisableOptimizations, disableOptimizations)
 	updateStringFromFlag(cmd, &cc.CustomQemuFirmwarePath, qemuFirmwarePath)
 
@@ -763,6 +881,14 @@ func updateExistingConfigFromFlags(cmd *cobra.Command, existing *config.ClusterC
 		cc.KubernetesConfig.CNI = getCNIConfig(cmd)
 	}
 
+	if cmd.Flags().Changed(imageDigests) {
+		cc.ImageDigests = getImageDigests()
+	}
+
+	if cmd.Flags().Changed(imagesFromFile) {
+		cc.ImagesFromFile = getImageList()
+	}
+
 	if cmd.Flags().Changed(waitComponents) {
 		cc.VerifyComponents = interpretWaitFlag(*cmd)
 	}
@@ -821,6 +947,13 @@ func updateDurationFromFlag(cmd *cobra.Command, v *time.Duration, key string) {
 	}
 }
 
+// updateFloat64FromFlag will update the existing float64 from the flag.
+func updateFloat64FromFlag(cmd *cobra.Command, v *float64, key string) {
+	if cmd.Flags().Changed(key) {
+		*v = viper.GetFloat64(key)
+	}
+}
+
 // updateUint16FromFlag will update the existing uint16 from the flag.
 func updateUint16FromFlag(cmd *cobra.Command, v *uint16, key string) {
 	if cmd.Flags().Changed(key) {
@@ -65,24 +65,31 @@ func generateTarball(kubernetesVersion, containerRuntime, tarballFilename string
 		return errors.Wrap(err, "verifying storage")
 	}
 
-	// Now, get images to pull
-	imgs, err := images.Kubeadm("", kubernetesVersion)
+	// will need to do this to enable the container run-time service
+	sv, err := util.ParseKubernetesVersion(kubernetesVersion)
 	if err != nil {
-		return errors.Wrap(err, "kubeadm images")
+		return errors.Wrap(err, "Failed to parse Kubernetes version")
 	}
 
+	// Now, get images to pull
+	opts := images.ImageOptions{}
 	if containerRuntime != "docker" { // kic overlay image is only needed by containerd and cri-o https://github.com/kubernetes/minikube/issues/7428
-		imgs = append(imgs, images.KindNet(""))
+		opts.CNI = "kindnet"
 	}
-
-	runner := command.NewKICRunner(profile, driver.OCIBinary)
-
-	// will need to do this to enable the container run-time service
-	sv, err := util.ParseKubernetesVersion(kubernetesVersion)
+	mirror := ""
+	if containerRuntime == "containerd" && *containerdCertsDir != "" {
+		// reference the images containerd will pull, rather than their upstream repository
+		if mirror, err = images.ContainerdMirror(*containerdCertsDir, sv); err != nil {
+			return errors.Wrap(err, "containerd mirror")
+		}
+	}
+	imgs, err := images.ImagesForVersion(mirror, sv, opts)
 	if err != nil {
-		return errors.Wrap(err, "Failed to parse Kubernetes version")
+		return errors.Wrap(err, "kubeadm images")
 	}
 
+	runner := command.NewKICRunner(profile, driver.OCIBinary)
+
 	co := cruntime.Config{
 		Type:              containerRuntime,
 		Runner:            runner,
@@ -97,7 +104,8 @@ func generateTarball(kubernetesVersion, containerRuntime, tarballFilename string
 		return errors.Wrap(err, "enable container runtime")
 	}
 
-	for _, img := range imgs {
+	// pull the largest images first, so a failing pull is found before the small ones are done
+	for _, img := range images.SortByPullSize(imgs) {
 		pull := func() error {
 			cmd := imagePullCommand(containerRuntime, img)
 			cmd.Stdout = os.Stdout
@@ -50,6 +50,7 @@ var (
 	limit               = flag.Int("limit", 0, "Limit the number of tarballs to generate")
 	armUpload           = flag.Bool("arm-upload", false, "Upload the arm64 preload tarballs to GCS")
 	armPreloadsDir      = flag.String("arm-preloads-dir", "artifacts", "Directory containing the arm64 preload tarballs")
+	containerdCertsDir  = flag.String("containerd-certs-dir", "", "containerd registry config directory (ex: /etc/containerd/certs.d), whose mirror the containerd tarballs reference images from")
 )
 
 type preloadCfg struct {
@@ -17,8 +17,8 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -26,36 +26,23 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
-	"text/template"
 	"time"
 
 	"golang.org/x/mod/semver"
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/hack/update"
-	"k8s.io/minikube/pkg/minikube/constants"
 )
 
 const (
 	// default context timeout
-	cxTimeout                 = 300 * time.Second
-	kubeadmReleaseURL         = "https://storage.googleapis.com/kubernetes-release/release/%s/bin/linux/amd64/kubeadm"
-	kubeadmBinaryName         = "kubeadm-linux-amd64-%s"
-	minikubeConstantsFilePath = "pkg/minikube/constants/constants_kubeadm_images.go"
-	kubeadmImagesTemplate     = `
-		{{- range $version, $element := .}}
-		"{{$version}}": {
-			{{- range $image, $tag := $element}}
-			"{{$image}}": "{{$tag}}",
-			{{- end}}
-		},{{- end}}`
+	cxTimeout         = 300 * time.Second
+	kubeadmReleaseURL = "https://storage.googleapis.com/kubernetes-release/release/%s/bin/linux/amd64/kubeadm"
+	kubeadmBinaryName = "kubeadm-linux-amd64-%s"
+	versionsFilePath  = "pkg/minikube/bootstrapper/images/versions.json"
 )
 
-// Data contains kubeadm Images map
-type Data struct {
-	ImageMap string `json:"ImageMap"`
-}
-
 func main() {
 
 	inputVersion := flag.Lookup("kubernetes-version").Value.String()
@@ -77,41 +64,58 @@ func main() {
 		klog.Fatal(errors.New("invalid version"))
 	}
 
+	versionsFile := filepath.Join(update.FSRoot, versionsFilePath)
+	versions, err := readVersions(versionsFile)
+	if err != nil {
+		klog.Fatalln(err)
+	}
 	for _, imageVersion := range imageVersions {
-		imageMapString, err := getKubeadmImagesMapString(imageVersion)
+		images, err := getKubeadmImages(imageVersion)
 		if err != nil {
 			klog.Fatalln(err)
 		}
-
-		var data Data
-		schema := map[string]update.Item{
-			minikubeConstantsFilePath: {
-				Replace: map[string]string{},
-			},
+		minor := semver.MajorMinor(imageVersion)
+		// kubeadm doesn't list the architecture specific tags, keep those added by hand
+		for imageName, tag := range versions[minor] {
+			if strings.Contains(imageName, "@") {
+				images[imageName] = tag
+			}
 		}
+		versions[minor] = images
+	}
+	if err := writeVersions(versionsFile, versions); err != nil {
+		klog.Fatalln(err)
+	}
+}
 
-		majorMinorVersion := semver.MajorMinor(imageVersion)
-
-		if _, ok := constants.KubeadmImages[majorMinorVersion]; !ok {
-			data = Data{ImageMap: imageMapString}
-			schema[minikubeConstantsFilePath].Replace[`KubeadmImages = .*`] =
-				`KubeadmImages = map[string]map[string]string{ {{.ImageMap}}`
-		} else {
-			data = Data{ImageMap: strings.TrimLeft(imageMapString, "\n")}
-			versionIdentifier := fmt.Sprintf(`"%s": {[^}]+},`, majorMinorVersion)
-			schema[minikubeConstantsFilePath].Replace[versionIdentifier] = "{{.ImageMap}}"
-		}
+// readVersions returns the kubeadm images table of path, keyed by Kubernetes minor version
+func readVersions(path string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[string]map[string]string{}
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return versions, nil
+}
 
-		update.Apply(ctx, schema, data, "", "", -1)
+// writeVersions writes the kubeadm images table versions to path, with its keys sorted
+func writeVersions(path string, versions map[string]map[string]string) error {
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return err
 	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
 }
 
-func getKubeadmImagesMapString(version string) (string, error) {
+func getKubeadmImages(version string) (map[string]string, error) {
 	url := fmt.Sprintf(kubeadmReleaseURL, version)
 	fileName := fmt.Sprintf(kubeadmBinaryName, version)
 	if err := downloadFile(url, fileName); err != nil {
 		klog.Errorf("failed to download kubeadm binary %s", err.Error())
-		return "", err
+		return nil, err
 	}
 
 	kubeadmCommand := fmt.Sprintf("./%s", fileName)
@@ -119,20 +123,19 @@ func getKubeadmImagesMapString(version string) (string, error) {
 	imageListString, err := executeCommand(kubeadmCommand, args...)
 	if err != nil {
 		klog.Errorf("failed to execute kubeadm command %s", kubeadmCommand)
-		return "", err
+		return nil, err
 	}
 
 	if err := os.Remove(fileName); err != nil {
 		klog.Errorf("failed to remove binary %s", fileName)
 	}
 
-	return formatKubeadmImageList(version, imageListString)
+	return formatKubeadmImageList(imageListString), nil
 }
 
-func formatKubeadmImageList(version, data string) (string, error) {
-	templateData := make(map[string]map[string]string)
-	majorMinorVersion := semver.MajorMinor(version)
-	templateData[majorMinorVersion] = make(map[string]string)
+// formatKubeadmImageList returns the tags of the images listed by `kubeadm config images list` in data, keyed by image name
+func formatKubeadmImageList(data string) map[string]string {
+	images := make(map[string]string)
 	lines := strings.Split(data, "\n")
 	for _, line := range lines {
 		imageTag := strings.Split(line, ":")
@@ -143,23 +146,10 @@ func formatKubeadmImageList(version, data string) (string, error) {
 		imageName := strings.Split(imageTag[0], "/")
 		imageTag[0] = strings.Join(imageName[1:], "/")
 		if !isKubeImage(imageTag[0]) {
-			templateData[majorMinorVersion][imageTag[0]] = imageTag[1]
+			images[imageTag[0]] = imageTag[1]
 		}
 	}
-
-	imageTemplate := template.New("kubeadmImage")
-	t, err := imageTemplate.Parse(kubeadmImagesTemplate)
-	if err != nil {
-		klog.Errorf("failed to create kubeadm image map template %s", err.Error())
-		return "", err
-	}
-
-	var bytesBuffer bytes.Buffer
-	if err := t.Execute(&bytesBuffer, &templateData); err != nil {
-		return "", err
-	}
-
-	return bytesBuffer.String(), nil
+	return images
 }
 
 func isKubeImage(name string) bool {
@@ -58,7 +58,7 @@ func GetCachedBinaryList(bootstrapper string) []string {
 	return constants.KubernetesReleaseBinaries
 }
 
-// GetCachedImageList returns the list of images for a version
-func GetCachedImageList(imageRepository string, version string, bootstrapper string) ([]string, error) {
-	return images.Kubeadm(imageRepository, version)
+// GetCachedImageList returns the list of images of the cluster cc for a version
+func GetCachedImageList(cc config.ClusterConfig, version string, bootstrapper string) ([]string, error) {
+	return images.KubeadmForCluster(cc, version)
 }
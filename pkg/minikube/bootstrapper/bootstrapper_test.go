@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package bootstrapper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/tests"
+)
+
+func TestGetCachedImageList(t *testing.T) {
+	tests.MakeTempDir(t)
+	images.SetOffline(true)
+	defer images.SetOffline(false)
+
+	testCases := []struct {
+		name     string
+		addons   map[string]bool
+		options  config.ExtraOptionSlice
+		included []string
+		excluded []string
+	}{
+		{name: "Default", included: []string{"/kube-proxy:", "/storage-provisioner:"}},
+		{name: "MetricsServer", addons: map[string]bool{"metrics-server": true}, included: []string{"/metrics-server:"}},
+		{name: "Gvisor", addons: map[string]bool{"gvisor": true}, included: []string{"/gvisor-addon:"}},
+		{name: "NoStorageProvisioner", addons: map[string]bool{"storage-provisioner": false}, excluded: []string{"/storage-provisioner:"}},
+		{name: "KubeProxyless", options: config.ExtraOptionSlice{{Component: "kubeadm", Key: "skip-phases", Value: "addon/kube-proxy"}}, excluded: []string{"/kube-proxy:"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := config.ClusterConfig{Addons: tc.addons, KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.24.1", ExtraOptions: tc.options}}
+			cached, err := GetCachedImageList(cc, cc.KubernetesConfig.KubernetesVersion, Kubeadm)
+			if err != nil {
+				t.Fatalf("GetCachedImageList: %v", err)
+			}
+			// UpdateCluster loads the images of KubeadmForCluster from the cache
+			loaded, err := images.KubeadmForCluster(cc, cc.KubernetesConfig.KubernetesVersion)
+			if err != nil {
+				t.Fatalf("KubeadmForCluster: %v", err)
+			}
+			if diff := cmp.Diff(loaded, cached); diff != "" {
+				t.Errorf("cached images differ from the loaded images (-loaded +cached):\n%s", diff)
+			}
+			for _, want := range tc.included {
+				if !containsImage(cached, want) {
+					t.Errorf("cached images %v are missing %s", cached, want)
+				}
+			}
+			for _, unwanted := range tc.excluded {
+				if containsImage(cached, unwanted) {
+					t.Errorf("cached images %v include %s", cached, unwanted)
+				}
+			}
+		})
+	}
+}
+
+// containsImage reports whether one of imgs contains part
+func containsImage(imgs []string, part string) bool {
+	for _, img := range imgs {
+		if strings.Contains(img, part) {
+			return true
+		}
+	}
+	return false
+}
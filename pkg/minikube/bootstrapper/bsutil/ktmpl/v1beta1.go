@@ -60,9 +60,15 @@ clusterName: {{.ClusterName}}
 controlPlaneEndpoint: {{.ControlPlaneAddress}}:{{.APIServerPort}}
 dns:
   type: CoreDNS
+{{- if .CoreDNSImageTag}}
+  imageTag: {{.CoreDNSImageTag}}
+{{- end}}
 etcd:
   local:
     dataDir: {{.EtcdDataDir}}
+{{- if .EtcdImageTag}}
+    imageTag: {{.EtcdImageTag}}
+{{- end}}
     extraArgs:
       listen-metrics-urls: http://127.0.0.1:2381,http://{{.AdvertiseAddress}}:2381
 kubernetesVersion: {{.KubernetesVersion}}
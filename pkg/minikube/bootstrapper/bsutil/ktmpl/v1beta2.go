@@ -60,9 +60,15 @@ clusterName: mk
 controlPlaneEndpoint: {{.ControlPlaneAddress}}:{{.APIServerPort}}
 dns:
   type: CoreDNS
+{{- if .CoreDNSImageTag}}
+  imageTag: {{.CoreDNSImageTag}}
+{{- end}}
 etcd:
   local:
     dataDir: {{.EtcdDataDir}}
+{{- if .EtcdImageTag}}
+    imageTag: {{.EtcdImageTag}}
+{{- end}}
     extraArgs:
       proxy-refresh-interval: "70000"
 {{- range $i, $val := printMapInOrder .EtcdExtraArgs ": " }}
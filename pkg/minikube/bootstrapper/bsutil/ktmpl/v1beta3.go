@@ -58,9 +58,16 @@ kind: ClusterConfiguration
 certificatesDir: {{.CertDir}}
 clusterName: mk
 controlPlaneEndpoint: {{.ControlPlaneAddress}}:{{.APIServerPort}}
+{{- if .CoreDNSImageTag}}
+dns:
+  imageTag: {{.CoreDNSImageTag}}
+{{- end}}
 etcd:
   local:
     dataDir: {{.EtcdDataDir}}
+{{- if .EtcdImageTag}}
+    imageTag: {{.EtcdImageTag}}
+{{- end}}
     extraArgs:
       proxy-refresh-interval: "70000"
 {{- range $i, $val := printMapInOrder .EtcdExtraArgs ": " }}
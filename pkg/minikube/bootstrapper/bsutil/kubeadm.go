@@ -95,6 +95,8 @@ func GenerateKubeadmYAML(cc config.ClusterConfig, n config.Node, r cruntime.Mana
 		KubernetesVersion   string
 		EtcdDataDir         string
 		EtcdExtraArgs       map[string]string
+		EtcdImageTag        string
+		CoreDNSImageTag     string
 		ClusterName         string
 		NodeName            string
 		DNSDomain           string
@@ -118,6 +120,8 @@ func GenerateKubeadmYAML(cc config.ClusterConfig, n config.Node, r cruntime.Mana
 		KubernetesVersion: k8s.KubernetesVersion,
 		EtcdDataDir:       EtcdDataDir(),
 		EtcdExtraArgs:     etcdExtraArgs(k8s.ExtraOptions),
+		EtcdImageTag:      k8s.EtcdVersion,
+		CoreDNSImageTag:   k8s.CoreDNSVersion,
 		ClusterName:       cc.Name,
 		// kubeadm uses NodeName as the --hostname-override parameter, so this needs to be the name of the machine
 		NodeName:            KubeNodeName(cc, n),
@@ -292,6 +292,43 @@ func TestGenerateKubeadmYAML(t *testing.T) {
 	}
 }
 
+func TestGenerateKubeadmYAMLImageTags(t *testing.T) {
+	fcr := command.NewFakeCommandRunner()
+	fcr.SetCommandToOutput(map[string]string{
+		"docker info --format {{.CgroupDriver}}": "systemd\n",
+	})
+	runtime, err := cruntime.New(cruntime.Config{Type: "docker", Runner: fcr})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	// v1beta1, v1beta2 and v1beta3 kubeadm configs respectively
+	for _, version := range []string{"v1.16.0", "v1.22.0", "v1.23.0"} {
+		t.Run(version, func(t *testing.T) {
+			cfg := config.ClusterConfig{
+				Name: "mk",
+				KubernetesConfig: config.KubernetesConfig{
+					KubernetesVersion: version,
+					EtcdVersion:       "3.5.3-0",
+					CoreDNSVersion:    "v1.8.7",
+				},
+				Nodes: []config.Node{{IP: "1.1.1.1", Name: "mk", ControlPlane: true}},
+			}
+			got, err := GenerateKubeadmYAML(cfg, cfg.Nodes[0], runtime)
+			if err != nil {
+				t.Fatalf("GenerateKubeadmYAML: %v", err)
+			}
+			for _, want := range []string{"dns:\n  type: CoreDNS\n  imageTag: v1.8.7\n", "    dataDir: /var/lib/minikube/etcd\n    imageTag: 3.5.3-0\n"} {
+				if version == "v1.23.0" {
+					want = strings.Replace(want, "  type: CoreDNS\n", "", 1)
+				}
+				if !strings.Contains(string(got), want) {
+					t.Errorf("expected config to contain %q, got:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
 func TestEtcdExtraArgs(t *testing.T) {
 	expected := map[string]string{
 		"key": "value",
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"path"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+)
+
+// AddonImages returns the pinned images the addon addonName deploys, pulled from mirror if it is set
+func AddonImages(addonName string, mirror string) ([]string, error) {
+	addon, ok := assets.Addons[addonName]
+	if !ok {
+		return nil, fmt.Errorf("unknown addon %q", addonName)
+	}
+	mirror = normalizeMirror(mirror)
+	imgs := []string{}
+	for name, img := range addon.Images {
+		switch {
+		case mirror != "":
+			imgs = append(imgs, path.Join(mirror, img))
+		case addon.Registries[name] != "":
+			imgs = append(imgs, path.Join(addon.Registries[name], img))
+		default:
+			imgs = append(imgs, img)
+		}
+	}
+	if err := ValidateReferences(imgs); err != nil {
+		return nil, err
+	}
+	return dedupe(imgs), nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAddonImages(t *testing.T) {
+	const (
+		certgen    = "k8s.gcr.io/ingress-nginx/kube-webhook-certgen:v1.1.1@sha256:64d8c73dca984af206adf9d6d7e46aa550362b1d7a01f3a0a91b20cc67868660"
+		controller = "ingress-nginx/controller:v1.2.0@sha256:d8196e3bc1e72547c5dec66d6556c0ff92a23f6d0919b206be170bc90d5f9185"
+		dashboard  = "kubernetesui/dashboard:v2.6.0@sha256:4af9580485920635d888efe1eddbd67e12f9d5d84dba87100e93feb4e46636b3"
+		scraper    = "kubernetesui/metrics-scraper:v1.0.8@sha256:76049887f07a0476dc93efc2d3569b9529bf982b22d29f356092ce206e98765c"
+	)
+	tests := []struct {
+		addon  string
+		mirror string
+		want   []string
+	}{
+		{"ingress", "", []string{"k8s.gcr.io/" + controller, certgen}},
+		{"ingress", "mirror.corp:5000/", []string{"mirror.corp:5000/" + controller, "mirror.corp:5000/" + certgen}},
+		{"dashboard", "", []string{dashboard, scraper}},
+		{"dashboard", "mirror.corp:5000", []string{"mirror.corp:5000/" + dashboard, "mirror.corp:5000/" + scraper}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.addon+"/"+tc.mirror, func(t *testing.T) {
+			got, err := AddonImages(tc.addon, tc.mirror)
+			if err != nil {
+				t.Fatalf("AddonImages(%q, %q) error: %v", tc.addon, tc.mirror, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("AddonImages(%q, %q) images mismatch (-want +got):\n%s", tc.addon, tc.mirror, diff)
+			}
+		})
+	}
+}
+
+func TestAddonImagesUnknown(t *testing.T) {
+	if _, err := AddonImages("no-such-addon", ""); err == nil {
+		t.Errorf("AddonImages(%q) expected an error", "no-such-addon")
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+)
+
+// supportedArchs are the architectures upstream publishes the Kubernetes images for
+var supportedArchs = map[string]bool{
+	"amd64":   true,
+	"arm":     true,
+	"arm64":   true,
+	"ppc64le": true,
+	"s390x":   true,
+}
+
+// archSuffixedImages are the images published with a tag per architecture, such as flannel:v0.12.0-arm64,
+// rather than a manifest list. Every other image, including etcd and coredns, is referenced the same on all architectures.
+var archSuffixedImages = map[string]bool{
+	"flannel": true,
+}
+
+// targetArch returns the architecture the images of opts are for, defaulting to the architecture minikube runs on
+func targetArch(opts ImageOptions) string {
+	if opts.Arch != "" {
+		return opts.Arch
+	}
+	return runtime.GOARCH
+}
+
+// validateArch returns an error if images aren't published for arch
+func validateArch(arch string) error {
+	if arch != "" && !supportedArchs[arch] {
+		return fmt.Errorf("unsupported architecture: %q", arch)
+	}
+	return nil
+}
+
+// forArch returns the reference ref has on arch, suffixing the tag of images published per architecture
+func forArch(ref string, arch string) string {
+	img := parseImage(ref, "")
+	if !archSuffixedImages[path.Base(img.Repo)] || img.Tag == "" || img.Digest != "" {
+		return ref
+	}
+	return ref + "-" + arch
+}
+
+// withArch returns the reference every image of refs has on arch
+func withArch(refs []string, arch string) []string {
+	imgs := []string{}
+	for _, ref := range refs {
+		imgs = append(imgs, forArch(ref, arch))
+	}
+	return imgs
+}
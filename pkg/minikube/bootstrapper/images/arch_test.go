@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestImagesForArch(t *testing.T) {
+	tests := []struct {
+		version string
+		arch    string
+		want    []string
+	}{
+		{"1.24.0", "arm64", []string{"k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/coredns/coredns:v1.8.6", "quay.io/coreos/flannel:v0.12.0-arm64"}},
+		{"1.20.0", "arm64", []string{"k8s.gcr.io/etcd:3.4.13-0", "k8s.gcr.io/coredns:1.7.0", "quay.io/coreos/flannel:v0.12.0-arm64"}},
+		{"1.24.0", "amd64", []string{"k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/coredns/coredns:v1.8.6", "quay.io/coreos/flannel:v0.12.0-amd64"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version+"/"+tc.arch, func(t *testing.T) {
+			imgs, err := ImagesForVersion("", semver.MustParse(tc.version), ImageOptions{CNI: "flannel", Arch: tc.arch})
+			if err != nil {
+				t.Fatalf("ImagesForVersion: %v", err)
+			}
+			got := map[string]bool{}
+			for _, img := range imgs {
+				got[img] = true
+			}
+			for _, want := range tc.want {
+				if !got[want] {
+					t.Errorf("missing %s in %v", want, imgs)
+				}
+			}
+		})
+	}
+}
+
+func TestImagesForArchInvalid(t *testing.T) {
+	if _, err := ImagesForVersion("", semver.MustParse("1.24.0"), ImageOptions{Arch: "mips"}); err == nil {
+		t.Errorf("ImagesForVersion with arch mips succeeded, want an error")
+	}
+	if _, err := KubeadmWithOptions("", "v1.24.0", ImageOptions{Arch: "mips"}); err == nil {
+		t.Errorf("KubeadmWithOptions with arch mips succeeded, want an error")
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+)
+
+// AuditStatus is what the registry of an audited image says about it
+type AuditStatus string
+
+const (
+	// AuditPresent is the status of images the registry serves
+	AuditPresent AuditStatus = "present"
+	// AuditMissing is the status of images the registry has no manifest for
+	AuditMissing AuditStatus = "missing"
+	// AuditUnverified is the status of images which weren't checked, as minikube is offline
+	AuditUnverified AuditStatus = "unverified"
+	// AuditError is the status of images whose registry couldn't tell whether it serves them
+	AuditError AuditStatus = "error"
+)
+
+// AuditedImage is a required image and whether its registry serves it
+type AuditedImage struct {
+	// Image is the reference which would be fetched, after mirror rewriting and digest pinning
+	Image string `json:"image"`
+	// Upstream is the reference of the image in its upstream repository, if Image is rewritten to a mirror
+	Upstream string      `json:"upstream,omitempty"`
+	Role     Role        `json:"role"`
+	Status   AuditStatus `json:"status"`
+	// Digest is the digest the registry serves Image with, if it is present
+	Digest string `json:"digest,omitempty"`
+	// Error is why the registry couldn't be checked, if the status is AuditError
+	Error string `json:"error,omitempty"`
+}
+
+// ImageAudit is the report of whether the registries the cluster would pull from serve every required image
+type ImageAudit struct {
+	Images []AuditedImage `json:"images"`
+}
+
+// Missing returns the images whose registry doesn't serve them
+func (a ImageAudit) Missing() []string {
+	missing := []string{}
+	for _, img := range a.Images {
+		if img.Status == AuditMissing {
+			missing = append(missing, img.Image)
+		}
+	}
+	return missing
+}
+
+// AuditImages reports, for every image of ImagesForVersion, its role, the upstream image a mirror rewrote it from and
+// whether its registry serves it, with the digest it is served with. The images are listed without any registry lookup,
+// as PreviewImages does, so only checking them reaches the network. Offline, every image is AuditUnverified.
+func AuditImages(ctx context.Context, repo string, k8sVersion semver.Version, opts ImageOptions) (ImageAudit, error) {
+	opts.Offline = true
+	imgs, err := StructuredImagesForVersion(repo, k8sVersion, opts)
+	if err != nil {
+		return ImageAudit{}, err
+	}
+	upstream, err := upstreamImages(k8sVersion, opts)
+	if err != nil {
+		return ImageAudit{}, errors.Wrap(err, "upstream images")
+	}
+
+	audit := ImageAudit{Images: []AuditedImage{}}
+	for _, img := range imgs {
+		a := AuditedImage{Image: img.String(), Role: img.Role, Status: AuditUnverified}
+		if u, ok := upstream[planKey(img)]; ok && (u.Registry != img.Registry || u.Repo != img.Repo) {
+			a.Upstream = u.String()
+		}
+		audit.Images = append(audit.Images, a)
+	}
+	if offline {
+		return audit, nil
+	}
+
+	runBounded(ctx, len(audit.Images), func(i int) {
+		a := &audit.Images[i]
+		desc, err := headImage(ctx, a.Image)
+		if err != nil {
+			var terr *transport.Error
+			if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+				a.Status = AuditMissing
+				return
+			}
+			a.Status = AuditError
+			a.Error = registryError(ctx, err).Error()
+			return
+		}
+		a.Status = AuditPresent
+		a.Digest = desc.Digest.String()
+	})
+	if err := ctx.Err(); err != nil {
+		return ImageAudit{}, err
+	}
+	return audit, nil
+}
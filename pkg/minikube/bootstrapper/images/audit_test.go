@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAuditImages(t *testing.T) {
+	useTagCache(t, "")
+	useRateLimit(t, 1000)
+
+	// the mirror only holds some of the images of v1.24.0
+	mirror := newDigestRegistry(map[string]string{
+		"/v2/kube-apiserver/manifests/v1.24.0":          "a",
+		"/v2/kube-controller-manager/manifests/v1.24.0": "b",
+		"/v2/etcd/manifests/3.5.3-0":                    "c",
+		"/v2/pause/manifests/3.7":                       "d",
+	})
+	defer mirror.Close()
+	host := strings.TrimPrefix(mirror.URL, "http://")
+
+	audit, err := AuditImages(context.Background(), host, semver.MustParse("1.24.0"), ImageOptions{NoStorageProvisioner: true})
+	if err != nil {
+		t.Fatalf("AuditImages: %v", err)
+	}
+	present := func(ref string, upstream string, d string) AuditedImage {
+		return AuditedImage{Image: host + "/" + ref, Upstream: upstream, Role: RoleControlPlane, Status: AuditPresent, Digest: "sha256:" + strings.Repeat(d, 64)}
+	}
+	missing := func(ref string, upstream string) AuditedImage {
+		return AuditedImage{Image: host + "/" + ref, Upstream: upstream, Role: RoleControlPlane, Status: AuditMissing}
+	}
+	want := ImageAudit{Images: []AuditedImage{
+		missing("coredns/coredns:v1.8.6", "k8s.gcr.io/coredns/coredns:v1.8.6"),
+		present("etcd:3.5.3-0", "k8s.gcr.io/etcd:3.5.3-0", "c"),
+		present("kube-apiserver:v1.24.0", "k8s.gcr.io/kube-apiserver:v1.24.0", "a"),
+		present("kube-controller-manager:v1.24.0", "k8s.gcr.io/kube-controller-manager:v1.24.0", "b"),
+		missing("kube-proxy:v1.24.0", "k8s.gcr.io/kube-proxy:v1.24.0"),
+		missing("kube-scheduler:v1.24.0", "k8s.gcr.io/kube-scheduler:v1.24.0"),
+		present("pause:3.7", "k8s.gcr.io/pause:3.7", "d"),
+	}}
+	if diff := cmp.Diff(want, audit); diff != "" {
+		t.Errorf("AuditImages mismatch (-want +got):\n%s", diff)
+	}
+	wantMissing := []string{host + "/coredns/coredns:v1.8.6", host + "/kube-proxy:v1.24.0", host + "/kube-scheduler:v1.24.0"}
+	if diff := cmp.Diff(wantMissing, audit.Missing()); diff != "" {
+		t.Errorf("Missing() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAuditImagesUnreachable(t *testing.T) {
+	useTagCache(t, "")
+	transport := &countingTransport{}
+	useTagLookupTransport(t, transport)
+
+	audit, err := AuditImages(context.Background(), "registry.corp", semver.MustParse("1.24.0"), ImageOptions{NoStorageProvisioner: true})
+	if err != nil {
+		t.Fatalf("AuditImages: %v", err)
+	}
+	for _, img := range audit.Images {
+		if img.Status != AuditError || img.Error == "" {
+			t.Errorf("%s is %s (%q), want an unreachable registry error", img.Image, img.Status, img.Error)
+		}
+	}
+}
+
+func TestAuditImagesOffline(t *testing.T) {
+	useTagCache(t, "")
+	transport := &countingTransport{}
+	useTagLookupTransport(t, transport)
+	SetOffline(true)
+	defer SetOffline(false)
+
+	audit, err := AuditImages(context.Background(), "registry.corp", semver.MustParse("1.24.0"), ImageOptions{CNI: "calico"})
+	if err != nil {
+		t.Fatalf("AuditImages: %v", err)
+	}
+	roles := map[Role]bool{}
+	for _, img := range audit.Images {
+		roles[img.Role] = true
+		if img.Status != AuditUnverified {
+			t.Errorf("%s is %s offline, want %s", img.Image, img.Status, AuditUnverified)
+		}
+		if img.Upstream == "" {
+			t.Errorf("%s has no upstream image, want the image it was rewritten from", img.Image)
+		}
+	}
+	for _, role := range []Role{RoleControlPlane, RoleAuxiliary, RoleCNI} {
+		if !roles[role] {
+			t.Errorf("no %s image was audited", role)
+		}
+	}
+	if n := atomic.LoadInt32(&transport.requests); n != 0 {
+		t.Errorf("made %d requests offline, want none", n)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// dockerConfigPath is the docker config file registry credentials are read from, empty uses ~/.docker/config.json or $DOCKER_CONFIG
+var dockerConfigPath string
+
+// SetDockerConfig makes the tag lookups and image checks authenticate with the credentials of the docker config file path,
+// instead of those of ~/.docker/config.json. An empty path restores the default.
+func SetDockerConfig(path string) {
+	dockerConfigPath = path
+}
+
+// registryKeychain returns the keychain registry credentials are resolved with
+func registryKeychain() authn.Keychain {
+	if dockerConfigPath == "" {
+		return authn.DefaultKeychain
+	}
+	return fileKeychain{path: dockerConfigPath}
+}
+
+// fileKeychain resolves registry credentials from the docker config file at path, as authn.DefaultKeychain does from ~/.docker/config.json
+type fileKeychain struct {
+	path string
+}
+
+// Resolve returns the credentials of the registry of target, or anonymous access if the file has none
+func (k fileKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	f, err := os.Open(k.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "docker config")
+	}
+	defer f.Close()
+	cf, err := config.LoadFromReader(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing docker config %s", k.path)
+	}
+
+	key := target.RegistryStr()
+	if key == name.DefaultRegistry {
+		key = authn.DefaultAuthKey
+	}
+	cfg, err := cf.GetAuthConfig(key)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == (types.AuthConfig{}) {
+		return authn.Anonymous, nil
+	}
+	return authn.FromConfig(authn.AuthConfig{
+		Username:      cfg.Username,
+		Password:      cfg.Password,
+		Auth:          cfg.Auth,
+		IdentityToken: cfg.IdentityToken,
+		RegistryToken: cfg.RegistryToken,
+	}), nil
+}
+
+// withRegistryAuth wraps t so that requests the registry rejects as unauthorized are retried with the credentials of registryKeychain,
+// following the basic or bearer token challenge of the registry
+func withRegistryAuth(t http.RoundTripper) http.RoundTripper {
+	return &authTransport{inner: t}
+}
+
+// authTransport retries the requests rejected with 401 Unauthorized through an authenticating transport of go-containerregistry
+type authTransport struct {
+	inner http.RoundTripper
+}
+
+// RoundTrip sends req, authenticating it if the registry asks for credentials
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.Body != nil {
+		return resp, err
+	}
+
+	authed, err := t.authenticated(req)
+	if err != nil {
+		klog.V(3).Infof("failed to authenticate with %s: %v", req.URL.Host, err)
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return authed.RoundTrip(req.Clone(req.Context()))
+}
+
+// authenticated returns a transport attaching the credentials of the registry of req, scoped to pulling the repository of req
+func (t *authTransport) authenticated(req *http.Request) (http.RoundTripper, error) {
+	opts := []name.Option{}
+	if req.URL.Scheme == "http" || isInsecureRegistry(req.URL.Host) {
+		opts = append(opts, name.Insecure)
+	}
+	reg, err := name.NewRegistry(req.URL.Host, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "registry")
+	}
+	auth, err := registryKeychain().Resolve(reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "credentials")
+	}
+	scopes := []string{}
+	if repo := tagListRepository(req.URL.Path); repo != "" {
+		r, err := name.NewRepository(reg.Name()+"/"+repo, opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "repository")
+		}
+		scopes = append(scopes, r.Scope(transport.PullScope))
+	}
+	return transport.NewWithContext(req.Context(), reg, auth, t.inner, scopes)
+}
+
+// tagListRepository returns the repository of a docker v2 tag list path such as /v2/coredns/coredns/tags/list, or "" for other paths
+func tagListRepository(p string) string {
+	if !strings.HasPrefix(p, "/v2/") || !strings.HasSuffix(p, "/tags/list") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(p, "/v2/"), "/tags/list")
+}
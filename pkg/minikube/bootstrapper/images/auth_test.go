@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDockerConfig writes a docker config file holding user:password for the registry of server, returning its path
+func writeDockerConfig(t *testing.T, server *httptest.Server, user string, password string) string {
+	t.Helper()
+	auth := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+	data := fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, strings.TrimPrefix(server.URL, "http://"), auth)
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("write docker config: %v", err)
+	}
+	return path
+}
+
+// useDockerConfig points SetDockerConfig at a docker config file holding user:password for the registry of server
+func useDockerConfig(t *testing.T, server *httptest.Server, user string, password string) {
+	t.Helper()
+	SetDockerConfig(writeDockerConfig(t, server, user, password))
+	t.Cleanup(func() { SetDockerConfig("") })
+}
+
+func TestLatestTagBasicAuth(t *testing.T) {
+	useTagCache(t, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	url := server.URL + "/v2/coredns/tags/list"
+	if _, err := findLatestTagFromRepositoryE(context.Background(), url, "v1.8.6"); err == nil {
+		t.Errorf("lookup without credentials succeeded, want an error")
+	}
+
+	useDockerConfig(t, server, "user", "secret")
+	got, err := findLatestTagFromRepositoryE(context.Background(), url, "v1.8.6")
+	if err != nil {
+		t.Fatalf("lookup with credentials: %v", err)
+	}
+	if got != "v1.8.9" {
+		t.Errorf("got %s, want v1.8.9", got)
+	}
+}
+
+func TestLatestTagBearerAuth(t *testing.T) {
+	useTagCache(t, "")
+	const token = "registry-token"
+	var gotScope string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			gotScope = r.URL.Query().Get("scope")
+			fmt.Fprintf(w, `{"token": %q}`, token)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+	useDockerConfig(t, server, "user", "secret")
+
+	got, err := findLatestTagFromRepositoryE(context.Background(), server.URL+"/v2/coredns/coredns/tags/list", "v1.8.6")
+	if err != nil {
+		t.Fatalf("lookup with credentials: %v", err)
+	}
+	if got != "v1.8.9" {
+		t.Errorf("got %s, want v1.8.9", got)
+	}
+	if want := "repository:coredns/coredns:pull"; gotScope != want {
+		t.Errorf("token requested for scope %q, want %q", gotScope, want)
+	}
+}
+
+func TestVerifyImagesExistBasicAuth(t *testing.T) {
+	registry := newManifestRegistry(map[string]bool{"/v2/pause/manifests/3.7": true})
+	defer registry.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		registry.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	useDockerConfig(t, server, "user", "secret")
+
+	img := strings.TrimPrefix(server.URL, "http://") + "/pause:3.7"
+	missing, err := VerifyImagesExist(context.Background(), []string{img})
+	if err != nil {
+		t.Fatalf("VerifyImagesExist: %v", err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("missing images = %v, want none", missing)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+// MissingImages returns the images of required which are not yet saved in cachedDir, such as detect.ImageCacheDir().
+// Empty cache files, left behind by an interrupted download, are reported as missing.
+func MissingImages(required []string, cachedDir string) ([]string, error) {
+	missing := []string{}
+	for _, img := range required {
+		p := localpath.SanitizeCacheDir(filepath.Join(cachedDir, img))
+		fi, err := os.Stat(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				missing = append(missing, img)
+				continue
+			}
+			return nil, errors.Wrapf(err, "stat %s", p)
+		}
+		if !fi.Mode().IsRegular() || fi.Size() == 0 {
+			klog.Infof("cached image %s is corrupt, treating it as missing", p)
+			missing = append(missing, img)
+		}
+	}
+	return missing, nil
+}
+
+// ImageToCachePath returns the path image is saved to in the image cache directory, relative to it.
+// Colons, which separate the port of a registry and the tag of an image, are replaced by underscores.
+func ImageToCachePath(image string) string {
+	return localpath.SanitizeCacheDir(filepath.FromSlash(image))
+}
+
+// CachePathToImage returns the image saved to the path p of the image cache directory, relative to it, reversing ImageToCachePath.
+// The cached images are expected to be tagged, so the last underscore of the path is taken to precede the tag.
+func CachePathToImage(p string) string {
+	parts := strings.Split(filepath.ToSlash(p), "/")
+
+	// like docker, the first component is only a registry if it looks like a host
+	if len(parts) > 1 {
+		if i := strings.LastIndex(parts[0], "_"); i >= 0 && isPort(parts[0][i+1:]) {
+			if host := parts[0][:i]; strings.Contains(host, ".") || host == "localhost" {
+				parts[0] = host + ":" + parts[0][i+1:]
+			}
+		}
+	}
+
+	last := parts[len(parts)-1]
+	digest := ""
+	if i := strings.Index(last, "@"); i >= 0 {
+		last, digest = last[:i], "@"+strings.Replace(last[i+1:], "_", ":", 1)
+	}
+	if i := strings.LastIndex(last, "_"); i >= 0 {
+		last = last[:i] + ":" + last[i+1:]
+	}
+	parts[len(parts)-1] = last + digest
+	return strings.Join(parts, "/")
+}
+
+// isPort reports whether s is a port number
+func isPort(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+func TestMissingImages(t *testing.T) {
+	dir := t.TempDir()
+	cache := func(img string, content string) {
+		p := localpath.SanitizeCacheDir(filepath.Join(dir, img))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	cache("k8s.gcr.io/pause:3.5", "tarball")
+	cache("k8s.gcr.io/etcd:3.5.0-0", "tarball")
+	cache("k8s.gcr.io/coredns/coredns:v1.8.4", "")
+	if err := os.MkdirAll(localpath.SanitizeCacheDir(filepath.Join(dir, "k8s.gcr.io/kube-proxy:v1.22.0")), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	required := []string{
+		"k8s.gcr.io/kube-apiserver:v1.22.0",
+		"k8s.gcr.io/kube-proxy:v1.22.0",
+		"k8s.gcr.io/pause:3.5",
+		"k8s.gcr.io/etcd:3.5.0-0",
+		"k8s.gcr.io/coredns/coredns:v1.8.4",
+	}
+	want := []string{
+		"k8s.gcr.io/kube-apiserver:v1.22.0",
+		"k8s.gcr.io/kube-proxy:v1.22.0",
+		"k8s.gcr.io/coredns/coredns:v1.8.4",
+	}
+	got, err := MissingImages(required, dir)
+	if err != nil {
+		t.Fatalf("MissingImages: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("missing images mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = MissingImages(required, filepath.Join(dir, "empty"))
+	if err != nil {
+		t.Fatalf("MissingImages: %v", err)
+	}
+	if diff := cmp.Diff(required, got); diff != "" {
+		t.Errorf("missing images from an empty cache mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestImageToCachePath(t *testing.T) {
+	tests := []struct {
+		image string
+		path  string
+	}{
+		{"k8s.gcr.io/pause:3.7", "k8s.gcr.io/pause_3.7"},
+		{"k8s.gcr.io/coredns/coredns:v1.8.6", "k8s.gcr.io/coredns/coredns_v1.8.6"},
+		{"registry.corp:5000/k8s-minikube/storage-provisioner:v5", "registry.corp_5000/k8s-minikube/storage-provisioner_v5"},
+		{"localhost:5000/etcd:3.5.3-0", "localhost_5000/etcd_3.5.3-0"},
+		{"registry.cn-hangzhou.aliyuncs.com/google_containers/pause:3.7", "registry.cn-hangzhou.aliyuncs.com/google_containers/pause_3.7"},
+		{"kindest/kindnetd:v20210326-1e038dc5", "kindest/kindnetd_v20210326-1e038dc5"},
+		{"k8s.gcr.io/metrics-server/metrics-server:v0.6.1@sha256:5ddc", "k8s.gcr.io/metrics-server/metrics-server_v0.6.1@sha256_5ddc"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.image, func(t *testing.T) {
+			p := ImageToCachePath(tc.image)
+			if diff := cmp.Diff(filepath.FromSlash(tc.path), p); diff != "" {
+				t.Errorf("ImageToCachePath mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.image, CachePathToImage(p)); diff != "" {
+				t.Errorf("CachePathToImage mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
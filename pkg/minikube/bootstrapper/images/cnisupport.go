@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+)
+
+// cniSupport is the range of Kubernetes versions the pinned version of a CNI claims to support.
+// CNIs missing from it, such as kindnet and bridge, are assumed to support every version minikube does.
+var cniSupport = map[string]struct {
+	version string
+	k8s     string
+}{
+	// https://projectcalico.docs.tigera.io/archive/v3.20/getting-started/kubernetes/requirements
+	"calico": {calicoVersion, ">=1.19.0 <1.22.0"},
+	// https://docs.cilium.io/en/v1.9/concepts/kubernetes/requirements/
+	"cilium": {ciliumVersion, ">=1.12.0"},
+}
+
+// CheckCNICompatibility returns an ErrIncompatibleCNI if the version of the named CNI minikube pins
+// doesn't claim support for Kubernetes v. Pre-releases are checked as their release.
+func CheckCNICompatibility(name string, v semver.Version) error {
+	support, ok := cniSupport[name]
+	if !ok {
+		return nil
+	}
+	release := semver.Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	if semver.MustParseRange(support.k8s)(release) {
+		return nil
+	}
+	return withKind(ErrIncompatibleCNI, fmt.Errorf("%s %s supports Kubernetes %s, not %s", name, support.version, support.k8s, v))
+}
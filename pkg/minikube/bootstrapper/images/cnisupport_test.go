@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog/v2"
+)
+
+func TestCheckCNICompatibility(t *testing.T) {
+	tests := []struct {
+		cni        string
+		version    string
+		compatible bool
+	}{
+		{"calico", "1.20.0", true},
+		{"calico", "1.21.0-rc.0", true},
+		{"calico", "1.18.20", false},
+		{"calico", "1.24.0", false},
+		{"cilium", "1.24.0", true},
+		{"cilium", "1.12.0-alpha.0", true},
+		{"kindnet", "1.24.0", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.cni+"/"+tc.version, func(t *testing.T) {
+			err := CheckCNICompatibility(tc.cni, semver.MustParse(tc.version))
+			if tc.compatible {
+				if err != nil {
+					t.Errorf("CheckCNICompatibility: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrIncompatibleCNI) {
+				t.Errorf("CheckCNICompatibility error = %v, want an error matching ErrIncompatibleCNI", err)
+			}
+		})
+	}
+}
+
+func TestStructuredImagesWarnIncompatibleCNI(t *testing.T) {
+	logs := captureLogs(t, "0")
+	if _, err := StructuredImagesForVersion("", semver.MustParse("1.24.0"), ImageOptions{CNI: "calico", NoStorageProvisioner: true}); err != nil {
+		t.Fatalf("StructuredImagesForVersion: %v", err)
+	}
+	klog.Flush()
+	if !strings.Contains(logs.String(), "calico "+calicoVersion+" supports Kubernetes") {
+		t.Errorf("logs = %q, want a warning that calico doesn't support Kubernetes v1.24.0", logs.String())
+	}
+}
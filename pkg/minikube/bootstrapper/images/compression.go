@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// CompressionGzip prefers the Docker manifests, whose layers are always gzip compressed
+	CompressionGzip = "gzip"
+	// CompressionZstd prefers the OCI manifests, which may reference zstd compressed layers
+	CompressionZstd = "zstd"
+)
+
+// compressionPreference is the layer compression the manifest requests ask the registry for, empty leaves it to the registry
+var compressionPreference string
+
+// SetCompression sets the layer compression, gzip or zstd, the manifests are requested for. Empty restores the default of leaving it to the registry.
+func SetCompression(compression string) error {
+	switch compression {
+	case "", CompressionGzip, CompressionZstd:
+		compressionPreference = compression
+		return nil
+	}
+	return fmt.Errorf("unsupported compression %q, expected %s or %s", compression, CompressionGzip, CompressionZstd)
+}
+
+// manifestAccept returns the Accept header of manifest requests preferring the media types of compression
+func manifestAccept(compression string) string {
+	docker := []types.MediaType{types.DockerManifestList, types.DockerManifestSchema2}
+	oci := []types.MediaType{types.OCIImageIndex, types.OCIManifestSchema1}
+	preferred, fallback := docker, oci
+	if compression == CompressionZstd {
+		preferred, fallback = oci, docker
+	}
+	accept := []string{}
+	for _, mt := range preferred {
+		accept = append(accept, string(mt))
+	}
+	for _, mt := range fallback {
+		accept = append(accept, string(mt)+";q=0.5")
+	}
+	return strings.Join(accept, ", ")
+}
+
+// PullTransport returns the transport images are pulled from registries with, asking for the manifests of the compression
+// preference and reaching registries with the TLS settings of the tag lookups. Pulls aren't rate limited.
+func PullTransport() http.RoundTripper {
+	return withCompressionPreference(registryTransport())
+}
+
+// withCompressionPreference returns t, asking for the manifests of compressionPreference if it is set
+func withCompressionPreference(t http.RoundTripper) http.RoundTripper {
+	if compressionPreference == "" {
+		return t
+	}
+	return &compressionTransport{next: t, accept: manifestAccept(compressionPreference)}
+}
+
+// compressionTransport replaces the Accept header of manifest requests, other requests are left as is
+type compressionTransport struct {
+	next   http.RoundTripper
+	accept string
+}
+
+// RoundTrip sends req with the Accept header of the compression preference if it is a manifest request
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.Contains(req.URL.Path, "/manifests/") {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept", t.accept)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newAcceptRegistry returns a registry serving any manifest, recording the Accept header of the manifest requests in accept
+func newAcceptRegistry(accept *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") {
+			*accept = r.Header.Get("Accept")
+		}
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.list.v2+json")
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+		w.Header().Set("Content-Length", "0")
+	}))
+}
+
+func TestSetCompression(t *testing.T) {
+	for _, c := range []string{"", CompressionGzip, CompressionZstd} {
+		if err := SetCompression(c); err != nil {
+			t.Errorf("SetCompression(%q) error: %v", c, err)
+		}
+	}
+	if err := SetCompression("lz4"); err == nil {
+		t.Errorf("SetCompression(%q) expected an error", "lz4")
+	}
+	if err := SetCompression(""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompressionAcceptHeader(t *testing.T) {
+	tests := []struct {
+		compression string
+		want        string
+	}{
+		{CompressionGzip, "application/vnd.docker.distribution.manifest.list.v2+json, application/vnd.docker.distribution.manifest.v2+json, " +
+			"application/vnd.oci.image.index.v1+json;q=0.5, application/vnd.oci.image.manifest.v1+json;q=0.5"},
+		{CompressionZstd, "application/vnd.oci.image.index.v1+json, application/vnd.oci.image.manifest.v1+json, " +
+			"application/vnd.docker.distribution.manifest.list.v2+json;q=0.5, application/vnd.docker.distribution.manifest.v2+json;q=0.5"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.compression, func(t *testing.T) {
+			var accept string
+			registry := newAcceptRegistry(&accept)
+			defer registry.Close()
+
+			if err := SetCompression(tc.compression); err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := SetCompression(""); err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			img := strings.TrimPrefix(registry.URL, "http://") + "/pause:3.7"
+			if _, err := headImage(context.Background(), img); err != nil {
+				t.Fatalf("headImage(%s): %v", img, err)
+			}
+			if accept != tc.want {
+				t.Errorf("manifest Accept = %q, want %q", accept, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompressionDefaultAcceptHeader(t *testing.T) {
+	var accept string
+	registry := newAcceptRegistry(&accept)
+	defer registry.Close()
+
+	img := strings.TrimPrefix(registry.URL, "http://") + "/pause:3.7"
+	if _, err := headImage(context.Background(), img); err != nil {
+		t.Fatalf("headImage(%s): %v", img, err)
+	}
+	if strings.Contains(accept, ";q=") {
+		t.Errorf("manifest Accept = %q, want the go-containerregistry default", accept)
+	}
+}
+
+func TestPullTransportAcceptHeader(t *testing.T) {
+	var accept string
+	registry := newAcceptRegistry(&accept)
+	defer registry.Close()
+
+	if err := SetCompression(CompressionGzip); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := SetCompression(""); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	client := &http.Client{Transport: PullTransport()}
+	resp, err := client.Get(registry.URL + "/v2/pause/manifests/3.7")
+	if err != nil {
+		t.Fatalf("GET manifest: %v", err)
+	}
+	resp.Body.Close()
+	if want := manifestAccept(CompressionGzip); accept != want {
+		t.Errorf("manifest Accept = %q, want %q", accept, want)
+	}
+}
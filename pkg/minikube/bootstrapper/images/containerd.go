@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/blang/semver/v4"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+// ContainerdMirror returns the mirror containerd pulls the Kubernetes images of k8sVersion from, as configured by
+// the hosts.toml of their registry in certsDir (e.g. /etc/containerd/certs.d), or an empty mirror if there is none.
+// Passing it as the mirror of ImagesForVersion makes preloaded references agree with the images containerd pulls.
+func ContainerdMirror(certsDir string, k8sVersion semver.Version) (string, error) {
+	registry := kubernetesRepo("", k8sVersion)
+	p := filepath.Join(certsDir, registry, "hosts.toml")
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "read containerd hosts")
+	}
+	mirror, err := parseContainerdHosts(data)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse %s", p)
+	}
+	if mirror == registry {
+		return "", nil
+	}
+	return mirror, nil
+}
+
+// parseContainerdHosts returns the first host of a containerd hosts.toml which images can be pulled from,
+// falling back to its server. See https://github.com/containerd/containerd/blob/main/docs/hosts.md
+func parseContainerdHosts(data []byte) (string, error) {
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return "", err
+	}
+
+	if hosts, ok := tree.Get("host").(*toml.Tree); ok {
+		type host struct {
+			url  string
+			line int
+		}
+		ordered := []host{}
+		for _, url := range hosts.Keys() {
+			h, ok := hosts.GetPath([]string{url}).(*toml.Tree)
+			if !ok || !canPull(h) {
+				continue
+			}
+			ordered = append(ordered, host{url, h.Position().Line})
+		}
+		// hosts are tried by containerd in the order they are listed
+		sort.Slice(ordered, func(i, j int) bool { return ordered[i].line < ordered[j].line })
+		if len(ordered) > 0 {
+			return normalizeMirror(ordered[0].url), nil
+		}
+	}
+
+	if server, ok := tree.Get("server").(string); ok && server != "" {
+		return normalizeMirror(server), nil
+	}
+	return "", fmt.Errorf("no host to pull from")
+}
+
+// canPull reports whether the capabilities of a containerd host allow pulls, hosts without capabilities allow all of them
+func canPull(h *toml.Tree) bool {
+	caps, ok := h.Get("capabilities").([]interface{})
+	if !ok {
+		return !h.Has("capabilities")
+	}
+	for _, c := range caps {
+		if c == "pull" {
+			return true
+		}
+	}
+	return false
+}
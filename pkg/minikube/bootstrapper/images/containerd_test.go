@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestParseContainerdHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   string
+		want    string
+		wantErr bool
+	}{
+		{"first pull host", `
+server = "https://k8s.gcr.io"
+
+[host."https://push.corp:5000"]
+  capabilities = ["push"]
+
+[host."https://mirror.corp:5000/k8s"]
+  capabilities = ["pull", "resolve"]
+
+[host."https://fallback.corp"]
+  capabilities = ["pull", "resolve"]
+`, "mirror.corp:5000/k8s", false},
+		{"host without capabilities", `
+[host."https://mirror.corp"]
+  skip_verify = true
+`, "mirror.corp", false},
+		{"server only", `server = "https://k8s.gcr.io"`, "k8s.gcr.io", false},
+		{"no hosts", `[host."https://push.corp"]
+  capabilities = ["push"]
+`, "", true},
+		{"malformed", `server = `, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseContainerdHosts([]byte(tc.hosts))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseContainerdHosts() error = %v, want error: %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("parseContainerdHosts() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestContainerdMirror(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "k8s.gcr.io"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	hosts := `server = "https://k8s.gcr.io"
+
+[host."https://mirror.corp:5000"]
+  capabilities = ["pull", "resolve"]
+`
+	if err := os.WriteFile(filepath.Join(dir, "k8s.gcr.io", "hosts.toml"), []byte(hosts), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.24.0", "mirror.corp:5000"},
+		// registry.k8s.io has no hosts.toml
+		{"1.25.0", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version, func(t *testing.T) {
+			got, err := ContainerdMirror(dir, semver.MustParse(tc.version))
+			if err != nil {
+				t.Fatalf("ContainerdMirror: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("ContainerdMirror() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"sort"
+
+	"github.com/blang/semver/v4"
+)
+
+// Retag is an image whose repository is required by both sides of an ImageDiff, with a different tag or digest
+type Retag struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ImageDiff is how the required images changed, sorted by reference. An image is retagged rather than
+// removed and added if only its tag or digest changed.
+type ImageDiff struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Retagged []Retag  `json:"retagged"`
+}
+
+// Empty reports whether the images didn't change
+func (d ImageDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Retagged) == 0
+}
+
+// DiffImages returns how the images to differ from the images from
+func DiffImages(from []string, to []string) ImageDiff {
+	before := byRepository(from)
+	after := byRepository(to)
+	diff := ImageDiff{Added: []string{}, Removed: []string{}, Retagged: []Retag{}}
+	for repo, ref := range before {
+		next, ok := after[repo]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, ref)
+		case qualify(next) != qualify(ref):
+			diff.Retagged = append(diff.Retagged, Retag{From: ref, To: next})
+		}
+	}
+	for repo, ref := range after {
+		if _, ok := before[repo]; !ok {
+			diff.Added = append(diff.Added, ref)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Retagged, func(i, j int) bool { return diff.Retagged[i].From < diff.Retagged[j].From })
+	return diff
+}
+
+// DiffVersions returns how the images of ImagesForVersion change from Kubernetes version from to version to
+func DiffVersions(repo string, from semver.Version, to semver.Version, opts ImageOptions) (ImageDiff, error) {
+	before, err := ImagesForVersion(repo, from, opts)
+	if err != nil {
+		return ImageDiff{}, err
+	}
+	after, err := ImagesForVersion(repo, to, opts)
+	if err != nil {
+		return ImageDiff{}, err
+	}
+	return DiffImages(before, after), nil
+}
+
+// byRepository returns imgs keyed by their registry and repository, Docker Hub images referenced without a registry included
+func byRepository(imgs []string) map[string]string {
+	repos := map[string]string{}
+	for _, ref := range imgs {
+		img := parseImage(qualify(ref), "")
+		repos[img.Registry+"/"+img.Repo] = ref
+	}
+	return repos
+}
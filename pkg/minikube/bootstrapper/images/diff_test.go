@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffVersions(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+
+	got, err := DiffVersions("", semver.MustParse("1.20.0"), semver.MustParse("1.21.0"), ImageOptions{NoStorageProvisioner: true})
+	if err != nil {
+		t.Fatalf("DiffVersions: %v", err)
+	}
+	want := ImageDiff{
+		// coredns moved to coredns/coredns in v1.21
+		Added:   []string{"k8s.gcr.io/coredns/coredns:v1.8.0"},
+		Removed: []string{"k8s.gcr.io/coredns:1.7.0"},
+		Retagged: []Retag{
+			{From: "k8s.gcr.io/kube-apiserver:v1.20.0", To: "k8s.gcr.io/kube-apiserver:v1.21.0"},
+			{From: "k8s.gcr.io/kube-controller-manager:v1.20.0", To: "k8s.gcr.io/kube-controller-manager:v1.21.0"},
+			{From: "k8s.gcr.io/kube-proxy:v1.20.0", To: "k8s.gcr.io/kube-proxy:v1.21.0"},
+			{From: "k8s.gcr.io/kube-scheduler:v1.20.0", To: "k8s.gcr.io/kube-scheduler:v1.21.0"},
+			{From: "k8s.gcr.io/pause:3.2", To: "k8s.gcr.io/pause:3.4.1"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("image diff mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffImages(t *testing.T) {
+	from := []string{"k8s.gcr.io/etcd:3.5.0-0", "k8s.gcr.io/pause:3.5", "docker.io/kindest/kindnetd:v20210326-1e038dc5", "kubernetesui/dashboard:v2.6.0"}
+	to := []string{"k8s.gcr.io/metrics-server/metrics-server:v0.6.1", "k8s.gcr.io/pause:3.5", "k8s.gcr.io/etcd:3.5.0-0@sha256:9ce33ba33d8e738a5b85ed50b5080ac746deceed4a7496c550927a7a19ca3b6d", "kindest/kindnetd:v20210326-1e038dc5"}
+	want := ImageDiff{
+		// a Docker Hub image referenced without its registry is the same image
+		Added:    []string{"k8s.gcr.io/metrics-server/metrics-server:v0.6.1"},
+		Removed:  []string{"kubernetesui/dashboard:v2.6.0"},
+		Retagged: []Retag{{From: "k8s.gcr.io/etcd:3.5.0-0", To: "k8s.gcr.io/etcd:3.5.0-0@sha256:9ce33ba33d8e738a5b85ed50b5080ac746deceed4a7496c550927a7a19ca3b6d"}},
+	}
+	if diff := cmp.Diff(want, DiffImages(from, to)); diff != "" {
+		t.Errorf("image diff mismatch (-want +got):\n%s", diff)
+	}
+	if d := DiffImages(from, from); !d.Empty() {
+		t.Errorf("DiffImages of the same images = %+v, want no changes", d)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// digestExpression matches an image digest, such as sha256:<64 hex characters>
+var digestExpression = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// imageDigests maps image repositories, such as k8s.gcr.io/etcd, to the digest their image is pinned to
+var imageDigests map[string]string
+
+// SetImageDigests pins the image of every repository in digests, such as k8s.gcr.io/etcd, to its digest instead of its tag.
+// A nil map restores tags for every image.
+func SetImageDigests(digests map[string]string) error {
+	for repo, digest := range digests {
+		if !digestExpression.MatchString(digest) {
+			return fmt.Errorf("invalid digest %q for %s: expected sha256:<64 hex characters>", digest, repo)
+		}
+	}
+	imageDigests = digests
+	return nil
+}
+
+// LoadImageDigests reads the JSON object mapping image repositories to their digest at path
+func LoadImageDigests(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading image digests")
+	}
+	digests := map[string]string{}
+	if err := json.Unmarshal(data, &digests); err != nil {
+		return nil, errors.Wrapf(err, "parsing image digests %s", path)
+	}
+	return digests, nil
+}
+
+// digestsFor returns the image digests of opts, falling back to the ones given to SetImageDigests
+func digestsFor(opts ImageOptions) map[string]string {
+	if opts.Digests != nil {
+		return opts.Digests
+	}
+	return imageDigests
+}
+
+// withDigest returns ref as repo@digest if digests pins its repository, or ref unchanged otherwise
+func withDigest(ref string, digests map[string]string) string {
+	if len(digests) == 0 || strings.Contains(ref, "@") {
+		return ref
+	}
+	repo := ref
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repo = ref[:i]
+	}
+	if digest, ok := digests[repo]; ok {
+		return repo + "@" + digest
+	}
+	return ref
+}
+
+// withDigests returns imgs after withDigest
+func withDigests(imgs []string, digests map[string]string) []string {
+	if len(digests) == 0 {
+		return imgs
+	}
+	pinned := make([]string, 0, len(imgs))
+	for _, img := range imgs {
+		pinned = append(pinned, withDigest(img, digests))
+	}
+	return pinned
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+const etcdDigest = "sha256:9ce33ba33d8e738a5b85ed50b5080ac746deceed4a7496c550927a7a19ca3b6d"
+
+func TestEssentialsDigests(t *testing.T) {
+	v := semver.MustParse("1.22.0")
+	defaults := essentials("k8s.gcr.io", v)
+	digests := map[string]string{"k8s.gcr.io/etcd": etcdDigest}
+
+	want := []string{}
+	for _, img := range defaults {
+		if strings.HasPrefix(img, "k8s.gcr.io/etcd:") {
+			img = "k8s.gcr.io/etcd@" + etcdDigest
+		}
+		want = append(want, img)
+	}
+
+	got := essentialsWithOptions("k8s.gcr.io", v, ImageOptions{Digests: digests})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("images mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := SetImageDigests(digests); err != nil {
+		t.Fatalf("SetImageDigests: %v", err)
+	}
+	got = essentials("k8s.gcr.io", v)
+	if err := SetImageDigests(nil); err != nil {
+		t.Fatalf("SetImageDigests: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("images with global digests mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(defaults, essentials("k8s.gcr.io", v)); diff != "" {
+		t.Errorf("default images mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPauseDigest(t *testing.T) {
+	v := semver.MustParse("1.22.0")
+	if err := SetImageDigests(map[string]string{"k8s.gcr.io/pause": etcdDigest}); err != nil {
+		t.Fatalf("SetImageDigests: %v", err)
+	}
+	got := Pause(v, "")
+	if err := SetImageDigests(nil); err != nil {
+		t.Fatalf("SetImageDigests: %v", err)
+	}
+
+	if want := "k8s.gcr.io/pause@" + etcdDigest; got != want {
+		t.Errorf("Pause() = %s, want %s", got, want)
+	}
+}
+
+func TestSetImageDigestsInvalid(t *testing.T) {
+	for _, digest := range []string{"", "latest", "sha256:abc", "md5:" + strings.Repeat("a", 64)} {
+		if err := SetImageDigests(map[string]string{"k8s.gcr.io/etcd": digest}); err == nil {
+			t.Errorf("SetImageDigests(%q) = nil, want error", digest)
+		}
+	}
+	if imageDigests != nil {
+		t.Errorf("imageDigests = %v after invalid input, want it unchanged", imageDigests)
+	}
+}
+
+func TestLoadImageDigests(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "digests.json")
+	if err := os.WriteFile(valid, []byte(`{"k8s.gcr.io/etcd": "`+etcdDigest+`"}`), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	malformed := filepath.Join(dir, "malformed.json")
+	if err := os.WriteFile(malformed, []byte(`["k8s.gcr.io/etcd"]`), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	got, err := LoadImageDigests(valid)
+	if err != nil {
+		t.Fatalf("LoadImageDigests: %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"k8s.gcr.io/etcd": etcdDigest}, got); diff != "" {
+		t.Errorf("digests mismatch (-want +got):\n%s", diff)
+	}
+	for _, path := range []string{malformed, filepath.Join(dir, "missing.json")} {
+		if _, err := LoadImageDigests(path); err == nil {
+			t.Errorf("LoadImageDigests(%s) = nil error, want error", path)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import "errors"
+
+var (
+	// ErrUnknownVersion is returned for Kubernetes versions whose images can't be determined, such as unreleased minor versions
+	ErrUnknownVersion = errors.New("unknown Kubernetes version")
+	// ErrInvalidMirror is returned for image repositories which aren't of the host[:port][/path] form
+	ErrInvalidMirror = errors.New("invalid mirror")
+	// ErrIncompatibleCNI is returned when the version of a CNI minikube pins doesn't claim support for the Kubernetes version
+	ErrIncompatibleCNI = errors.New("incompatible CNI")
+	// ErrRegistryUnreachable is returned when a registry can't be reached, or fails with a server side error, so that retrying may succeed
+	ErrRegistryUnreachable = errors.New("registry unreachable")
+	// ErrRegistryNotAllowed is returned when a required image is hosted on a registry the registry policy denies, or doesn't allow
+	ErrRegistryNotAllowed = errors.New("registry not allowed")
+)
+
+// kindError is an error of one of the kinds above, which keeps the message of the error it classifies.
+// errors.Is matches it against its kind, and errors.As against the error it classifies.
+type kindError struct {
+	kind error
+	err  error
+}
+
+// withKind classifies err as kind
+func withKind(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// Error returns the message of the classified error
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the classified error
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is the kind of e
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
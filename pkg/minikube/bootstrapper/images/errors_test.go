@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKubeadmErrorKinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirror  string
+		version string
+		opts    ImageOptions
+		kind    error
+	}{
+		{"TooNew", "", "v2.0.0", ImageOptions{}, ErrUnknownVersion},
+		{"TooOld", "", "v1.11.0", ImageOptions{}, ErrUnknownVersion},
+		{"MalformedMirror", "registry.corp:99999", "v1.24.0", ImageOptions{}, ErrInvalidMirror},
+		{"MalformedComponentRepo", "", "v1.24.0", ImageOptions{ComponentRepos: map[string]string{"etcd": "etcd.corp/"}}, nil},
+		{"MalformedComponentRepoPath", "", "v1.24.0", ImageOptions{ComponentRepos: map[string]string{"etcd": "etcd.corp/-bad"}}, ErrInvalidMirror},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := KubeadmWithOptions(tc.mirror, tc.version, tc.opts)
+			if tc.kind == nil {
+				if err != nil {
+					t.Fatalf("KubeadmWithOptions: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.kind) {
+				t.Errorf("KubeadmWithOptions error = %v, want an error matching %v", err, tc.kind)
+			}
+			for _, other := range []error{ErrUnknownVersion, ErrInvalidMirror, ErrRegistryUnreachable} {
+				if other != tc.kind && errors.Is(err, other) {
+					t.Errorf("KubeadmWithOptions error %v also matches %v", err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorKindKeepsMessage(t *testing.T) {
+	err := ValidateMirror("registry.corp:99999")
+	if want := `invalid mirror "registry.corp:99999": port 99999 out of range`; err == nil || err.Error() != want {
+		t.Errorf("ValidateMirror error = %v, want %s", err, want)
+	}
+}
+
+func TestLatestTagRegistryUnreachable(t *testing.T) {
+	useTagCache(t, "")
+	defer func(d time.Duration) { tagLookupInterval = d }(tagLookupInterval)
+	tagLookupInterval = time.Millisecond
+
+	tests := []struct {
+		name        string
+		status      int
+		closed      bool
+		unreachable bool
+	}{
+		{name: "ServerError", status: http.StatusServiceUnavailable, unreachable: true},
+		{name: "TooManyRequests", status: http.StatusTooManyRequests, unreachable: true},
+		{name: "Closed", closed: true, unreachable: true},
+		{name: "NotFound", status: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			if tc.closed {
+				server.Close()
+			} else {
+				defer server.Close()
+			}
+
+			_, err := findLatestTagFromRepositoryE(context.Background(), server.URL, "v1.8.6")
+			if err == nil {
+				t.Fatalf("lookup succeeded, want an error")
+			}
+			if got := errors.Is(err, ErrRegistryUnreachable); got != tc.unreachable {
+				t.Errorf("errors.Is(%v, ErrRegistryUnreachable) = %t, want %t", err, got, tc.unreachable)
+			}
+		})
+	}
+}
+
+func TestVerifyImagesExistRegistryUnreachable(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	for _, server := range []*httptest.Server{failing, closed} {
+		img := strings.TrimPrefix(server.URL, "http://") + "/pause:3.7"
+		_, err := VerifyImagesExist(context.Background(), []string{img})
+		if !errors.Is(err, ErrRegistryUnreachable) {
+			t.Errorf("VerifyImagesExist(%s) error = %v, want an error matching ErrRegistryUnreachable", img, err)
+		}
+	}
+}
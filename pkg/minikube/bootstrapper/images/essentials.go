@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/blang/semver/v4"
+)
+
+// essentialImage is an essential image relative to the repository it is pulled from
+type essentialImage struct {
+	// ref is the path:tag of the image in its repository
+	ref string
+	// archSuffixed is whether the tag of the image is suffixed by the architecture, see forArch
+	archSuffixed bool
+}
+
+// essentialTemplate is the repository independent part of the essentials of a Kubernetes version on an architecture
+type essentialTemplate struct {
+	components    []essentialImage // kube-apiserver, kube-controller-manager, kube-scheduler and kube-proxy, in this order
+	pause         essentialImage
+	etcd          essentialImage
+	coreDNS       essentialImage // in the path coreDNSImageName gives
+	flatCoreDNS   essentialImage // in <repository>/coredns, for SetFlatCoreDNSPath
+	nestedCoreDNS bool           // whether coreDNS isn't flatCoreDNS
+}
+
+// templateKey is the major.minor.patch of a released Kubernetes version, and the architecture its essentials are for
+type templateKey struct {
+	major uint64
+	minor uint64
+	patch uint64
+	arch  string
+}
+
+// essentialTemplates caches the essentialTemplate of each Kubernetes version and architecture essentials was called for, by templateKey
+var essentialTemplates sync.Map
+
+// newEssentialImage returns the essential image ref, which is relative to its repository
+func newEssentialImage(ref string) essentialImage {
+	return essentialImage{ref: ref, archSuffixed: archSuffixedImages[path.Base(parseImage(ref, "").Repo)]}
+}
+
+// essentialTemplateFor returns the essentialTemplate of v on arch, or false if its essentials depend on
+// more than the repository they are pulled from: pre-releases, tag overrides and tags looked up in the registry
+func essentialTemplateFor(v semver.Version, arch string) (*essentialTemplate, bool) {
+	if len(v.Pre) > 0 || len(v.Build) > 0 || pauseVersion != "" || etcdVersion != "" || coreDNSVersion != "" || coreDNSLatestPatch {
+		return nil, false
+	}
+	key := templateKey{major: v.Major, minor: v.Minor, patch: v.Patch, arch: arch}
+	if t, ok := essentialTemplates.Load(key); ok {
+		return t.(*essentialTemplate), true
+	}
+	tags, err := kubeadmImageTags(v)
+	if err != nil {
+		return nil, false
+	}
+	coreDNSName := coreDNSImageName(v)
+	pauseTag, ok := tags["pause"]
+	if !ok {
+		return nil, false
+	}
+	etcdTag, ok := tags["etcd"]
+	if !ok {
+		return nil, false
+	}
+	coreDNSTag, ok := archTag(tags, coreDNSName, arch)
+	if !ok {
+		return nil, false
+	}
+	t := &essentialTemplate{
+		pause:         newEssentialImage("pause:" + pauseTag),
+		etcd:          newEssentialImage("etcd:" + etcdTag),
+		coreDNS:       newEssentialImage(coreDNSName + ":" + coreDNSTag),
+		flatCoreDNS:   newEssentialImage("coredns:" + coreDNSTag),
+		nestedCoreDNS: coreDNSName != "coredns",
+	}
+	for _, name := range []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kube-proxy"} {
+		t.components = append(t.components, newEssentialImage(name+":v"+v.String()))
+	}
+	actual, _ := essentialTemplates.LoadOrStore(key, t)
+	return actual.(*essentialTemplate), true
+}
+
+// templatedEssentials returns the essentials of v from its essentialTemplate, or false if essentialsWithOptions
+// has to build them, because v has no template or opts pulls images from per-component repositories or for another OS
+func templatedEssentials(mirror string, v semver.Version, opts ImageOptions) ([]string, bool) {
+	if len(opts.ComponentRepos) > 0 || targetOS(opts) != "linux" {
+		return nil, false
+	}
+	repo := kubernetesRepo(mirror, v)
+	// path.Join(repo, ref) is repo + "/" + ref for clean repositories only
+	if repo == "" || repo == "." || strings.HasSuffix(repo, "/") || path.Clean(repo) != repo {
+		return nil, false
+	}
+	arch := targetArch(opts)
+	t, ok := essentialTemplateFor(v, arch)
+	if !ok {
+		return nil, false
+	}
+	prefixedIn := func(repo string, img essentialImage) string {
+		if img.archSuffixed {
+			return repo + "/" + img.ref + "-" + arch
+		}
+		return repo + "/" + img.ref
+	}
+	prefixed := func(img essentialImage) string {
+		return prefixedIn(repo, img)
+	}
+
+	components := t.components
+	if opts.KubeProxyless {
+		components = components[:3]
+	}
+	imgs := make([]string, 0, len(components)+3)
+	for _, img := range components {
+		imgs = append(imgs, prefixed(img))
+	}
+	if opts.Pause != "" {
+		imgs = append(imgs, forArch(opts.Pause, arch))
+	} else {
+		// the pause repository is normalized and validated, or repo itself
+		imgs = append(imgs, prefixedIn(pauseRepo(pauseMirror(mirror), v), t.pause))
+	}
+	imgs = append(imgs, prefixed(t.etcd))
+	if t.nestedCoreDNS && flatCoreDNSPath && repo != DefaultKubernetesRepo && repo != RegistryK8sIORepo {
+		imgs = append(imgs, prefixed(t.flatCoreDNS))
+	} else {
+		imgs = append(imgs, prefixed(t.coreDNS))
+	}
+	return withDigests(imgs, digestsFor(opts)), true
+}
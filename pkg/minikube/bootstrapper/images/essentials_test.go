@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTemplatedEssentials(t *testing.T) {
+	useTagCache(t, "")
+	defer SetFlatCoreDNSPath(false)
+
+	mirrors := []string{"", "k8s.gcr.io", "registry.k8s.io", "registry.corp", "registry.corp/", "https://registry.corp/k8s", "localhost:5000"}
+	options := map[string]ImageOptions{
+		"default":       {},
+		"proxyless":     {KubeProxyless: true},
+		"arm64":         {Arch: "arm64"},
+		"pause":         {Pause: "registry.corp/pause:3.9"},
+		"digests":       {Digests: map[string]string{"registry.corp/etcd": etcdDigest, "k8s.gcr.io/pause": etcdDigest}},
+		"windows":       {OS: "windows"},
+		"componentRepo": {ComponentRepos: map[string]string{"etcd": "etcd.corp"}},
+	}
+	versions := []semver.Version{semver.MustParse("1.24.0-rc.1")}
+	for _, v := range KnownVersions() {
+		patched := v
+		patched.Patch = 5
+		versions = append(versions, v, patched)
+	}
+
+	for _, flat := range []bool{false, true} {
+		SetFlatCoreDNSPath(flat)
+		for _, v := range versions {
+			for _, mirror := range mirrors {
+				for name, opts := range options {
+					t.Run(fmt.Sprintf("%s/%q/%s/flat=%t", v, mirror, name, flat), func(t *testing.T) {
+						want := buildEssentials(mirror, v, opts)
+						if diff := cmp.Diff(want, essentialsWithOptions(mirror, v, opts)); diff != "" {
+							t.Errorf("essentialsWithOptions(%q, %s) mismatch (-built +templated):\n%s", mirror, v, diff)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestTemplatedEssentialsFallback(t *testing.T) {
+	useTagCache(t, "")
+
+	v := semver.MustParse("1.24.0")
+	if _, ok := templatedEssentials("registry.corp", v, ImageOptions{}); !ok {
+		t.Errorf("templatedEssentials(%s) isn't templated", v)
+	}
+	if _, ok := templatedEssentials("registry.corp", semver.MustParse("1.24.0-rc.1"), ImageOptions{}); ok {
+		t.Error("templatedEssentials of a pre-release is templated, want it built")
+	}
+
+	if err := SetEtcdVersion("3.5.9-0"); err != nil {
+		t.Fatalf("SetEtcdVersion: %v", err)
+	}
+	defer func() { _ = SetEtcdVersion("") }()
+	if _, ok := templatedEssentials("registry.corp", v, ImageOptions{}); ok {
+		t.Error("templatedEssentials with an etcd override is templated, want it built")
+	}
+	want := buildEssentials("registry.corp", v, ImageOptions{})
+	if diff := cmp.Diff(want, essentials("registry.corp", v)); diff != "" {
+		t.Errorf("essentials with an etcd override mismatch (-built +got):\n%s", diff)
+	}
+}
+
+func BenchmarkEssentials(b *testing.B) {
+	v := semver.MustParse("1.24.0")
+	for name, essentials := range map[string]func(string, semver.Version, ImageOptions) []string{
+		"built":     buildEssentials,
+		"templated": essentialsWithOptions,
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				essentials("registry.corp", v, ImageOptions{})
+			}
+		})
+	}
+}
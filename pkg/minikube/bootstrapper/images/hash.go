@@ -0,0 +1,46 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// ImageSetHash returns a stable SHA256 hex digest of the set of images, suitable as a cache key.
+// The digest doesn't depend on the order of images or duplicates, but changes with any repository, tag or digest.
+func ImageSetHash(images []string) string {
+	seen := map[string]bool{}
+	refs := []string{}
+	for _, img := range images {
+		// hash the fully qualified reference, so calico/node:v3.20.0 and docker.io/calico/node:v3.20.0 are the same image
+		key := img
+		if ref, err := name.ParseReference(img, name.WeakValidation); err == nil {
+			key = ref.Name()
+		}
+		if !seen[key] {
+			seen[key] = true
+			refs = append(refs, key)
+		}
+	}
+	sort.Strings(refs)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(refs, "\n"))))
+}
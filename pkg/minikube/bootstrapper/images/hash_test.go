@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+)
+
+func TestImageSetHash(t *testing.T) {
+	imgs := []string{
+		"k8s.gcr.io/kube-apiserver:v1.24.0",
+		"k8s.gcr.io/etcd:3.5.3-0",
+		"k8s.gcr.io/pause:3.7",
+		"calico/node:v3.20.0",
+	}
+	want := ImageSetHash(imgs)
+	if len(want) != 64 {
+		t.Errorf("ImageSetHash = %q, want a SHA256 hex digest", want)
+	}
+
+	same := map[string][]string{
+		"reordered":  {"k8s.gcr.io/pause:3.7", "calico/node:v3.20.0", "k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/kube-apiserver:v1.24.0"},
+		"duplicated": {"k8s.gcr.io/kube-apiserver:v1.24.0", "k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/pause:3.7", "calico/node:v3.20.0", "k8s.gcr.io/pause:3.7"},
+		"qualified":  {"k8s.gcr.io/kube-apiserver:v1.24.0", "k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/pause:3.7", "docker.io/calico/node:v3.20.0"},
+	}
+	for name, set := range same {
+		if got := ImageSetHash(set); got != want {
+			t.Errorf("%s: ImageSetHash = %s, want %s", name, got, want)
+		}
+	}
+
+	different := map[string][]string{
+		"tag":    {"k8s.gcr.io/kube-apiserver:v1.24.0", "k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/pause:3.6", "calico/node:v3.20.0"},
+		"mirror": {"registry.k8s.io/kube-apiserver:v1.24.0", "k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/pause:3.7", "calico/node:v3.20.0"},
+		"subset": {"k8s.gcr.io/kube-apiserver:v1.24.0", "k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/pause:3.7"},
+	}
+	for name, set := range different {
+		if got := ImageSetHash(set); got == want {
+			t.Errorf("%s: ImageSetHash = %s, want it to differ", name, got)
+		}
+	}
+}
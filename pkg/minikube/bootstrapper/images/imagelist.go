@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// imageList replaces the images minikube computes for a cluster when set
+var imageList []string
+
+// SetImageList makes Kubeadm, ImagesForVersion and the functions built on them return imgs verbatim, whatever the
+// Kubernetes version, mirror and options. A nil imgs restores the images minikube computes.
+func SetImageList(imgs []string) error {
+	for _, img := range imgs {
+		if _, err := reference.ParseNormalizedNamed(img); err != nil {
+			return fmt.Errorf("invalid image reference %q: %v", img, err)
+		}
+	}
+	imageList = imgs
+	return nil
+}
+
+// LoadImageList reads the image references listed one per line at path, ignoring blank lines and # comments.
+// It fails on the first line which isn't a valid image reference.
+func LoadImageList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading image list")
+	}
+	imgs := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := reference.ParseNormalizedNamed(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid image reference %q: %v", path, n, line, err)
+		}
+		imgs = append(imgs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "reading image list %s", path)
+	}
+	if len(imgs) == 0 {
+		return nil, fmt.Errorf("image list %s lists no images", path)
+	}
+	return imgs, nil
+}
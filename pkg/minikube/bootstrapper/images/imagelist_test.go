@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeImageList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "images.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write image list: %v", err)
+	}
+	return path
+}
+
+func TestLoadImageList(t *testing.T) {
+	path := writeImageList(t, `# air-gapped images
+registry.corp/kube-apiserver:v1.24.0
+
+registry.corp/etcd@sha256:`+strings.Repeat("a", 64)+`
+  registry.corp/pause:3.7
+`)
+	got, err := LoadImageList(path)
+	if err != nil {
+		t.Fatalf("LoadImageList: %v", err)
+	}
+	want := []string{"registry.corp/kube-apiserver:v1.24.0", "registry.corp/etcd@sha256:" + strings.Repeat("a", 64), "registry.corp/pause:3.7"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("images mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadImageListInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"Malformed", "registry.corp/pause:3.7\nregistry.corp//etcd:3.5.3-0\nUPPER/case:1\n", `images.txt:2: invalid image reference "registry.corp//etcd:3.5.3-0"`},
+		{"Empty", "# nothing\n\n", "lists no images"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadImageList(writeImageList(t, tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("LoadImageList error = %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+	if _, err := LoadImageList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Errorf("LoadImageList of a missing file succeeded, want an error")
+	}
+}
+
+func TestSetImageList(t *testing.T) {
+	list := []string{"registry.corp/pause:3.7", "registry.corp/kube-apiserver:v1.24.0"}
+	if err := SetImageList(list); err != nil {
+		t.Fatalf("SetImageList: %v", err)
+	}
+	defer func() {
+		if err := SetImageList(nil); err != nil {
+			t.Fatalf("reset image list: %v", err)
+		}
+	}()
+
+	got, err := KubeadmWithOptions("k8s.gcr.io", "v1.24.0", ImageOptions{CNI: "calico", HA: true})
+	if err != nil {
+		t.Fatalf("KubeadmWithOptions: %v", err)
+	}
+	if diff := cmp.Diff(list, got); diff != "" {
+		t.Errorf("Kubeadm images mismatch (-want +got):\n%s", diff)
+	}
+	sorted, err := ImagesForVersion("", semver.MustParse("1.24.0"), ImageOptions{})
+	if err != nil {
+		t.Fatalf("ImagesForVersion: %v", err)
+	}
+	if diff := cmp.Diff([]string{list[1], list[0]}, sorted); diff != "" {
+		t.Errorf("ImagesForVersion mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := SetImageList([]string{"registry.corp/pause:3.7", "registry.corp//etcd"}); err == nil {
+		t.Errorf("SetImageList with a malformed reference succeeded, want an error")
+	}
+	if diff := cmp.Diff(list, imageList); diff != "" {
+		t.Errorf("image list changed by an invalid SetImageList (-want +got):\n%s", diff)
+	}
+}
@@ -18,166 +18,495 @@ limitations under the License.
 package images
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"path"
-
-	"k8s.io/klog/v2"
-
-	"k8s.io/minikube/pkg/minikube/constants"
+	"runtime"
+	"strings"
 
 	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
 
 	"k8s.io/minikube/pkg/version"
 )
 
+// the tags used for Kubernetes versions missing from the kubeadm images table, if the latest tag can't be looked up
 const (
-	// builds a docker v2 repository API call in the format https://k8s.gcr.io/v2/coredns/coredns/tags/list
-	tagURLTemplate = "https://%s/v2/%s/tags/list"
+	defaultPauseVersion   = "3.6"
+	defaultEtcdVersion    = "3.5.0-0"
+	defaultCoreDNSVersion = "v1.8.6"
 )
 
 // Pause returns the image name to pull for a given Kubernetes version
 func Pause(v semver.Version, mirror string) string {
+	return withDigest(pause(v, pauseMirror(mirror), false), imageDigests)
+}
+
+// pause returns the pause image for a given Kubernetes version, by tag. noLookup uses the last known good tag rather than looking up the latest one.
+func pause(v semver.Version, mirror string, noLookup bool) string {
 	// Note: changing this logic requires bumping the preload version
 	// Should match `PauseVersion` in:
 	// https://github.com/kubernetes/kubernetes/blob/master/cmd/kubeadm/app/constants/constants.go
 	// https://github.com/kubernetes/kubernetes/blob/master/cmd/kubeadm/app/constants/constants_unix.go
-	pv := "3.6"
+	pv := defaultPauseVersion
 	imageName := "pause"
-	majorMinorVersion := fmt.Sprintf("v%d.%d", v.Major, v.Minor)
 
-	if pVersion, ok := constants.KubeadmImages[majorMinorVersion][imageName]; ok {
+	if pVersion, ok := pinnedTag(v, imageName, pauseVersion, ""); ok {
+		tracePinned(imageName, pVersion, pauseVersion)
 		pv = pVersion
 	} else {
-		pv = findLatestTagFromRepository(fmt.Sprintf(tagURLTemplate, kubernetesRepo(mirror), imageName), pv)
+		pv = latestTag(v, pauseRepo(mirror, v), imageName, pv, noLookup)
+	}
+
+	return fmt.Sprintf("%s:%s", path.Join(pauseRepo(mirror, v), imageName), pv)
+}
+
+// pauseVersion, etcdVersion and coreDNSVersion override the image tags derived from the Kubernetes version when set
+var (
+	pauseVersion   string
+	etcdVersion    string
+	coreDNSVersion string
+)
+
+// SetPauseVersion overrides the pause image tag, an empty version restores the default
+func SetPauseVersion(v string) error {
+	return setVersion(&pauseVersion, v, "pause")
+}
+
+// SetEtcdVersion overrides the etcd image tag, an empty version restores the default
+func SetEtcdVersion(v string) error {
+	return setVersion(&etcdVersion, v, "etcd")
+}
+
+// SetCoreDNSVersion overrides the coredns image tag, an empty version restores the default
+func SetCoreDNSVersion(v string) error {
+	return setVersion(&coreDNSVersion, v, "coredns")
+}
+
+// setVersion validates v before storing it in override
+func setVersion(override *string, v string, imageName string) error {
+	if v != "" {
+		if err := ValidateTag(v); err != nil {
+			return errors.Wrapf(err, "%s image version", imageName)
+		}
 	}
+	*override = v
+	return nil
+}
 
-	return fmt.Sprintf("%s:%s", path.Join(kubernetesRepo(mirror), imageName), pv)
+// ValidateTag checks that an image tag looks like a version, e.g. 3.6 or v1.8.6
+func ValidateTag(tag string) error {
+	if strings.TrimSpace(tag) != tag {
+		return fmt.Errorf("invalid image tag %q: contains whitespace", tag)
+	}
+	if _, err := semver.ParseTolerant(tag); err != nil {
+		return fmt.Errorf("invalid image tag %q: %v", tag, err)
+	}
+	return nil
 }
 
 // essentials returns images needed too bootstrap a Kubernetes
 func essentials(mirror string, v semver.Version) []string {
+	return essentialsWithOptions(mirror, v, ImageOptions{})
+}
+
+// essentialsWithOptions returns images needed to bootstrap a Kubernetes with the given options
+func essentialsWithOptions(mirror string, v semver.Version, opts ImageOptions) []string {
+	// templated essentials skip the decisions traced by buildEssentials
+	if traceEnabled() {
+		traceEssentials(mirror, v, opts)
+	} else if imgs, ok := templatedEssentials(mirror, v, opts); ok {
+		return imgs
+	}
+	return buildEssentials(mirror, v, opts)
+}
+
+// traceEssentials traces the version and repository decisions of the essentials of v
+func traceEssentials(mirror string, v semver.Version, opts ImageOptions) {
+	if _, err := kubeadmImageTags(v); err != nil {
+		tracef(TraceVersion, "", "Kubernetes v%s: %v", v, err)
+	} else {
+		tracef(TraceVersion, "", "Kubernetes v%s matched the image tags pinned for v%d.%d", v, v.Major, v.Minor)
+	}
+	if m := normalizeMirror(mirror); m != "" {
+		tracef(TraceRepository, "", "pulling from the mirror %s", m)
+	} else {
+		tracef(TraceRepository, "", "pulling from %s, the default repository of Kubernetes v%s", DefaultKubernetesRepoForVersion(v), v)
+	}
+	if pauseRepository != "" {
+		tracef(TraceRepository, "pause", "pulling from the pause image repository %s", pauseRepository)
+	}
+	for name, repo := range opts.ComponentRepos {
+		tracef(TraceRepository, name, "pulling from the component repository %s", repo)
+	}
+}
+
+// buildEssentials returns the images of essentialsWithOptions, building each of them
+func buildEssentials(mirror string, v semver.Version, opts ImageOptions) []string {
+	repo := func(name string) string {
+		if r := opts.ComponentRepos[name]; r != "" {
+			return r
+		}
+		if name == "pause" {
+			return pauseMirror(mirror)
+		}
+		return mirror
+	}
+	if !opts.Offline {
+		prefetchLatestTags(essentialTagLookups(v, repo))
+	}
+
 	imgs := []string{
 		// use the same order as: `kubeadm config images list`
-		componentImage("kube-apiserver", v, mirror),
-		componentImage("kube-controller-manager", v, mirror),
-		componentImage("kube-scheduler", v, mirror),
-		componentImage("kube-proxy", v, mirror),
-		Pause(v, mirror),
-		etcd(v, mirror),
-		coreDNS(v, mirror),
+		componentImage("kube-apiserver", v, repo("kube-apiserver")),
+		componentImage("kube-controller-manager", v, repo("kube-controller-manager")),
+		componentImage("kube-scheduler", v, repo("kube-scheduler")),
+	}
+	if !opts.KubeProxyless {
+		imgs = append(imgs, componentImage("kube-proxy", v, repo("kube-proxy")))
+	}
+	pauseImage := opts.Pause
+	if pauseImage == "" {
+		pauseImage = forOS(pause(v, repo("pause"), opts.Offline), targetOS(opts))
 	}
-	return imgs
+	imgs = append(imgs,
+		pauseImage,
+		etcd(v, repo("etcd"), opts.Offline),
+		coreDNS(v, repo("coredns"), targetArch(opts), opts.Offline),
+	)
+	return withDigests(withArch(imgs, targetArch(opts)), digestsFor(opts))
+}
+
+// KubeProxyImage returns the kube-proxy image of v as essentials pulls it from repo, for patching the kube-proxy DaemonSet
+func KubeProxyImage(repo string, v semver.Version) string {
+	return withDigest(forArch(componentImage("kube-proxy", v, repo), targetArch(ImageOptions{})), imageDigests)
 }
 
 // componentImage returns a Kubernetes component image to pull
 func componentImage(name string, v semver.Version, mirror string) string {
-	return fmt.Sprintf("%s:v%s", path.Join(kubernetesRepo(mirror), name), v)
+	return fmt.Sprintf("%s:v%s", path.Join(kubernetesRepo(mirror, v), name), v)
+}
+
+// coreDNS returns the images used for CoreDNS on arch, noLookup uses the last known good tag rather than looking up the latest one
+func coreDNS(v semver.Version, mirror string, arch string, noLookup bool) string {
+	// Note: changing this logic requires bumping the preload version
+	// Should match `CoreDNSImageName` and `CoreDNSVersion` in
+	// https://github.com/kubernetes/kubernetes/blob/master/cmd/kubeadm/app/constants/constants.go
+	return fmt.Sprintf("%s:%s", path.Join(kubernetesRepo(mirror, v), coreDNSPath(v, mirror)), coreDNSTag(v, mirror, arch, noLookup))
 }
 
-// fixes 13136 by getting the latest image version from the k8s.gcr.io repository instead of hardcoded
-func findLatestTagFromRepository(url string, lastKnownGood string) string {
-	client := &http.Client{}
-	errorMsg := fmt.Sprintf("Failed to get latest image version for %s, reverting to version %s.", url, lastKnownGood)
+// CoreDNSVersion returns the tag of the coredns image of v, as essentials pulls it from the default repository for the architecture minikube runs on
+func CoreDNSVersion(v semver.Version) string {
+	return coreDNSTag(v, "", targetArch(ImageOptions{}), false)
+}
 
-	resp, err := client.Get(url)
+// coreDNSLatestPatch pulls the latest coredns patch release of the minor version pinned for the Kubernetes version
+var coreDNSLatestPatch bool
 
-	if err != nil || resp.StatusCode != http.StatusOK {
-		klog.Warningf("%s Error %v", errorMsg, err)
-		return lastKnownGood
-	}
-	defer resp.Body.Close()
+// SetCoreDNSLatestPatch sets whether the coredns tag pinned for a Kubernetes version is replaced by the latest patch release of its minor version.
+// Tags given to SetCoreDNSVersion are used as they are.
+func SetCoreDNSLatestPatch(latest bool) {
+	coreDNSLatestPatch = latest
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		klog.Warningf("%s Error %v", errorMsg, err)
-		return lastKnownGood
+// coreDNSTag returns the tag of the coredns image of v in mirror on arch, without looking it up if noLookup
+func coreDNSTag(v semver.Version, mirror string, arch string, noLookup bool) string {
+	if tag, ok := pinnedTag(v, coreDNSImageName(v), coreDNSVersion, arch); ok {
+		tracePinned(coreDNSImageName(v), tag, coreDNSVersion)
+		if coreDNSLatestPatch && coreDNSVersion == "" {
+			return latestPatchTag(kubernetesRepo(mirror, v), coreDNSPath(v, mirror), tag, noLookup)
+		}
+		return tag
 	}
+	return latestTag(v, kubernetesRepo(mirror, v), coreDNSPath(v, mirror), defaultCoreDNSVersion, noLookup)
+}
+
+// etcd returns the image used for etcd, noLookup uses the last known good tag rather than looking up the latest one
+func etcd(v semver.Version, mirror string, noLookup bool) string {
+	// Note: changing this logic requires bumping the preload version
+	// Should match `DefaultEtcdVersion` in:
+	// https://github.com/kubernetes/kubernetes/blob/master/cmd/kubeadm/app/constants/constants.go
+	return fmt.Sprintf("%s:%s", path.Join(kubernetesRepo(mirror, v), "etcd"), etcdTag(v, mirror, noLookup))
+}
 
-	type TagsResponse struct {
-		Name string   `json:"name"`
-		Tags []string `json:"tags"`
+// EtcdVersion returns the tag of the etcd image of v, as essentials pulls it from the default repository
+func EtcdVersion(v semver.Version) string {
+	return etcdTag(v, "", false)
+}
+
+// etcdTag returns the tag of the etcd image of v in mirror, without looking it up if noLookup
+func etcdTag(v semver.Version, mirror string, noLookup bool) string {
+	if tag, ok := pinnedTag(v, "etcd", etcdVersion, ""); ok {
+		tracePinned("etcd", tag, etcdVersion)
+		return tag
 	}
+	return latestTag(v, kubernetesRepo(mirror, v), "etcd", defaultEtcdVersion, noLookup)
+}
 
-	tags := TagsResponse{}
-	err = json.Unmarshal(body, &tags)
-	if err != nil || len(tags.Tags) < 1 {
-		klog.Warningf("%s Error %v", errorMsg, err)
-		return lastKnownGood
+// beforeNestedCoreDNS matches the Kubernetes versions pulling coredns from <repository>/coredns
+var beforeNestedCoreDNS = semver.MustParseRange("<1.21.0-alpha.1")
+
+// coreDNSImageName returns the name of the coredns image for v, which moved to coredns/coredns in v1.21
+func coreDNSImageName(v semver.Version) string {
+	if beforeNestedCoreDNS(v) {
+		return "coredns"
 	}
-	lastTagNum := len(tags.Tags) - 1
-	return tags.Tags[lastTagNum]
+	return "coredns/coredns"
 }
 
-// coreDNS returns the images used for CoreDNS
-func coreDNS(v semver.Version, mirror string) string {
-	// Note: changing this logic requires bumping the preload version
-	// Should match `CoreDNSImageName` and `CoreDNSVersion` in
-	// https://github.com/kubernetes/kubernetes/blob/master/cmd/kubeadm/app/constants/constants.go
+// flatCoreDNSPath uses the coredns path of versions before v1.21 in mirrors, for mirrors which can't hold nested paths
+var flatCoreDNSPath bool
 
-	imageName := "coredns/coredns"
-	cv := "v1.8.6"
-	if semver.MustParseRange("<1.21.0-alpha.1")(v) {
-		imageName = "coredns"
+// SetFlatCoreDNSPath sets whether the coredns image is pulled from <mirror>/coredns rather than <mirror>/coredns/coredns
+func SetFlatCoreDNSPath(flat bool) {
+	flatCoreDNSPath = flat
+}
+
+// coreDNSPath returns the path of the coredns image for v in mirror, the upstream repositories always use coreDNSImageName
+func coreDNSPath(v semver.Version, mirror string) string {
+	if repo := kubernetesRepo(mirror, v); flatCoreDNSPath && repo != DefaultKubernetesRepo && repo != RegistryK8sIORepo {
+		return "coredns"
 	}
+	return coreDNSImageName(v)
+}
 
-	majorMinorVersion := fmt.Sprintf("v%d.%d", v.Major, v.Minor)
-	if cVersion, ok := constants.KubeadmImages[majorMinorVersion][imageName]; ok {
-		cv = cVersion
-	} else {
-		cv = findLatestTagFromRepository(fmt.Sprintf(tagURLTemplate, kubernetesRepo(mirror), imageName), cv)
+// pinnedTag returns the tag of imageName for v on arch given by override or the kubeadm images table, if any.
+// An empty arch is given the tag common to every architecture.
+func pinnedTag(v semver.Version, imageName string, override string, arch string) (string, bool) {
+	if override != "" {
+		return override, true
 	}
+	tags, err := kubeadmImageTags(v)
+	if err != nil {
+		return "", false
+	}
+	return archTag(tags, imageName, arch)
+}
 
-	return fmt.Sprintf("%s:%s", path.Join(kubernetesRepo(mirror), imageName), cv)
+// tracePinned traces that imageName is tagged tag, by override if set or else by the kubeadm images table
+func tracePinned(imageName string, tag string, override string) {
+	if override != "" {
+		tracef(TracePinned, imageName, "tag %s, overridden", tag)
+		return
+	}
+	tracef(TracePinned, imageName, "tag %s, from the kubeadm images table", tag)
 }
 
-// etcd returns the image used for etcd
-func etcd(v semver.Version, mirror string) string {
-	// Note: changing this logic requires bumping the preload version
-	// Should match `DefaultEtcdVersion` in:
-	// https://github.com/kubernetes/kubernetes/blob/master/cmd/kubeadm/app/constants/constants.go
-	ev := "3.5.0-0"
-	imageName := "etcd"
-	majorMinorVersion := fmt.Sprintf("v%d.%d", v.Major, v.Minor)
-	if eVersion, ok := constants.KubeadmImages[majorMinorVersion][imageName]; ok {
-		ev = eVersion
-	} else {
-		ev = findLatestTagFromRepository(fmt.Sprintf(tagURLTemplate, kubernetesRepo(mirror), imageName), ev)
+// kubeadmImageTags returns the image tags the kubeadm images table pins for the major.minor of v, whatever its patch
+func kubeadmImageTags(v semver.Version) (map[string]string, error) {
+	tags, ok := kubeadmImagesByMinor[minorVersion{major: v.Major, minor: v.Minor}]
+	if !ok {
+		return nil, fmt.Errorf("no image tags are pinned for Kubernetes v%d.%d, run `make update-kubeadm-constants` to add them", v.Major, v.Minor)
 	}
+	return tags, nil
+}
 
-	return fmt.Sprintf("%s:%s", path.Join(kubernetesRepo(mirror), imageName), ev)
+// essentialTagLookups returns the latest tag lookups the essentials of v need, for the images not pinned to a tag
+func essentialTagLookups(v semver.Version, repo func(string) string) []tagLookup {
+	images := []struct {
+		component     string
+		imageName     string
+		path          string
+		override      string
+		lastKnownGood string
+	}{
+		{"pause", "pause", "pause", pauseVersion, defaultPauseVersion},
+		{"etcd", "etcd", "etcd", etcdVersion, defaultEtcdVersion},
+		{"coredns", coreDNSImageName(v), coreDNSPath(v, repo("coredns")), coreDNSVersion, defaultCoreDNSVersion},
+	}
+	if _, err := kubeadmImageTags(v); err != nil {
+		klog.V(3).Infof("looking up the latest essential image tags: %v", err)
+	}
+	lookups := []tagLookup{}
+	for _, img := range images {
+		if _, ok := pinnedTag(v, img.imageName, img.override, ""); ok {
+			continue
+		}
+		r := kubernetesRepo(repo(img.component), v)
+		if img.component == "pause" {
+			r = pauseRepo(repo(img.component), v)
+		}
+		url := tagListURL(r, img.path)
+		lookups = append(lookups, tagLookup{url: url, lastKnownGood: lastGoodTagFor(url, v, img.lastKnownGood)})
+	}
+	return lookups
 }
 
 // auxiliary returns images that are helpful for running minikube
 func auxiliary(mirror string) []string {
+	return auxiliaryWithOptions(mirror, ImageOptions{})
+}
+
+// auxiliaryWithOptions returns images that are helpful for running minikube with the given options
+func auxiliaryWithOptions(mirror string, opts ImageOptions) []string {
 	// Note: changing this list requires bumping the preload version
-	return []string{
-		storageProvisioner(mirror),
-		// NOTE: kindnet is also used when the Docker driver is used with a non-Docker runtime
+	// NOTE: kindnet is also used when the Docker driver is used with a non-Docker runtime
+	imgs := []string{}
+	if !opts.NoStorageProvisioner {
+		imgs = append(imgs, storageProvisioner(mirror))
 	}
+	if opts.HA {
+		imgs = append(imgs, KubeVip(mirror))
+	}
+	if opts.MetricsServer {
+		imgs = append(imgs, MetricsServer(mirror))
+	}
+	if opts.Gvisor {
+		imgs = append(imgs, Gvisor(mirror))
+	}
+	return withDigests(withArch(imgs, targetArch(opts)), digestsFor(opts))
+}
+
+// storageProvisionerImage replaces the storage provisioner image, including its repository, when set
+var storageProvisionerImage string
+
+// SetStorageProvisionerImage replaces the storage provisioner image with img, an empty img restores the default
+func SetStorageProvisionerImage(img string) error {
+	if img != "" {
+		if _, err := name.ParseReference(img, name.WeakValidation); err != nil {
+			return errors.Wrap(err, "storage provisioner image")
+		}
+	}
+	storageProvisionerImage = img
+	return nil
 }
 
 // storageProvisioner returns the minikube storage provisioner image
 func storageProvisioner(mirror string) string {
+	if storageProvisionerImage != "" {
+		return storageProvisionerImage
+	}
 	return path.Join(minikubeRepo(mirror), "storage-provisioner:"+version.GetStorageProvisionerVersion())
 }
 
+// gvisorAddonVersion and gvisorAddonDigest should match the GvisorAddon image in k8s.io/minikube/pkg/minikube/assets/addons.go
+const (
+	gvisorAddonVersion = "3"
+	gvisorAddonDigest  = "sha256:23eb17d48a66fc2b09c31454fb54ecae520c3e9c9197ef17fcb398b4f31d505a"
+)
+
+// Gvisor returns the image used by the gvisor addon to install the runsc runtime
+func Gvisor(mirror string) string {
+	return path.Join(minikubeRepo(mirror), fmt.Sprintf("gvisor-addon:%s@%s", gvisorAddonVersion, gvisorAddonDigest))
+}
+
+// metricsServerVersion and metricsServerDigest should match the MetricsServer image in k8s.io/minikube/pkg/minikube/assets/addons.go
+const (
+	metricsServerVersion = "v0.6.1"
+	metricsServerDigest  = "sha256:5ddc6458eb95f5c70bd13fdab90cbd7d6ad1066e5b528ad1dcb28b76c5fb2f00"
+)
+
+// MetricsServer returns the image deployed by the metrics-server addon
+func MetricsServer(mirror string) string {
+	mirror = normalizeMirror(mirror)
+	if mirror == "" {
+		mirror = "k8s.gcr.io"
+	}
+	return path.Join(mirror, fmt.Sprintf("metrics-server/metrics-server:%s@%s", metricsServerVersion, metricsServerDigest))
+}
+
+// kubeVipVersion is the pinned kube-vip version, used to provide the control plane VIP of HA clusters
+const kubeVipVersion = "v0.4.3"
+
+// KubeVip returns the image used for the kube-vip static pod
+// ref: https://github.com/kube-vip/kube-vip/pkgs/container/kube-vip
+func KubeVip(mirror string) string {
+	return path.Join(kubeVipRepo(mirror), "kube-vip:"+kubeVipVersion)
+}
+
+// aliyunMirror is the image repository of the cn mirror, holding the minikube images without their k8s-minikube namespace
+const aliyunMirror = "registry.cn-hangzhou.aliyuncs.com/google_containers"
+
+// KicBase returns the kicbase image img of gcr.io pulled from mirror, the same way as the other minikube images, or img if there is no mirror
+func KicBase(img string, mirror string) string {
+	mirror = normalizeMirror(mirror)
+	if mirror == "" {
+		return img
+	}
+	image := strings.TrimPrefix(img, "gcr.io/")
+	if mirror == aliyunMirror {
+		// registry.cn-hangzhou.aliyuncs.com/google_containers/k8s-minikube/kicbase:v0.0.25 doesn't exist,
+		// registry.cn-hangzhou.aliyuncs.com/google_containers/kicbase:v0.0.25 does
+		image = strings.TrimPrefix(image, "k8s-minikube/")
+	}
+	return path.Join(mirror, image)
+}
+
 // KindNet returns the image used for kindnet
 // ref: https://hub.docker.com/r/kindest/kindnetd/tags
 // src: https://github.com/kubernetes-sigs/kind/tree/master/images/kindnetd
 func KindNet(repo string) string {
+	repo = normalizeMirror(repo)
 	if repo == "" {
 		repo = "kindest"
 	}
 	return path.Join(repo, "kindnetd:v20210326-1e038dc5")
 }
 
+// flannelVersion should match the flannel images in k8s.io/minikube/pkg/minikube/cni/flannel.go
+const flannelVersion = "v0.12.0"
+const flannelRepo = "quay.io/coreos"
+
+// Flannel returns the image used for flannel on the host architecture
+// ref: https://quay.io/repository/coreos/flannel?tab=tags
+func Flannel(repo string) string {
+	return forArch(flannel(repo), runtime.GOARCH)
+}
+
+// flannel returns the image used for flannel, without the architecture suffix of its tag
+func flannel(repo string) string {
+	repo = normalizeMirror(repo)
+	if repo == "" {
+		repo = flannelRepo
+	}
+	return path.Join(repo, "flannel:"+flannelVersion)
+}
+
+// cniPluginsVersion should match the cni-plugins package bundled in the ISO and kicbase
+const cniPluginsVersion = "v0.8.5"
+
+// BridgePlugins returns the image carrying the CNI plugins used by the bridge CNI
+// src: https://github.com/containernetworking/plugins
+func BridgePlugins(repo string) string {
+	return path.Join(minikubeRepo(repo), "cni-plugins:"+cniPluginsVersion)
+}
+
+// cilium images are from https://raw.githubusercontent.com/cilium/cilium/v1.9/install/kubernetes/quick-install.yaml
+const (
+	ciliumVersion               = "v1.9.9"
+	ciliumRepo                  = "quay.io/cilium"
+	ciliumDigest                = "sha256:a85d5cff13f8231c2e267d9fc3c6e43d24be4a75dac9f641c11ec46e7f17624d"
+	ciliumOperatorGenericDigest = "sha256:3726a965cd960295ca3c5e7f2b543c02096c0912c6652eb8bbb9ce54bcaa99d8"
+)
+
+// Cilium returns the image used for the cilium agent
+func Cilium(repo string) string {
+	return ciliumCommon(repo, "cilium", ciliumDigest)
+}
+
+// CiliumOperator returns the image used for the cilium operator
+func CiliumOperator(repo string) string {
+	return ciliumCommon(repo, "operator-generic", ciliumOperatorGenericDigest)
+}
+
+func ciliumCommon(repo string, name string, digest string) string {
+	repo = normalizeMirror(repo)
+	if repo == "" {
+		repo = ciliumRepo
+	}
+	return path.Join(repo, fmt.Sprintf("%s:%s@%s", name, ciliumVersion, digest))
+}
+
 // all calico images are from https://docs.projectcalico.org/manifests/calico.yaml
 const calicoVersion = "v3.20.0"
 const calicoRepo = "docker.io/calico"
 
+// CalicoVersion returns the version of the calico images, e.g. to fetch the matching manifest
+func CalicoVersion() string {
+	return calicoVersion
+}
+
 // CalicoDaemonSet returns the image used for calicoDaemonSet
 func CalicoDaemonSet(repo string) string {
 	return calicoCommon(repo, "node")
@@ -200,8 +529,47 @@ func CalicoBin(repo string) string {
 }
 
 func calicoCommon(repo string, name string) string {
+	repo = normalizeMirror(repo)
 	if repo == "" {
 		repo = calicoRepo
 	}
 	return path.Join(repo, fmt.Sprintf("%s:%s", name, calicoVersion))
 }
+
+// CNIImages returns every image used by the named CNI (e.g. "calico") on the host architecture, pulled from mirror if set
+func CNIImages(name string, mirror string) ([]string, error) {
+	imgs, err := cniImages(name, mirror)
+	if err != nil {
+		return nil, err
+	}
+	return withArch(imgs, runtime.GOARCH), nil
+}
+
+// CNIImageLister returns the images the manifest of the named CNI deploys, pulled from mirror if set
+type CNIImageLister func(name string, mirror string) ([]string, error)
+
+// cniImageLister lists the images of the CNIs deploying a manifest, set by k8s.io/minikube/pkg/minikube/cni which owns them
+var cniImageLister CNIImageLister
+
+// SetCNIImageLister makes the images of the CNIs deploying a manifest those listed by l, so that they match what is deployed
+func SetCNIImageLister(l CNIImageLister) {
+	cniImageLister = l
+}
+
+// cniImages returns the images used by the named CNI, see withArch for their reference on the target architecture.
+// The images of manifests are listed by cniImageLister. Flannel deploys one DaemonSet per architecture, and bridge no workload
+// but the plugins it runs on the host, so their images are listed here.
+func cniImages(name string, mirror string) ([]string, error) {
+	switch name {
+	case "false":
+		return []string{}, nil
+	case "bridge":
+		return []string{BridgePlugins(mirror)}, nil
+	case "flannel":
+		return []string{flannel(mirror)}, nil
+	}
+	if cniImageLister == nil {
+		return nil, fmt.Errorf("unknown CNI: %q", name)
+	}
+	return cniImageLister(name, mirror)
+}
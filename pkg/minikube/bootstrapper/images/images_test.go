@@ -17,16 +17,37 @@ limitations under the License.
 package images
 
 import (
-	"net/http"
-	"net/http/httptest"
+	"fmt"
+	"path"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/blang/semver/v4"
+	"github.com/docker/distribution/reference"
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/minikube/pkg/version"
 )
 
+// init lists the images of the CNI manifests as k8s.io/minikube/pkg/minikube/cni does, which can't be imported from here.
+// TestCNIImageLister of that package checks that its manifests deploy these images.
+func init() {
+	SetCNIImageLister(testCNIImages)
+}
+
+// testCNIImages returns the images the manifest of the named CNI deploys
+func testCNIImages(name string, mirror string) ([]string, error) {
+	switch name {
+	case "kindnet", "true":
+		return []string{KindNet(mirror)}, nil
+	case "cilium":
+		return []string{Cilium(""), CiliumOperator("")}, nil
+	case "calico":
+		return []string{CalicoBin(mirror), CalicoFelixDriver(mirror), CalicoDaemonSet(mirror), CalicoDeployment(mirror)}, nil
+	}
+	return nil, fmt.Errorf("unknown CNI: %q", name)
+}
+
 func TestEssentials(t *testing.T) {
 	var testCases = []struct {
 		version string
@@ -93,35 +114,341 @@ k8s.gcr.io/coredns/coredns:v1.8.4
 	}
 }
 
-func TestGetLatestTag(t *testing.T) {
-	serverResp := "{tags: [\"1.8.7\"]}"
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, err := w.Write([]byte(serverResp))
-		if err != nil {
-			t.Errorf("failed to write https response")
+func TestEssentialsPatchVersions(t *testing.T) {
+	tests := []struct {
+		version string
+		etcd    string
+		coreDNS string
+	}{
+		{"1.19.0", "k8s.gcr.io/etcd:3.4.9-1", "k8s.gcr.io/coredns:1.7.0"},
+		{"1.19.4", "k8s.gcr.io/etcd:3.4.9-1", "k8s.gcr.io/coredns:1.7.0"},
+		{"1.19.16", "k8s.gcr.io/etcd:3.4.9-1", "k8s.gcr.io/coredns:1.7.0"},
+		{"1.21.14", "k8s.gcr.io/etcd:3.4.13-0", "k8s.gcr.io/coredns/coredns:v1.8.0"},
+		{"1.22.0-rc.0", "k8s.gcr.io/etcd:3.5.0-0", "k8s.gcr.io/coredns/coredns:v1.8.4"},
+		{"1.22.17", "k8s.gcr.io/etcd:3.5.0-0", "k8s.gcr.io/coredns/coredns:v1.8.4"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version, func(t *testing.T) {
+			imgs := essentials("k8s.gcr.io", semver.MustParse(tc.version))
+			got := imgs[len(imgs)-2:]
+			if diff := cmp.Diff([]string{tc.etcd, tc.coreDNS}, got); diff != "" {
+				t.Errorf("etcd and coredns images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKubeadmImageTagsUnknownMinor(t *testing.T) {
+	if _, err := kubeadmImageTags(semver.MustParse("1.99.3")); err == nil {
+		t.Errorf("kubeadmImageTags(v1.99.3) expected an error")
+	}
+	if _, err := kubeadmImageTags(semver.MustParse("1.19.4")); err != nil {
+		t.Errorf("kubeadmImageTags(v1.19.4) error: %v", err)
+	}
+}
+
+func TestEssentialVersions(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+	for _, version := range []string{"1.18.0", "1.19.4", "1.20.0", "1.21.14", "1.22.0", "1.24.1", "1.25.0", "1.99.0"} {
+		t.Run(version, func(t *testing.T) {
+			v := semver.MustParse(version)
+			imgs := essentials("", v)
+			want := []string{
+				fmt.Sprintf("%s/etcd:%s", DefaultKubernetesRepoForVersion(v), EtcdVersion(v)),
+				fmt.Sprintf("%s/%s:%s", DefaultKubernetesRepoForVersion(v), coreDNSImageName(v), CoreDNSVersion(v)),
+			}
+			if diff := cmp.Diff(want, imgs[len(imgs)-2:]); diff != "" {
+				t.Errorf("etcd and coredns images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKubeProxyImage(t *testing.T) {
+	versions := []string{"1.18.0", "1.21.0-alpha.1", "1.22.3", "1.24.9", "1.25.0-alpha.1", "1.25.0"}
+	for minor := range kubeadmImages {
+		versions = append(versions, strings.TrimPrefix(minor, "v")+".0")
+	}
+	sort.Strings(versions)
+	digests := map[string]string{"k8s.gcr.io/kube-proxy": etcdDigest}
+	for _, withDigests := range []bool{false, true} {
+		if withDigests {
+			if err := SetImageDigests(digests); err != nil {
+				t.Fatalf("set image digests: %v", err)
+			}
+		}
+		for _, mirror := range []string{"", "k8s.gcr.io", "test.mirror/sub"} {
+			for _, s := range versions {
+				t.Run(fmt.Sprintf("%s/%q/digests=%t", s, mirror, withDigests), func(t *testing.T) {
+					v := semver.MustParse(s)
+					want := ""
+					for _, img := range essentialsWithOptions(mirror, v, ImageOptions{Offline: true}) {
+						if component(img) == "kube-proxy" {
+							want = img
+						}
+					}
+					if got := KubeProxyImage(mirror, v); got != want {
+						t.Errorf("KubeProxyImage(%q, %s) = %s, want the essentials entry %s", mirror, v, got, want)
+					}
+				})
+			}
 		}
-	}))
-	defer server.Close()
+	}
+	if err := SetImageDigests(nil); err != nil {
+		t.Fatalf("reset image digests: %v", err)
+	}
+	if got, want := KubeProxyImage("k8s.gcr.io", semver.MustParse("1.22.3")), "k8s.gcr.io/kube-proxy:v1.22.3"; got != want {
+		t.Errorf("KubeProxyImage() = %s, want %s", got, want)
+	}
+}
+
+func TestEssentialsDefaultRepo(t *testing.T) {
+	tests := []struct {
+		version   string
+		mirror    string
+		repo      string
+		pauseRepo string
+	}{
+		{"1.24.3", "", "k8s.gcr.io", "k8s.gcr.io"},
+		// the pause tag pinned for v1.25 predates RegistryK8sIOPauseTag
+		{"1.25.0", "", "registry.k8s.io", "k8s.gcr.io"},
+		{"1.24.3", "test.mirror", "test.mirror", "test.mirror"},
+		{"1.25.0", "test.mirror", "test.mirror", "test.mirror"},
+		{"1.25.0", "k8s.gcr.io", "k8s.gcr.io", "k8s.gcr.io"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version+"/"+tc.mirror, func(t *testing.T) {
+			for _, img := range essentials(tc.mirror, semver.MustParse(tc.version)) {
+				repo := tc.repo
+				if component(img) == "pause" {
+					repo = tc.pauseRepo
+				}
+				if !strings.HasPrefix(img, repo+"/") {
+					t.Errorf("got %s, want an image from %s", img, repo)
+				}
+			}
+		})
+	}
+}
+
+func TestEssentialsPortedMirror(t *testing.T) {
+	var testCases = []struct {
+		version string
+		images  []string
+	}{
+		{"v1.20.0", strings.Split(strings.Trim(`
+registry.corp:5000/kube-apiserver:v1.20.0
+registry.corp:5000/kube-controller-manager:v1.20.0
+registry.corp:5000/kube-scheduler:v1.20.0
+registry.corp:5000/kube-proxy:v1.20.0
+registry.corp:5000/pause:3.2
+registry.corp:5000/etcd:3.4.13-0
+registry.corp:5000/coredns:1.7.0
+`, "\n"), "\n")},
+		{"v1.22.0", strings.Split(strings.Trim(`
+registry.corp:5000/kube-apiserver:v1.22.0
+registry.corp:5000/kube-controller-manager:v1.22.0
+registry.corp:5000/kube-scheduler:v1.22.0
+registry.corp:5000/kube-proxy:v1.22.0
+registry.corp:5000/pause:3.5
+registry.corp:5000/etcd:3.5.0-0
+registry.corp:5000/coredns/coredns:v1.8.4
+`, "\n"), "\n")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.version, func(t *testing.T) {
+			got := essentials("registry.corp:5000", semver.MustParse(strings.TrimPrefix(tc.version, "v")))
+			if diff := cmp.Diff(tc.images, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
 
+func TestEssentialsComponentRepos(t *testing.T) {
 	var testCases = []struct {
-		name          string
-		url           string
-		lastKnownGood string
-		wsResponse    string
-		expect        string
+		name   string
+		mirror string
+		repos  map[string]string
+		images []string
 	}{
-		{name: "VersionGetSuccess", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": ["v1.8.9"]}`, expect: "v1.8.9"},
-		{name: "VersionGetFail", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "nah", "nope": ["v1.8.9"]}`, expect: "v1.8.6"},
-		{name: "VersionGetFailNone", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: ``, expect: "v1.8.6"},
-		{name: "VersionGetSuccessMultiple", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": ["1.8.7","v1.8.9"]}`, expect: "v1.8.9"},
+		{"etcd", "k8s.gcr.io", map[string]string{"etcd": "registry.corp/mirror"}, strings.Split(strings.Trim(`
+k8s.gcr.io/kube-apiserver:v1.22.0
+k8s.gcr.io/kube-controller-manager:v1.22.0
+k8s.gcr.io/kube-scheduler:v1.22.0
+k8s.gcr.io/kube-proxy:v1.22.0
+k8s.gcr.io/pause:3.5
+registry.corp/mirror/etcd:3.5.0-0
+k8s.gcr.io/coredns/coredns:v1.8.4
+`, "\n"), "\n")},
+		{"etcd-coredns-default-mirror", "", map[string]string{"etcd": "registry.corp/mirror", "coredns": "registry.corp/dns"}, strings.Split(strings.Trim(`
+k8s.gcr.io/kube-apiserver:v1.22.0
+k8s.gcr.io/kube-controller-manager:v1.22.0
+k8s.gcr.io/kube-scheduler:v1.22.0
+k8s.gcr.io/kube-proxy:v1.22.0
+k8s.gcr.io/pause:3.5
+registry.corp/mirror/etcd:3.5.0-0
+registry.corp/dns/coredns/coredns:v1.8.4
+`, "\n"), "\n")},
+		{"empty-override", "mirror.k8s.io", map[string]string{"etcd": ""}, strings.Split(strings.Trim(`
+mirror.k8s.io/kube-apiserver:v1.22.0
+mirror.k8s.io/kube-controller-manager:v1.22.0
+mirror.k8s.io/kube-scheduler:v1.22.0
+mirror.k8s.io/kube-proxy:v1.22.0
+mirror.k8s.io/pause:3.5
+mirror.k8s.io/etcd:3.5.0-0
+mirror.k8s.io/coredns/coredns:v1.8.4
+`, "\n"), "\n")},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			serverResp = tc.wsResponse
-			resp := findLatestTagFromRepository(tc.url, tc.lastKnownGood)
-			if diff := cmp.Diff(tc.expect, resp); diff != "" {
-				t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+			got := essentialsWithOptions(tc.mirror, semver.MustParse("1.22.0"), ImageOptions{ComponentRepos: tc.repos})
+			if diff := cmp.Diff(tc.images, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEssentialsKubeProxyless(t *testing.T) {
+	v := semver.MustParse("1.22.0")
+	all := essentials("k8s.gcr.io", v)
+	for _, kubeProxyless := range []bool{false, true} {
+		t.Run(fmt.Sprintf("KubeProxyless=%t", kubeProxyless), func(t *testing.T) {
+			want := []string{}
+			for _, img := range all {
+				if kubeProxyless && strings.HasPrefix(img, "k8s.gcr.io/kube-proxy:") {
+					continue
+				}
+				want = append(want, img)
+			}
+			got := essentialsWithOptions("k8s.gcr.io", v, ImageOptions{KubeProxyless: kubeProxyless})
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEssentialsVersionOverrides(t *testing.T) {
+	v := semver.MustParse("1.22.0")
+	defaults := essentials("k8s.gcr.io", v)
+
+	tests := []struct {
+		name   string
+		set    func(string) error
+		prefix string
+		tag    string
+	}{
+		{"pause", SetPauseVersion, "k8s.gcr.io/pause:", "3.7"},
+		{"etcd", SetEtcdVersion, "k8s.gcr.io/etcd:", "3.5.3-0"},
+		{"coredns", SetCoreDNSVersion, "k8s.gcr.io/coredns/coredns:", "v1.8.7"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.set(tc.tag); err != nil {
+				t.Fatalf("set %s version: %v", tc.name, err)
+			}
+			got := essentials("k8s.gcr.io", v)
+			if err := tc.set(""); err != nil {
+				t.Fatalf("reset %s version: %v", tc.name, err)
+			}
+
+			want := []string{}
+			for _, img := range defaults {
+				if strings.HasPrefix(img, tc.prefix) {
+					img = tc.prefix + tc.tag
+				}
+				want = append(want, img)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(defaults, essentials("k8s.gcr.io", v)); diff != "" {
+				t.Errorf("default images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEssentialsPauseOption(t *testing.T) {
+	v := semver.MustParse("1.22.0")
+	defaults := essentials("k8s.gcr.io", v)
+	const override = "registry.example.com/pause:3.6"
+
+	for _, globalVersion := range []string{"", "3.7"} {
+		t.Run(fmt.Sprintf("SetPauseVersion=%q", globalVersion), func(t *testing.T) {
+			if err := SetPauseVersion(globalVersion); err != nil {
+				t.Fatalf("set pause version: %v", err)
+			}
+			defer func() {
+				if err := SetPauseVersion(""); err != nil {
+					t.Fatalf("reset pause version: %v", err)
+				}
+			}()
+
+			want := []string{}
+			for _, img := range defaults {
+				if strings.HasPrefix(img, "k8s.gcr.io/pause:") {
+					img = override
+				}
+				want = append(want, img)
+			}
+			got := essentialsWithOptions("k8s.gcr.io", v, ImageOptions{Pause: override})
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+			if pauseVersion != globalVersion {
+				t.Errorf("pause version = %q after the per-call override, want it unchanged at %q", pauseVersion, globalVersion)
+			}
+		})
+	}
+}
+
+func TestEssentialsCoreDNSPath(t *testing.T) {
+	tests := []struct {
+		version string
+		mirror  string
+		flat    bool
+		want    string
+	}{
+		{"1.20.0", "", false, "k8s.gcr.io/coredns:1.7.0"},
+		{"1.21.0", "", false, "k8s.gcr.io/coredns/coredns:v1.8.0"},
+		{"1.20.0", "mirror.example.com/k8s", false, "mirror.example.com/k8s/coredns:1.7.0"},
+		{"1.21.0", "mirror.example.com/k8s", false, "mirror.example.com/k8s/coredns/coredns:v1.8.0"},
+		{"1.20.0", "mirror.example.com/k8s", true, "mirror.example.com/k8s/coredns:1.7.0"},
+		{"1.21.0", "mirror.example.com/k8s", true, "mirror.example.com/k8s/coredns:v1.8.0"},
+		// the upstream repositories hold the nested path
+		{"1.21.0", "", true, "k8s.gcr.io/coredns/coredns:v1.8.0"},
+		{"1.21.0", "k8s.gcr.io", true, "k8s.gcr.io/coredns/coredns:v1.8.0"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%s/flat=%t", tc.version, tc.mirror, tc.flat), func(t *testing.T) {
+			SetFlatCoreDNSPath(tc.flat)
+			defer SetFlatCoreDNSPath(false)
+			imgs := essentials(tc.mirror, semver.MustParse(tc.version))
+			if got := imgs[len(imgs)-1]; got != tc.want {
+				t.Errorf("coredns image = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetVersionInvalid(t *testing.T) {
+	for _, v := range []string{"latest", "3.x", "3.6 ", "3.6;rm"} {
+		t.Run(v, func(t *testing.T) {
+			if err := SetPauseVersion(v); err == nil {
+				t.Errorf("SetPauseVersion(%q) = nil, want error", v)
+			}
+			if err := SetEtcdVersion(v); err == nil {
+				t.Errorf("SetEtcdVersion(%q) = nil, want error", v)
+			}
+			if err := SetCoreDNSVersion(v); err == nil {
+				t.Errorf("SetCoreDNSVersion(%q) = nil, want error", v)
+			}
+			if pauseVersion != "" || etcdVersion != "" || coreDNSVersion != "" {
+				t.Errorf("versions = %q, %q, %q after invalid input, want them unchanged", pauseVersion, etcdVersion, coreDNSVersion)
 			}
 		})
 	}
@@ -138,12 +465,221 @@ func TestAuxiliary(t *testing.T) {
 }
 
 func TestAuxiliaryMirror(t *testing.T) {
-	want := []string{
-		"test.mirror/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+	var testCases = []struct {
+		mirror string
+		repo   string
+	}{
+		{"test.mirror", "test.mirror"},
+		{"registry.corp:5000", "registry.corp:5000"},
+		{"http://registry.corp:5000/", "registry.corp:5000"},
+		{"localhost:5000/mirror", "localhost:5000/mirror"},
 	}
-	got := auxiliary("test.mirror")
-	if diff := cmp.Diff(want, got); diff != "" {
-		t.Errorf("images mismatch (-want +got):\n%s", diff)
+	for _, tc := range testCases {
+		t.Run(tc.mirror, func(t *testing.T) {
+			want := []string{
+				tc.repo + "/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+			}
+			got := auxiliary(tc.mirror)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuxiliaryStorageProvisionerImage(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		image  string
+		mirror string
+		want   string
+	}{
+		{"default", "", "", "gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion()},
+		{"mirror", "", "test.mirror", "test.mirror/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion()},
+		{"override", "myrepo/sp:v1", "", "myrepo/sp:v1"},
+		{"override-mirror", "myrepo/sp:v1", "test.mirror", "myrepo/sp:v1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetStorageProvisionerImage(tc.image); err != nil {
+				t.Fatalf("SetStorageProvisionerImage: %v", err)
+			}
+			defer func() {
+				if err := SetStorageProvisionerImage(""); err != nil {
+					t.Errorf("reset storage provisioner image: %v", err)
+				}
+			}()
+			if diff := cmp.Diff([]string{tc.want}, auxiliary(tc.mirror)); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if err := SetStorageProvisionerImage("myrepo/SP:v1"); err == nil {
+		t.Errorf("SetStorageProvisionerImage with an invalid reference succeeded, want an error")
+	}
+}
+
+func TestAuxiliaryNoStorageProvisioner(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		mirror string
+		omit   bool
+		want   []string
+	}{
+		{"included", "", false, []string{"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion()}},
+		{"included-mirror", "test.mirror", false, []string{"test.mirror/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion()}},
+		{"omitted", "", true, []string{}},
+		{"omitted-mirror", "test.mirror", true, []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := auxiliaryWithOptions(tc.mirror, ImageOptions{NoStorageProvisioner: tc.omit})
+			if got == nil {
+				t.Fatalf("auxiliaryWithOptions returned nil, want an empty slice")
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuxiliaryMetricsServer(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		mirror  string
+		enabled bool
+		want    []string
+	}{
+		{"disabled", "", false, []string{
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}},
+		{"enabled", "", true, []string{
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+			"k8s.gcr.io/metrics-server/metrics-server:" + metricsServerVersion + "@" + metricsServerDigest,
+		}},
+		{"enabled-mirror", "test.mirror", true, []string{
+			"test.mirror/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+			"test.mirror/metrics-server/metrics-server:" + metricsServerVersion + "@" + metricsServerDigest,
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := auxiliaryWithOptions(tc.mirror, ImageOptions{MetricsServer: tc.enabled})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuxiliaryHA(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		mirror string
+		ha     bool
+		want   []string
+	}{
+		{"single-node", "", false, []string{
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}},
+		{"ha", "", true, []string{
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+			"ghcr.io/kube-vip/kube-vip:" + kubeVipVersion,
+		}},
+		{"ha-mirror", "test.mirror", true, []string{
+			"test.mirror/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+			"test.mirror/kube-vip/kube-vip:" + kubeVipVersion,
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := auxiliaryWithOptions(tc.mirror, ImageOptions{HA: tc.ha})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKicBase(t *testing.T) {
+	const img = "gcr.io/k8s-minikube/kicbase-builds:v0.0.32@sha256:9190bd2393eae887316c97a74370b7d5dad8f0b2ef91ac2662bc36f7ef8e0b95"
+	const image = "kicbase-builds:v0.0.32@sha256:9190bd2393eae887316c97a74370b7d5dad8f0b2ef91ac2662bc36f7ef8e0b95"
+	tests := []struct {
+		mirror string
+		want   string
+	}{
+		{"", img},
+		{"mirror.corp", "mirror.corp/k8s-minikube/" + image},
+		{"https://mirror.corp:5000/minikube/", "mirror.corp:5000/minikube/k8s-minikube/" + image},
+		{"registry.cn-hangzhou.aliyuncs.com/google_containers", "registry.cn-hangzhou.aliyuncs.com/google_containers/" + image},
+	}
+	for _, tc := range tests {
+		t.Run(tc.mirror, func(t *testing.T) {
+			if got := KicBase(img, tc.mirror); got != tc.want {
+				t.Errorf("KicBase(%s) = %s, want %s", tc.mirror, got, tc.want)
+			}
+			// the kicbase image comes from the same repository as the storage-provisioner
+			if tc.mirror != "" && tc.mirror != aliyunMirror && path.Dir(KicBase(img, tc.mirror)) != path.Dir(storageProvisioner(tc.mirror)) {
+				t.Errorf("KicBase(%s) = %s, want the repository of %s", tc.mirror, KicBase(img, tc.mirror), storageProvisioner(tc.mirror))
+			}
+		})
+	}
+}
+
+func TestCalicoVersion(t *testing.T) {
+	for _, fn := range []func(string) string{CalicoDaemonSet, CalicoDeployment, CalicoFelixDriver, CalicoBin} {
+		for _, mirror := range []string{"", "registry.example.com/mirror"} {
+			img := fn(mirror)
+			named, err := reference.ParseNormalizedNamed(img)
+			if err != nil {
+				t.Fatalf("parse %s: %v", img, err)
+			}
+			tagged, ok := named.(reference.Tagged)
+			if !ok {
+				t.Fatalf("%s has no tag", img)
+			}
+			if tagged.Tag() != CalicoVersion() {
+				t.Errorf("%s has version %s, want CalicoVersion() %s", img, tagged.Tag(), CalicoVersion())
+			}
+		}
+	}
+}
+
+func TestCNIImages(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"calico", []string{CalicoBin(""), CalicoFelixDriver(""), CalicoDaemonSet(""), CalicoDeployment("")}},
+		{"kindnet", []string{KindNet("")}},
+		{"flannel", []string{Flannel("")}},
+		{"false", []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CNIImages(tc.name, "")
+			if err != nil {
+				t.Fatalf("CNIImages: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := CNIImages("weave", ""); err == nil {
+		t.Errorf("CNIImages of an unknown CNI succeeded, want an error")
+	}
+
+	SetCNIImageLister(nil)
+	defer SetCNIImageLister(testCNIImages)
+	if _, err := CNIImages("calico", ""); err == nil {
+		t.Errorf("CNIImages of a manifest without an image lister succeeded, want an error")
+	}
+	if _, err := CNIImages("flannel", ""); err != nil {
+		t.Errorf("CNIImages of flannel without an image lister: %v", err)
 	}
 }
 
@@ -156,6 +692,10 @@ func TestCNI(t *testing.T) {
 		{"kindnet", KindNet},
 		{"calico-deployment", CalicoDeployment},
 		{"calico-daemonset", CalicoDaemonSet},
+		{"flannel", Flannel},
+		{"cilium", Cilium},
+		{"cilium-operator", CiliumOperator},
+		{"bridge", BridgePlugins},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -166,3 +706,31 @@ func TestCNI(t *testing.T) {
 		})
 	}
 }
+
+func TestEssentialsArchCoreDNSTag(t *testing.T) {
+	useTagCache(t, "")
+	useVersions(t, `{"v1.21": {"coredns/coredns": "v1.8.0", "coredns/coredns@arm64": "v1.7.1", "etcd": "3.4.13-0", "pause": "3.4.1"}}`)
+
+	v := semver.MustParse("1.21.2")
+	tests := []struct {
+		arch string
+		want string
+	}{
+		{"amd64", "k8s.gcr.io/coredns/coredns:v1.8.0"},
+		{"arm64", "k8s.gcr.io/coredns/coredns:v1.7.1"},
+		{"s390x", "k8s.gcr.io/coredns/coredns:v1.8.0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.arch, func(t *testing.T) {
+			opts := ImageOptions{Arch: tc.arch}
+			for name, imgs := range map[string][]string{
+				"templated": essentialsWithOptions("", v, opts),
+				"built":     buildEssentials("", v, opts),
+			} {
+				if got := imgs[len(imgs)-1]; got != tc.want {
+					t.Errorf("%s essentials for %s list coredns %s, want %s", name, tc.arch, got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -18,25 +18,254 @@ package images
 
 import (
 	"fmt"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/constants"
 )
 
+// ImageOptions customizes the list of images necessary to bootstrap kubeadm
+type ImageOptions struct {
+	// ComponentRepos overrides the repository of individual components, keyed by image name (e.g. "etcd")
+	ComponentRepos map[string]string
+	// KubeProxyless omits kube-proxy, for CNIs which replace it
+	KubeProxyless bool
+	// HA adds the images needed by clusters with multiple control plane nodes
+	HA bool
+	// CNI adds the images of the named CNI (e.g. "calico"), if set
+	CNI string
+	// Digests pins images to a digest instead of their tag, keyed by repository (e.g. "k8s.gcr.io/etcd").
+	// Defaults to the digests given to SetImageDigests.
+	Digests map[string]string
+	// Pause replaces the pause image derived from the Kubernetes version, including its repository
+	// (e.g. "registry.example.com/pause:3.6"), without changing the global pause version. Empty derives it.
+	Pause string
+	// NoStorageProvisioner omits the storage-provisioner image, for clusters with the storage-provisioner addon disabled
+	NoStorageProvisioner bool
+	// Gvisor adds the images of the gvisor addon, which runs pods with the runsc runtime
+	Gvisor bool
+	// MetricsServer adds the image of the metrics-server addon
+	MetricsServer bool
+	// Arch is the architecture of the nodes the images are for (e.g. "arm64"), defaults to the architecture minikube runs on
+	Arch string
+	// OS is the operating system of the node the images are for (e.g. "windows"), defaults to linux
+	OS string
+	// FullyQualified gives every image a registry host, docker.io if it has none, for runtimes such as podman
+	// which don't pull short names
+	FullyQualified bool
+	// Offline lists the images without any registry lookup, as SetOffline does for every listing. Tags which would be
+	// looked up are those already looked up by this process, or their last known good tag.
+	Offline bool
+	// Rewrite replaces every image reference with what it returns, as the last step after any other option.
+	// Nil leaves the references as they are.
+	Rewrite RewriteFunc
+}
+
 // Kubeadm returns a list of images necessary to bootstrap kubeadm
 func Kubeadm(mirror string, version string) ([]string, error) {
-	v, err := semver.Make(strings.TrimPrefix(version, "v"))
+	return KubeadmWithOptions(mirror, version, ImageOptions{})
+}
+
+// KubeadmWithOptions returns a list of images necessary to bootstrap kubeadm with the given options, in the order they are pulled.
+// They are the images of ImagesForVersion.
+func KubeadmWithOptions(mirror string, version string, opts ImageOptions) ([]string, error) {
+	v, err := ResolveVersion(version)
 	if err != nil {
-		return nil, errors.Wrap(err, "semver")
+		return nil, err
 	}
-	if v.Major > 1 {
-		return nil, fmt.Errorf("version too new: %v", v)
+	imgs, _, err := imageRefs(mirror, v, opts)
+	if err != nil {
+		return nil, err
 	}
-	if semver.MustParseRange("<1.12.0-alpha.0")(v) {
-		return nil, fmt.Errorf("version too old: %v", v)
+	if err := ValidateRegistries(imgs); err != nil {
+		return nil, err
 	}
-	imgs := essentials(mirror, v)
-	imgs = append(imgs, auxiliary(mirror)...)
 	return imgs, nil
 }
+
+// KubeadmConfigImages returns the essential images minikube pulls for the Kubernetes version, along with the images
+// kubeadm derives from the same imageRepository, in the same order, so that the two can be checked to agree.
+func KubeadmConfigImages(mirror string, version string) (essentials []string, derived []string, err error) {
+	v, err := ResolveVersion(version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkVersion(v); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateMirror(mirror); err != nil {
+		return nil, nil, err
+	}
+	essentials = essentialsWithOptions(mirror, v, ImageOptions{Digests: map[string]string{}})
+	return essentials, kubeadmDerivedImages(mirror, v, essentials), nil
+}
+
+// kubeadmDerivedImages returns the essential images as kubeadm names them for imageRepository mirror.
+// Tags kubeadm doesn't pin for v are taken from essentials, as minikube passes them to kubeadm as imageTag.
+func kubeadmDerivedImages(mirror string, v semver.Version, essentials []string) []string {
+	tags := map[string]string{}
+	for _, ref := range essentials {
+		tags[component(ref)] = parseImage(ref, "").Tag
+	}
+	// essentials are listed for the architecture minikube runs on
+	arch := targetArch(ImageOptions{})
+	tag := func(imageName string, override string) string {
+		if t, ok := pinnedTag(v, imageName, override, arch); ok {
+			return t
+		}
+		return tags[path.Base(imageName)]
+	}
+
+	repo := kubernetesRepo(mirror, v)
+	imgs := []string{}
+	for _, name := range []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kube-proxy"} {
+		imgs = append(imgs, fmt.Sprintf("%s:v%s", path.Join(repo, name), v))
+	}
+	// kubeadm only uses the coredns/coredns path of v1.21 in its default repository, other repositories hold coredns
+	dnsRepo := repo
+	if imageName := coreDNSImageName(v); imageName != "coredns" && repo == DefaultKubernetesRepoForVersion(v) {
+		dnsRepo = path.Join(repo, path.Dir(imageName))
+	}
+	// the pause version isn't passed to kubeadm, while the etcd and coredns versions are
+	return append(imgs,
+		fmt.Sprintf("%s:%s", path.Join(repo, "pause"), tag("pause", "")),
+		fmt.Sprintf("%s:%s", path.Join(repo, "etcd"), tag("etcd", etcdVersion)),
+		fmt.Sprintf("%s:%s", path.Join(dnsRepo, "coredns"), tag(coreDNSImageName(v), coreDNSVersion)),
+	)
+}
+
+// ImagesForVersion returns the sorted list of every image required to run the given Kubernetes version,
+// including the images of opts.CNI, so that they can be pulled ahead of time
+func ImagesForVersion(repo string, k8sVersion semver.Version, opts ImageOptions) ([]string, error) {
+	imgs, err := StructuredImagesForVersion(repo, k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	return References(imgs), nil
+}
+
+// validateMirrors checks mirror and the component repositories of opts are usable image repositories
+func validateMirrors(mirror string, opts ImageOptions) error {
+	if err := ValidateMirror(mirror); err != nil {
+		return err
+	}
+	for name, repo := range opts.ComponentRepos {
+		if err := ValidateMirror(repo); err != nil {
+			return errors.Wrapf(err, "%s repository", name)
+		}
+	}
+	return nil
+}
+
+// tooOldVersion matches the Kubernetes versions older than any minikube lists images for
+var tooOldVersion = semver.MustParseRange("<1.12.0-alpha.0")
+
+// checkVersion returns an ErrUnknownVersion if images can't be determined for the Kubernetes version.
+// Versions missing from the kubeadm images table are only warned about, the tags of their images are looked up instead.
+func checkVersion(v semver.Version) error {
+	if v.Major > 1 {
+		return withKind(ErrUnknownVersion, fmt.Errorf("version too new: %v", v))
+	}
+	if tooOldVersion(v) {
+		return withKind(ErrUnknownVersion, fmt.Errorf("version too old: %v", v))
+	}
+	if err := CheckKnownVersion(v); err != nil && !allowUnknownVersions {
+		if _, warned := unknownVersionsWarned.LoadOrStore(fmt.Sprintf("%d.%d", v.Major, v.Minor), true); !warned {
+			klog.Warningf("%v, looking up the tags of its images in the image repository", err)
+		}
+	}
+	return nil
+}
+
+// unknownVersionsWarned holds the minor versions missing from the kubeadm images table already warned about
+var unknownVersionsWarned sync.Map
+
+// allowUnknownVersions silences the warning about versions missing from the kubeadm images table, once the user was told
+var allowUnknownVersions bool
+
+// SetAllowUnknownVersions sets whether images are listed for Kubernetes versions missing from KnownVersions without warning,
+// as the user was already told that the latest tags found in the image repository are used
+func SetAllowUnknownVersions(allow bool) {
+	allowUnknownVersions = allow
+}
+
+// KnownVersions returns the Kubernetes minor versions, such as 1.24.0, whose image tags are known
+func KnownVersions() []semver.Version {
+	versions := []semver.Version{}
+	for minor := range kubeadmImages {
+		v, err := semver.ParseTolerant(minor)
+		if err != nil || tooOldVersion(v) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	semver.Sort(versions)
+	return versions
+}
+
+// ResolveVersion returns the Kubernetes version named by version, which is either a version such as v1.24.0
+// or an alias: "stable" for the default version of minikube, or "latest" (also "newest") for the newest version
+// whose images are known, taken at its .0 patch release unless minikube supports a later patch of it.
+func ResolveVersion(version string) (semver.Version, error) {
+	switch strings.ToLower(version) {
+	case "stable":
+		return semver.Make(strings.TrimPrefix(constants.DefaultKubernetesVersion, "v"))
+	case "latest", "newest":
+		newest, err := semver.Make(strings.TrimPrefix(constants.NewestKubernetesVersion, "v"))
+		if err != nil {
+			return semver.Version{}, errors.Wrap(err, "newest version")
+		}
+		if known := KnownVersions(); len(known) > 0 && known[len(known)-1].GT(newest) {
+			return known[len(known)-1], nil
+		}
+		return newest, nil
+	}
+	v, err := semver.Make(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return semver.Version{}, errors.Wrap(err, "semver")
+	}
+	return v, nil
+}
+
+// CheckKnownVersion returns an ErrUnknownVersion listing the known versions if the image tags of Kubernetes v aren't known
+func CheckKnownVersion(v semver.Version) error {
+	known := KnownVersions()
+	for _, k := range known {
+		if k.Major == v.Major && k.Minor == v.Minor {
+			return nil
+		}
+	}
+	if len(known) == 0 {
+		return withKind(ErrUnknownVersion, fmt.Errorf("the images of Kubernetes %v are not known", v))
+	}
+	return withKind(ErrUnknownVersion, fmt.Errorf("the images of Kubernetes %v are not known, known versions are v%d.%d to v%d.%d", v,
+		known[0].Major, known[0].Minor, known[len(known)-1].Major, known[len(known)-1].Minor))
+}
+
+// dedupe returns the sorted list of unique images.
+// Images are compared by reference, so that calico/node:v3.20.0 and docker.io/calico/node:v3.20.0 are only listed once.
+func dedupe(imgs []string) []string {
+	sorted := append([]string{}, imgs...)
+	sort.Strings(sorted)
+	seen := map[string]bool{}
+	uniq := []string{}
+	for _, img := range sorted {
+		key := img
+		if ref, err := name.ParseReference(img, name.WeakValidation); err == nil {
+			key = ref.Name()
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		uniq = append(uniq, img)
+	}
+	return uniq
+}
@@ -18,9 +18,13 @@ package images
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/blang/semver/v4"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/version"
 )
 
@@ -112,3 +116,340 @@ func TestKubeadmImages(t *testing.T) {
 		}
 	}
 }
+
+func TestImagesForVersion(t *testing.T) {
+	calico := []string{
+		"docker.io/calico/cni:" + calicoVersion,
+		"docker.io/calico/kube-controllers:" + calicoVersion,
+		"docker.io/calico/node:" + calicoVersion,
+		"docker.io/calico/pod2daemon-flexvol:" + calicoVersion,
+	}
+	tests := []struct {
+		version string
+		cni     string
+		want    []string
+	}{
+		{"v1.18.0", "", []string{
+			"k8s.gcr.io/coredns:1.6.7",
+			"k8s.gcr.io/etcd:3.4.3-0",
+			"k8s.gcr.io/kube-apiserver:v1.18.0",
+			"k8s.gcr.io/kube-controller-manager:v1.18.0",
+			"k8s.gcr.io/kube-proxy:v1.18.0",
+			"k8s.gcr.io/kube-scheduler:v1.18.0",
+			"k8s.gcr.io/pause:3.2",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}},
+		{"v1.19.0", "", []string{
+			"k8s.gcr.io/coredns:1.7.0",
+			"k8s.gcr.io/etcd:3.4.9-1",
+			"k8s.gcr.io/kube-apiserver:v1.19.0",
+			"k8s.gcr.io/kube-controller-manager:v1.19.0",
+			"k8s.gcr.io/kube-proxy:v1.19.0",
+			"k8s.gcr.io/kube-scheduler:v1.19.0",
+			"k8s.gcr.io/pause:3.2",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}},
+		{"v1.20.0", "", []string{
+			"k8s.gcr.io/coredns:1.7.0",
+			"k8s.gcr.io/etcd:3.4.13-0",
+			"k8s.gcr.io/kube-apiserver:v1.20.0",
+			"k8s.gcr.io/kube-controller-manager:v1.20.0",
+			"k8s.gcr.io/kube-proxy:v1.20.0",
+			"k8s.gcr.io/kube-scheduler:v1.20.0",
+			"k8s.gcr.io/pause:3.2",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}},
+		{"v1.21.0", "", []string{
+			"k8s.gcr.io/coredns/coredns:v1.8.0",
+			"k8s.gcr.io/etcd:3.4.13-0",
+			"k8s.gcr.io/kube-apiserver:v1.21.0",
+			"k8s.gcr.io/kube-controller-manager:v1.21.0",
+			"k8s.gcr.io/kube-proxy:v1.21.0",
+			"k8s.gcr.io/kube-scheduler:v1.21.0",
+			"k8s.gcr.io/pause:3.4.1",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}},
+		{"v1.22.0", "", []string{
+			"k8s.gcr.io/coredns/coredns:v1.8.4",
+			"k8s.gcr.io/etcd:3.5.0-0",
+			"k8s.gcr.io/kube-apiserver:v1.22.0",
+			"k8s.gcr.io/kube-controller-manager:v1.22.0",
+			"k8s.gcr.io/kube-proxy:v1.22.0",
+			"k8s.gcr.io/kube-scheduler:v1.22.0",
+			"k8s.gcr.io/pause:3.5",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}},
+		{"v1.18.0", "calico", append([]string{
+			"k8s.gcr.io/coredns:1.6.7",
+			"k8s.gcr.io/etcd:3.4.3-0",
+			"k8s.gcr.io/kube-apiserver:v1.18.0",
+			"k8s.gcr.io/kube-controller-manager:v1.18.0",
+			"k8s.gcr.io/kube-proxy:v1.18.0",
+			"k8s.gcr.io/kube-scheduler:v1.18.0",
+			"k8s.gcr.io/pause:3.2",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}, calico...)},
+		{"v1.19.0", "calico", append([]string{
+			"k8s.gcr.io/coredns:1.7.0",
+			"k8s.gcr.io/etcd:3.4.9-1",
+			"k8s.gcr.io/kube-apiserver:v1.19.0",
+			"k8s.gcr.io/kube-controller-manager:v1.19.0",
+			"k8s.gcr.io/kube-proxy:v1.19.0",
+			"k8s.gcr.io/kube-scheduler:v1.19.0",
+			"k8s.gcr.io/pause:3.2",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}, calico...)},
+		{"v1.20.0", "calico", append([]string{
+			"k8s.gcr.io/coredns:1.7.0",
+			"k8s.gcr.io/etcd:3.4.13-0",
+			"k8s.gcr.io/kube-apiserver:v1.20.0",
+			"k8s.gcr.io/kube-controller-manager:v1.20.0",
+			"k8s.gcr.io/kube-proxy:v1.20.0",
+			"k8s.gcr.io/kube-scheduler:v1.20.0",
+			"k8s.gcr.io/pause:3.2",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}, calico...)},
+		{"v1.21.0", "calico", append([]string{
+			"k8s.gcr.io/coredns/coredns:v1.8.0",
+			"k8s.gcr.io/etcd:3.4.13-0",
+			"k8s.gcr.io/kube-apiserver:v1.21.0",
+			"k8s.gcr.io/kube-controller-manager:v1.21.0",
+			"k8s.gcr.io/kube-proxy:v1.21.0",
+			"k8s.gcr.io/kube-scheduler:v1.21.0",
+			"k8s.gcr.io/pause:3.4.1",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}, calico...)},
+		{"v1.22.0", "calico", append([]string{
+			"k8s.gcr.io/coredns/coredns:v1.8.4",
+			"k8s.gcr.io/etcd:3.5.0-0",
+			"k8s.gcr.io/kube-apiserver:v1.22.0",
+			"k8s.gcr.io/kube-controller-manager:v1.22.0",
+			"k8s.gcr.io/kube-proxy:v1.22.0",
+			"k8s.gcr.io/kube-scheduler:v1.22.0",
+			"k8s.gcr.io/pause:3.5",
+			"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		}, calico...)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version+"-"+tc.cni, func(t *testing.T) {
+			got, err := ImagesForVersion("", semver.MustParse(strings.TrimPrefix(tc.version, "v")), ImageOptions{CNI: tc.cni})
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			want := append([]string{}, tc.want...)
+			sort.Strings(want)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("%s images mismatch (-want +got):\n%s", tc.version, diff)
+			}
+		})
+	}
+}
+
+func TestImagesForVersionGvisor(t *testing.T) {
+	gvisor := "gcr.io/k8s-minikube/gvisor-addon:" + gvisorAddonVersion + "@" + gvisorAddonDigest
+	for _, enabled := range []bool{false, true} {
+		imgs, err := ImagesForVersion("", semver.MustParse("1.24.0"), ImageOptions{Gvisor: enabled})
+		if err != nil {
+			t.Fatalf("ImagesForVersion: %v", err)
+		}
+		kubeadm, err := KubeadmWithOptions("", "v1.24.0", ImageOptions{Gvisor: enabled})
+		if err != nil {
+			t.Fatalf("KubeadmWithOptions: %v", err)
+		}
+		for name, list := range map[string][]string{"ImagesForVersion": imgs, "KubeadmWithOptions": kubeadm} {
+			found := false
+			for _, img := range list {
+				if img == gvisor {
+					found = true
+				}
+			}
+			if found != enabled {
+				t.Errorf("gvisor option %t: gvisor image listed by %s = %t in %v", enabled, name, found, list)
+			}
+		}
+		if diff := cmp.Diff(imgs, dedupe(kubeadm)); diff != "" {
+			t.Errorf("gvisor option %t: KubeadmWithOptions and ImagesForVersion disagree (-ImagesForVersion +KubeadmWithOptions):\n%s", enabled, diff)
+		}
+	}
+}
+
+func TestImagesForVersionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		cni     string
+		mirror  string
+	}{
+		{"too-old", "1.11.0", "", ""},
+		{"too-new", "2.0.0", "", ""},
+		{"unknown-cni", "1.22.0", "unknown", ""},
+		{"invalid-mirror", "1.22.0", "", "test mirror"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, err := ImagesForVersion(tc.mirror, semver.MustParse(tc.version), ImageOptions{CNI: tc.cni}); err == nil {
+				t.Errorf("expected err: %v", got)
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	cni, err := cniImages("calico", "")
+	if err != nil {
+		t.Fatalf("cniImages: %v", err)
+	}
+	imgs := append(auxiliaryWithOptions("", ImageOptions{HA: true}), cni...)
+	// the same images, produced by other code paths using a different spelling of their references
+	imgs = append(imgs,
+		"calico/node:"+calicoVersion,
+		"index.docker.io/calico/cni:"+calicoVersion,
+		storageProvisioner(""),
+		KubeVip("ghcr.io/"),
+	)
+
+	want := []string{
+		"docker.io/calico/cni:" + calicoVersion,
+		"docker.io/calico/kube-controllers:" + calicoVersion,
+		"docker.io/calico/node:" + calicoVersion,
+		"docker.io/calico/pod2daemon-flexvol:" + calicoVersion,
+		"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+		"ghcr.io/kube-vip/kube-vip:" + kubeVipVersion,
+	}
+	got := dedupe(imgs)
+	if len(got) != len(want) {
+		t.Errorf("got %d images, want %d: %v", len(got), len(want), got)
+	}
+	for _, w := range want {
+		ref, err := name.ParseReference(w, name.WeakValidation)
+		if err != nil {
+			t.Fatalf("parse %s: %v", w, err)
+		}
+		n := 0
+		for _, g := range got {
+			if r, err := name.ParseReference(g, name.WeakValidation); err == nil && r.Name() == ref.Name() {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("got %d entries for %s, want 1: %v", n, w, got)
+		}
+	}
+}
+
+func TestKubeadmConfigImages(t *testing.T) {
+	tests := []struct {
+		version string
+		mirror  string
+		agree   bool
+		coreDNS string
+	}{
+		{"v1.20.0", "", true, "k8s.gcr.io/coredns:1.7.0"},
+		{"v1.21.0", "", true, "k8s.gcr.io/coredns/coredns:v1.8.0"},
+		{"v1.20.0", "mirror.example.com/k8s", true, "mirror.example.com/k8s/coredns:1.7.0"},
+		// kubeadm only uses the coredns/coredns path in its default repository
+		{"v1.21.0", "mirror.example.com/k8s", false, "mirror.example.com/k8s/coredns:v1.8.0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version+"/"+tc.mirror, func(t *testing.T) {
+			essentials, derived, err := KubeadmConfigImages(tc.mirror, tc.version)
+			if err != nil {
+				t.Fatalf("KubeadmConfigImages: %v", err)
+			}
+			if got := derived[len(derived)-1]; got != tc.coreDNS {
+				t.Errorf("kubeadm coredns image = %s, want %s", got, tc.coreDNS)
+			}
+			diff := cmp.Diff(essentials, derived)
+			if tc.agree && diff != "" {
+				t.Errorf("essentials and kubeadm images differ (-essentials +kubeadm):\n%s", diff)
+			}
+			if !tc.agree && diff == "" {
+				t.Errorf("essentials and kubeadm images agree, want them to differ: %v", essentials)
+			}
+		})
+	}
+}
+
+func TestKnownVersions(t *testing.T) {
+	known := KnownVersions()
+	if len(known) == 0 {
+		t.Fatalf("KnownVersions() is empty")
+	}
+	if oldest := known[0]; !oldest.Equals(semver.MustParse("1.12.0")) {
+		t.Errorf("oldest known version = %s, want 1.12.0", oldest)
+	}
+	if newest := known[len(known)-1]; !newest.Equals(semver.MustParse("1.25.0")) {
+		t.Errorf("newest known version = %s, want 1.25.0", newest)
+	}
+
+	for _, v := range []string{"1.12.0", "1.25.0-alpha.1", "1.25.3"} {
+		if err := CheckKnownVersion(semver.MustParse(v)); err != nil {
+			t.Errorf("CheckKnownVersion(%s) = %v, want nil", v, err)
+		}
+	}
+	err := CheckKnownVersion(semver.MustParse("1.26.0"))
+	if err == nil || !strings.Contains(err.Error(), "v1.12 to v1.25") {
+		t.Errorf("CheckKnownVersion(1.26.0) = %v, want an error listing the known versions", err)
+	}
+
+	// versions missing from the table fall back to looking up the tags of their images, offline to their last known good tags
+	SetOffline(true)
+	defer SetOffline(false)
+	if _, err := Kubeadm("", "v1.26.0"); err != nil {
+		t.Errorf("Kubeadm(v1.26.0) of a version missing from the table: %v", err)
+	}
+	if _, err := ImagesForVersion("", semver.MustParse("1.26.0"), ImageOptions{}); err != nil {
+		t.Errorf("ImagesForVersion(1.26.0) of a version missing from the table: %v", err)
+	}
+
+	SetAllowUnknownVersions(true)
+	defer SetAllowUnknownVersions(false)
+	if _, err := Kubeadm("", "v1.26.0"); err != nil {
+		t.Errorf("Kubeadm(v1.26.0) allowing unknown versions: %v", err)
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	known := KnownVersions()
+	newest := semver.MustParse(strings.TrimPrefix(constants.NewestKubernetesVersion, "v"))
+	if newest.LT(known[len(known)-1]) {
+		newest = known[len(known)-1]
+	}
+	tests := []struct {
+		alias string
+		want  semver.Version
+	}{
+		{"stable", semver.MustParse(strings.TrimPrefix(constants.DefaultKubernetesVersion, "v"))},
+		{"Stable", semver.MustParse(strings.TrimPrefix(constants.DefaultKubernetesVersion, "v"))},
+		{"latest", newest},
+		{"newest", newest},
+		{"v1.22.3", semver.MustParse("1.22.3")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.alias, func(t *testing.T) {
+			got, err := ResolveVersion(tc.alias)
+			if err != nil {
+				t.Fatalf("ResolveVersion: %v", err)
+			}
+			if !got.Equals(tc.want) {
+				t.Errorf("ResolveVersion(%s) = %s, want %s", tc.alias, got, tc.want)
+			}
+
+			aliased, err := KubeadmWithOptions("", tc.alias, ImageOptions{NoStorageProvisioner: true})
+			if err != nil {
+				t.Fatalf("KubeadmWithOptions(%s): %v", tc.alias, err)
+			}
+			concrete, err := KubeadmWithOptions("", "v"+got.String(), ImageOptions{NoStorageProvisioner: true})
+			if err != nil {
+				t.Fatalf("KubeadmWithOptions(%s): %v", got, err)
+			}
+			if diff := cmp.Diff(concrete, aliased); diff != "" {
+				t.Errorf("images of %s mismatch the images of %s (-want +got):\n%s", tc.alias, got, diff)
+			}
+		})
+	}
+
+	if _, err := ResolveVersion("edge"); err == nil {
+		t.Errorf("ResolveVersion of an unknown alias succeeded, want an error")
+	}
+}
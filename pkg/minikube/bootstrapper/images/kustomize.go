@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"sort"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// KustomizeImage is an entry of the images transformer of a kustomization
+type KustomizeImage struct {
+	Name    string `yaml:"name"`
+	NewName string `yaml:"newName,omitempty"`
+	NewTag  string `yaml:"newTag,omitempty"`
+	Digest  string `yaml:"digest,omitempty"`
+}
+
+// kustomization is the part of a kustomization file holding the images transformer
+type kustomization struct {
+	Images []KustomizeImage `yaml:"images"`
+}
+
+// KustomizeImages returns the images transformer which rewrites every upstream image of ImagesForVersion
+// to the image minikube would use, after mirror rewriting and digest pinning. Entries are ordered by name.
+func KustomizeImages(repo string, k8sVersion semver.Version, opts ImageOptions) ([]KustomizeImage, error) {
+	imgs, err := StructuredImagesForVersion(repo, k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := upstreamImages(k8sVersion, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "upstream images")
+	}
+
+	entries := []KustomizeImage{}
+	seen := map[string]bool{}
+	for _, img := range imgs {
+		u, ok := upstream[planKey(img)]
+		if !ok {
+			u = img
+		}
+		e := KustomizeImage{Name: imageName(u), NewTag: img.Tag, Digest: img.Digest}
+		if n := imageName(img); n != e.Name {
+			e.NewName = n
+		}
+		if seen[e.Name] {
+			continue
+		}
+		seen[e.Name] = true
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// KustomizeImagesYAML returns KustomizeImages as the images block of a kustomization file
+func KustomizeImagesYAML(repo string, k8sVersion semver.Version, opts ImageOptions) ([]byte, error) {
+	entries, err := KustomizeImages(repo, k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(kustomization{Images: entries})
+}
+
+// imageName returns the reference of img without its tag or digest
+func imageName(img Image) string {
+	if img.Registry == "" {
+		return img.Repo
+	}
+	return img.Registry + "/" + img.Repo
+}
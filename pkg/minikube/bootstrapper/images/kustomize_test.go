@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestKustomizeImagesYAML(t *testing.T) {
+	useTagCache(t, "")
+	SetOffline(true)
+	defer SetOffline(false)
+
+	// the storage-provisioner tag is set by the build, so it is left out of the golden files
+	opts := ImageOptions{NoStorageProvisioner: true}
+	tests := []struct {
+		name   string
+		mirror string
+	}{
+		{"upstream", ""},
+		{"mirror", "registry.corp:5000/mirror"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := KustomizeImagesYAML(tc.mirror, semver.MustParse("1.22.0"), opts)
+			if err != nil {
+				t.Fatalf("KustomizeImagesYAML: %v", err)
+			}
+			want, err := os.ReadFile(fmt.Sprintf("testdata/kustomize/v1.22/%s.yaml", tc.name))
+			if err != nil {
+				t.Fatalf("unable to read testdata: %v", err)
+			}
+			if diff := cmp.Diff(string(want), string(got)); diff != "" {
+				t.Errorf("kustomize images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+// lastGoodTagsPath returns the file the tags of the images of the last cluster started are kept in, an empty path disables them
+var lastGoodTagsPath = func() string {
+	return localpath.MakeMiniPath("cache", "last-good-tags.json")
+}
+
+// lastGoodTags holds the last good tags file, read once per process and kept up to date by RecordStartedImages.
+// The tags are keyed by lastGoodKey.
+var lastGoodTags struct {
+	sync.Mutex
+	path string
+	tags map[string]string
+}
+
+// lookedUpTags holds the tags this process looked up, or found in the tag cache, keyed by the tag list url of their repository
+var lookedUpTags sync.Map
+
+// lastGoodKey returns the key of the last good tag of the repository listing its tags at url, for the minor version of v
+func lastGoodKey(url string, v semver.Version) string {
+	return fmt.Sprintf("%s#v%d.%d", url, v.Major, v.Minor)
+}
+
+// loadLastGoodTags returns the tags the last clusters started with, or none if there is no last good tags file
+func loadLastGoodTags(path string) map[string]string {
+	tags := map[string]string{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Warningf("failed to read last good tags %s: %v", path, err)
+		}
+		return tags
+	}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		klog.Warningf("ignoring malformed last good tags %s: %v", path, err)
+		return map[string]string{}
+	}
+	return tags
+}
+
+// cachedLastGoodTags returns the tags of the last good tags file at path, reading it on first use. lastGoodTags must be locked.
+func cachedLastGoodTags(path string) map[string]string {
+	if lastGoodTags.tags == nil || lastGoodTags.path != path {
+		lastGoodTags.path = path
+		lastGoodTags.tags = loadLastGoodTags(path)
+	}
+	return lastGoodTags.tags
+}
+
+// lastGoodTagFor returns the tag a cluster of the minor version of v last started with for the repository listing its tags at url,
+// or lastKnownGood if none did
+func lastGoodTagFor(url string, v semver.Version, lastKnownGood string) string {
+	path := lastGoodTagsPath()
+	if path == "" {
+		return lastKnownGood
+	}
+	lastGoodTags.Lock()
+	defer lastGoodTags.Unlock()
+	if t := cachedLastGoodTags(path)[lastGoodKey(url, v)]; t != "" {
+		return t
+	}
+	return lastKnownGood
+}
+
+// RecordStartedImages records the tags of imgs, the images of a cluster of Kubernetes version v which started, so that they replace
+// the last known good tags minikube was built with when the latest tag of their repository can't be looked up.
+// Only the tags this process looked up are recorded, pinned and built-in tags are left out.
+func RecordStartedImages(v semver.Version, imgs []string) error {
+	path := lastGoodTagsPath()
+	if path == "" {
+		return nil
+	}
+	lastGoodTags.Lock()
+	defer lastGoodTags.Unlock()
+	tags := cachedLastGoodTags(path)
+	changed := false
+	for _, ref := range imgs {
+		img := parseImage(ref, "")
+		if img.Tag == "" || img.Registry == "" {
+			continue
+		}
+		url := tagListURL(img.Registry, img.Repo)
+		if t, ok := lookedUpTags.Load(url); !ok || t.(string) != img.Tag {
+			continue
+		}
+		key := lastGoodKey(url, v)
+		if tags[key] != img.Tag {
+			tags[key] = img.Tag
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "mkdir")
+	}
+	return lock.WriteFile(path, data, 0644)
+}
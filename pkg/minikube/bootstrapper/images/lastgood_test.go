@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+// useLastGoodTags points the last good tags file at path for the duration of the test, an empty path disables it.
+// The tags looked up by earlier tests are forgotten.
+func useLastGoodTags(t *testing.T, path string) {
+	t.Helper()
+	orig := lastGoodTagsPath
+	lastGoodTagsPath = func() string { return path }
+	forget := func() {
+		lastGoodTags.Lock()
+		lastGoodTags.tags = nil
+		lastGoodTags.Unlock()
+		lookedUpTags.Range(func(k, _ interface{}) bool {
+			lookedUpTags.Delete(k)
+			return true
+		})
+	}
+	forget()
+	t.Cleanup(func() {
+		lastGoodTagsPath = orig
+		forget()
+	})
+}
+
+func writeLastGoodTags(t *testing.T, path string, tags map[string]string) {
+	t.Helper()
+	data, err := json.Marshal(tags)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write last good tags: %v", err)
+	}
+}
+
+func TestLastGoodTagFallback(t *testing.T) {
+	defer func(d time.Duration) { tagLookupInterval = d }(tagLookupInterval)
+	tagLookupInterval = time.Millisecond
+	useTagCache(t, "")
+	defer SetInsecureRegistries(nil)
+
+	v := semver.MustParse("1.24.1")
+	tests := []struct {
+		name         string
+		persisted    string
+		persistedFor string
+		serverStatus int
+		offline      bool
+		pinnedOnly   bool
+		want         string
+	}{
+		{name: "Unreachable", persisted: "v1.8.7", persistedFor: "1.24.0", serverStatus: http.StatusNotFound, want: "v1.8.7"},
+		{name: "UnreachableOtherMinor", persisted: "v1.8.7", persistedFor: "1.23.0", serverStatus: http.StatusNotFound, want: "v1.8.6"},
+		{name: "UnreachableNothingPersisted", serverStatus: http.StatusNotFound, want: "v1.8.6"},
+		{name: "Reachable", persisted: "v1.8.7", persistedFor: "1.24.0", serverStatus: http.StatusOK, want: "v1.8.9"},
+		{name: "Offline", persisted: "v1.8.7", persistedFor: "1.24.0", serverStatus: http.StatusOK, offline: true, want: "v1.8.7"},
+		{name: "PinnedOnly", persisted: "v1.8.7", persistedFor: "1.24.0", serverStatus: http.StatusOK, pinnedOnly: true, want: "v1.8.6"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.serverStatus)
+				if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+					t.Errorf("failed to write https response")
+				}
+			}))
+			defer server.Close()
+			repo := strings.TrimPrefix(server.URL, "https://")
+			SetInsecureRegistries([]string{repo})
+
+			path := filepath.Join(t.TempDir(), "last-good-tags.json")
+			useLastGoodTags(t, path)
+			if tc.persisted != "" {
+				key := lastGoodKey(tagListURL(repo, "coredns"), semver.MustParse(tc.persistedFor))
+				writeLastGoodTags(t, path, map[string]string{key: tc.persisted})
+			}
+			SetOffline(tc.offline)
+			defer SetOffline(false)
+			SetPinnedOnly(tc.pinnedOnly)
+			defer SetPinnedOnly(false)
+
+			if got := latestTag(v, repo, "coredns", "v1.8.6", false); got != tc.want {
+				t.Errorf("latestTag() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRecordStartedImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "last-good-tags.json")
+	useLastGoodTags(t, path)
+	v := semver.MustParse("1.24.1")
+	for url, tag := range map[string]string{
+		tagListURL("registry.example.com", "pause"):                     "3.7",
+		tagListURL("registry.example.com", "coredns/coredns"):           "v1.8.7",
+		tagListURL("mirror.example.com:5000/google_containers", "etcd"): "3.5.4-0",
+	} {
+		lookedUpTags.Store(url, tag)
+	}
+	imgs := []string{
+		"registry.example.com/pause:3.7",
+		"registry.example.com/coredns/coredns:v1.8.7",
+		// pinned or built-in rather than looked up
+		"mirror.example.com:5000/google_containers/etcd:3.5.3-0",
+		"registry.example.com/kube-apiserver:v1.24.1",
+		// not looked up by registry, or without a tag
+		"kindest/kindnetd:v20210326-1e038dc5",
+		"registry.example.com/kube-proxy@sha256:9ce33ba33d8e738a5b85ed50b5080ac746deceed4a7496c550927a7a19ca3b6d",
+	}
+	if err := RecordStartedImages(v, imgs); err != nil {
+		t.Fatalf("RecordStartedImages: %v", err)
+	}
+
+	want := map[string]string{
+		lastGoodKey(tagListURL("registry.example.com", "pause"), v):           "3.7",
+		lastGoodKey(tagListURL("registry.example.com", "coredns/coredns"), v): "v1.8.7",
+	}
+	if diff := cmp.Diff(want, loadLastGoodTags(path)); diff != "" {
+		t.Errorf("last good tags mismatch (-want +got):\n%s", diff)
+	}
+
+	// tags looked up without any registry request fall back on the ones recorded for the same minor version
+	if got := latestTag(semver.MustParse("1.24.3"), "registry.example.com", "pause", defaultPauseVersion, true); got != "3.7" {
+		t.Errorf("latestTag(noLookup) = %s, want the recorded 3.7", got)
+	}
+	if got := latestTag(semver.MustParse("1.25.0"), "registry.example.com", "pause", defaultPauseVersion, true); got != defaultPauseVersion {
+		t.Errorf("latestTag(noLookup) of another minor version = %s, want %s", got, defaultPauseVersion)
+	}
+	if got := latestTag(v, "registry.example.com", "etcd", defaultEtcdVersion, true); got != defaultEtcdVersion {
+		t.Errorf("latestTag(noLookup) of a repository without a recorded tag = %s, want %s", got, defaultEtcdVersion)
+	}
+
+	// a later start replaces the tags of the images it started with only, and the file isn't read again
+	lookedUpTags.Store(tagListURL("registry.example.com", "pause"), "3.8")
+	if err := RecordStartedImages(v, []string{"registry.example.com/pause:3.8"}); err != nil {
+		t.Fatalf("RecordStartedImages: %v", err)
+	}
+	want[lastGoodKey(tagListURL("registry.example.com", "pause"), v)] = "3.8"
+	if diff := cmp.Diff(want, loadLastGoodTags(path)); diff != "" {
+		t.Errorf("last good tags mismatch after a later start (-want +got):\n%s", diff)
+	}
+	writeLastGoodTags(t, path, map[string]string{})
+	if got := latestTag(v, "registry.example.com", "pause", defaultPauseVersion, true); got != "3.8" {
+		t.Errorf("latestTag(noLookup) after the file changed = %s, want the 3.8 read once per process", got)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+// ImageLockFile is the name of the file an air-gapped bundle records its ImageLock in
+const ImageLockFile = "images.lock"
+
+// LockedImage is an image reference along with the digest its registry resolved it to
+type LockedImage struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+// ImageLock records the digest of every image of a bundle, so that the exact same images can be pulled again later
+type ImageLock struct {
+	Images []LockedImage `json:"images"`
+}
+
+// LockMismatch is an image whose current digest isn't the one recorded in the lock, Locked is empty if the lock doesn't hold the image
+type LockMismatch struct {
+	Image   string
+	Locked  string
+	Current string
+}
+
+// LockImages requests the digest of every image from its registry, returning them sorted by reference
+func LockImages(ctx context.Context, images []string) (ImageLock, error) {
+	if offline {
+		return ImageLock{}, errors.New("can't lock images in offline mode")
+	}
+	images = dedupe(images)
+	digests := make([]string, len(images))
+	errs := make([]error, len(images))
+	runBounded(ctx, len(images), func(i int) {
+		desc, err := headImage(ctx, images[i])
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		digests[i] = desc.Digest.String()
+	})
+	if err := ctx.Err(); err != nil {
+		return ImageLock{}, err
+	}
+
+	l := ImageLock{Images: []LockedImage{}}
+	for i, img := range images {
+		if errs[i] != nil {
+			return ImageLock{}, errors.Wrapf(errs[i], "resolving %s", img)
+		}
+		l.Images = append(l.Images, LockedImage{Image: img, Digest: digests[i]})
+	}
+	return l, nil
+}
+
+// WriteImageLock writes l to path as indented JSON, sorted by reference so that the same images always produce the same file
+func WriteImageLock(path string, l ImageLock) error {
+	sorted := ImageLock{Images: append([]LockedImage{}, l.Images...)}
+	sort.Slice(sorted.Images, func(i, j int) bool { return sorted.Images[i].Image < sorted.Images[j].Image })
+	data, err := json.MarshalIndent(sorted, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+	return lock.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadImageLock reads the ImageLock written by WriteImageLock at path
+func LoadImageLock(path string) (ImageLock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImageLock{}, errors.Wrap(err, "reading image lock")
+	}
+	l := ImageLock{}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return ImageLock{}, errors.Wrapf(err, "parsing image lock %s", path)
+	}
+	for _, img := range l.Images {
+		if !digestExpression.MatchString(img.Digest) {
+			return ImageLock{}, fmt.Errorf("invalid digest %q for %s in image lock %s", img.Digest, img.Image, path)
+		}
+	}
+	return l, nil
+}
+
+// Mismatches returns the images of current whose digest differs from the one l records for them, or which l doesn't hold.
+// Images l holds which aren't in current are ignored, as a bundle may be verified one part at a time.
+func (l ImageLock) Mismatches(current ImageLock) []LockMismatch {
+	locked := map[string]string{}
+	for _, img := range l.Images {
+		locked[img.Image] = img.Digest
+	}
+	mismatches := []LockMismatch{}
+	for _, img := range current.Images {
+		digest, ok := locked[img.Image]
+		if !ok {
+			klog.Warningf("%s is not in the image lock", img.Image)
+		} else if digest != img.Digest {
+			klog.Warningf("%s has digest %s, but the image lock records %s", img.Image, img.Digest, digest)
+		}
+		if digest != img.Digest {
+			mismatches = append(mismatches, LockMismatch{Image: img.Image, Locked: digest, Current: img.Digest})
+		}
+	}
+	return mismatches
+}
+
+// VerifyImageLock requests the current digest of every image from its registry, returning the images which no longer match l
+func VerifyImageLock(ctx context.Context, l ImageLock, images []string) ([]LockMismatch, error) {
+	current, err := LockImages(ctx, images)
+	if err != nil {
+		return nil, err
+	}
+	return l.Mismatches(current), nil
+}
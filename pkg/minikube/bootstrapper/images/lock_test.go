@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImageLock(t *testing.T) {
+	useRateLimit(t, 1000)
+	digests := map[string]string{
+		"/v2/kube-apiserver/manifests/v1.24.0": "a",
+		"/v2/etcd/manifests/3.5.3-0":           "b",
+		"/v2/pause/manifests/3.7":              "c",
+	}
+	server := newDigestRegistry(digests)
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+	imgs := []string{registry + "/pause:3.7", registry + "/kube-apiserver:v1.24.0", registry + "/etcd:3.5.3-0", registry + "/pause:3.7"}
+
+	l, err := LockImages(context.Background(), imgs)
+	if err != nil {
+		t.Fatalf("LockImages: %v", err)
+	}
+	want := ImageLock{Images: []LockedImage{
+		{Image: registry + "/etcd:3.5.3-0", Digest: "sha256:" + strings.Repeat("b", 64)},
+		{Image: registry + "/kube-apiserver:v1.24.0", Digest: "sha256:" + strings.Repeat("a", 64)},
+		{Image: registry + "/pause:3.7", Digest: "sha256:" + strings.Repeat("c", 64)},
+	}}
+	if diff := cmp.Diff(want, l); diff != "" {
+		t.Errorf("lock mismatch (-want +got):\n%s", diff)
+	}
+
+	path := filepath.Join(t.TempDir(), ImageLockFile)
+	if err := WriteImageLock(path, l); err != nil {
+		t.Fatalf("WriteImageLock: %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read lock: %v", err)
+	}
+	loaded, err := LoadImageLock(path)
+	if err != nil {
+		t.Fatalf("LoadImageLock: %v", err)
+	}
+	if diff := cmp.Diff(l, loaded); diff != "" {
+		t.Errorf("loaded lock mismatch (-want +got):\n%s", diff)
+	}
+	// the same images in another order write the same file
+	reversed := ImageLock{Images: []LockedImage{l.Images[2], l.Images[0], l.Images[1]}}
+	if err := WriteImageLock(path, reversed); err != nil {
+		t.Fatalf("WriteImageLock: %v", err)
+	}
+	if rewritten, err := os.ReadFile(path); err != nil || string(rewritten) != string(written) {
+		t.Errorf("rewritten lock = %q (err %v), want the stable %q", rewritten, err, written)
+	}
+
+	mismatches, err := VerifyImageLock(context.Background(), loaded, imgs)
+	if err != nil {
+		t.Fatalf("VerifyImageLock: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Errorf("mismatches = %v for unchanged images, want none", mismatches)
+	}
+
+	digests["/v2/etcd/manifests/3.5.3-0"] = "d"
+	digests["/v2/coredns/manifests/v1.8.6"] = "e"
+	mismatches, err = VerifyImageLock(context.Background(), loaded, append(imgs, registry+"/coredns:v1.8.6"))
+	if err != nil {
+		t.Fatalf("VerifyImageLock: %v", err)
+	}
+	wantMismatches := []LockMismatch{
+		{Image: registry + "/coredns:v1.8.6", Current: "sha256:" + strings.Repeat("e", 64)},
+		{Image: registry + "/etcd:3.5.3-0", Locked: "sha256:" + strings.Repeat("b", 64), Current: "sha256:" + strings.Repeat("d", 64)},
+	}
+	if diff := cmp.Diff(wantMismatches, mismatches); diff != "" {
+		t.Errorf("mismatches (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadImageLockInvalidDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ImageLockFile)
+	if err := os.WriteFile(path, []byte(`{"images": [{"image": "k8s.gcr.io/pause:3.7", "digest": "sha256:abc"}]}`), 0644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	if _, err := LoadImageLock(path); err == nil {
+		t.Errorf("LoadImageLock with an invalid digest succeeded, want an error")
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TagLookup is how the latest tag of a component is found
+type TagLookup struct {
+	// URLTemplate is the url of the tag list, with %s standing for the registry host, such as https://%s/v2/coredns/coredns/tags/list.
+	// The namespace of the repository the tags are resolved in, if any, is inserted after /v2/.
+	URLTemplate string
+	// LastKnownGood is the tag used when the latest tag can't be found
+	LastKnownGood string
+	// Parse returns the tags listed in a tag list response, the registry's own tag list formats are recognized if nil
+	Parse func(url string, body []byte) ([]string, error)
+}
+
+// TagLookups maps components to how their latest tag is found, so their dynamic tags can be resolved at once.
+// Components must be registered before resolving, registering isn't safe to do concurrently.
+type TagLookups struct {
+	lookups map[string]TagLookup
+}
+
+// NewTagLookups returns an empty registry of tag lookups
+func NewTagLookups() *TagLookups {
+	return &TagLookups{lookups: map[string]TagLookup{}}
+}
+
+// Register adds the lookup of component, which must not already be registered
+func (r *TagLookups) Register(component string, l TagLookup) error {
+	if component == "" {
+		return errors.New("component name is empty")
+	}
+	if _, ok := r.lookups[component]; ok {
+		return fmt.Errorf("component %q is already registered", component)
+	}
+	if strings.Count(l.URLTemplate, "%s") != 1 {
+		return fmt.Errorf("url template %q of %s must contain a single %%s for the repository", l.URLTemplate, component)
+	}
+	if l.LastKnownGood == "" {
+		return fmt.Errorf("component %q has no last known good tag", component)
+	}
+	r.lookups[component] = l
+	return nil
+}
+
+// Components returns the registered components, sorted by name
+func (r *TagLookups) Components() []string {
+	names := []string{}
+	for c := range r.lookups {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveAll concurrently looks up the latest tag of every registered component in repo, keyed by component.
+// Components whose latest tag can't be found, or which aren't looked up before ctx is done, get their last known good tag.
+func (r *TagLookups) ResolveAll(ctx context.Context, repo string) map[string]string {
+	components := r.Components()
+	lookups := []tagLookup{}
+	for _, c := range components {
+		l := r.lookups[c]
+		lookups = append(lookups, tagLookup{url: expandTagURLTemplate(l.URLTemplate, repo), lastKnownGood: l.LastKnownGood, parse: l.Parse})
+	}
+
+	tags := map[string]string{}
+	for i, tag := range findLatestTags(ctx, lookups) {
+		tags[components[i]] = tag
+	}
+	return tags
+}
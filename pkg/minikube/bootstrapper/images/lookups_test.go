@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTagLookupsResolveAll(t *testing.T) {
+	useTagCache(t, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/coredns/coredns/tags/list":
+			_, _ = w.Write([]byte(`{"name": "coredns/coredns", "tags": ["v1.8.6", "v1.9.3"]}`))
+		case "/cni/tags":
+			_, _ = w.Write([]byte("v1.1.0\nv1.1.1\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	lines := func(url string, body []byte) ([]string, error) {
+		return strings.Fields(string(body)), nil
+	}
+	r := NewTagLookups()
+	lookups := map[string]TagLookup{
+		"coredns": {URLTemplate: "http://%s/v2/coredns/coredns/tags/list", LastKnownGood: "v1.8.6"},
+		"cni":     {URLTemplate: "http://%s/cni/tags", LastKnownGood: "v1.0.0", Parse: lines},
+		"missing": {URLTemplate: "http://%s/v2/missing/tags/list", LastKnownGood: "v0.1.0"},
+	}
+	for c, l := range lookups {
+		if err := r.Register(c, l); err != nil {
+			t.Fatalf("Register(%s): %v", c, err)
+		}
+	}
+
+	got := r.ResolveAll(context.Background(), strings.TrimPrefix(server.URL, "http://"))
+	want := map[string]string{"coredns": "v1.9.3", "cni": "v1.1.1", "missing": "v0.1.0"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("resolved tags mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTagLookupsRegister(t *testing.T) {
+	r := NewTagLookups()
+	if err := r.Register("etcd", TagLookup{URLTemplate: "https://%s/v2/etcd/tags/list", LastKnownGood: "3.5.3-0"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		component string
+		lookup    TagLookup
+	}{
+		{"duplicate", "etcd", TagLookup{URLTemplate: "https://%s/v2/etcd/tags/list", LastKnownGood: "3.5.3-0"}},
+		{"no name", "", TagLookup{URLTemplate: "https://%s/v2/pause/tags/list", LastKnownGood: "3.7"}},
+		{"no repository", "pause", TagLookup{URLTemplate: "https://k8s.gcr.io/v2/pause/tags/list", LastKnownGood: "3.7"}},
+		{"no last known good", "pause", TagLookup{URLTemplate: "https://%s/v2/pause/tags/list"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := r.Register(tc.component, tc.lookup); err == nil {
+				t.Errorf("Register(%q) succeeded, want an error", tc.component)
+			}
+		})
+	}
+	if diff := cmp.Diff([]string{"etcd"}, r.Components()); diff != "" {
+		t.Errorf("components mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// FirstReachableMirror returns the first of mirrors which serves the pause image of k8sVersion, checking them in order.
+// An empty mirror stands for the upstream repository.
+func FirstReachableMirror(ctx context.Context, mirrors []string, k8sVersion semver.Version) (string, error) {
+	if len(mirrors) == 0 {
+		return "", errors.New("no mirrors given")
+	}
+	for _, m := range mirrors {
+		if err := ValidateMirror(m); err != nil {
+			return "", err
+		}
+	}
+	if offline {
+		klog.Infof("offline, using the first mirror %q without checking it", mirrors[0])
+		return mirrors[0], nil
+	}
+
+	for _, m := range mirrors {
+		probe := pause(k8sVersion, m, false)
+		missing, err := imageMissing(ctx, probe)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if err != nil {
+			klog.Warningf("mirror %q is unreachable, trying the next one: %v", m, err)
+			continue
+		}
+		if missing {
+			klog.Warningf("mirror %q doesn't serve %s, trying the next one", m, probe)
+			continue
+		}
+		return m, nil
+	}
+	return "", fmt.Errorf("none of the mirrors %q serve the images of Kubernetes %s", mirrors, k8sVersion)
+}
+
+// ImagesForMirrors returns ImagesForVersion for the first reachable of mirrors, along with the chosen mirror
+func ImagesForMirrors(ctx context.Context, mirrors []string, k8sVersion semver.Version, opts ImageOptions) ([]string, string, error) {
+	mirror, err := FirstReachableMirror(ctx, mirrors, k8sVersion)
+	if err != nil {
+		return nil, "", err
+	}
+	imgs, err := ImagesForVersion(mirror, k8sVersion, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return imgs, mirror, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+// newTestRegistry returns a registry serving every manifest if serves is set, and none otherwise
+func newTestRegistry(serves bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			return
+		}
+		if !serves {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.list.v2+json")
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+		w.Header().Set("Content-Length", "0")
+	}))
+}
+
+func TestImagesForMirrors(t *testing.T) {
+	empty := newTestRegistry(false)
+	defer empty.Close()
+	full := newTestRegistry(true)
+	defer full.Close()
+	down := newTestRegistry(true)
+	down.Close()
+
+	host := func(s *httptest.Server) string {
+		return strings.TrimPrefix(s.URL, "http://")
+	}
+	v := semver.MustParse("1.24.0")
+
+	tests := []struct {
+		name    string
+		mirrors []string
+		want    string
+	}{
+		{"first missing", []string{host(empty), host(full)}, host(full)},
+		{"first unreachable", []string{host(down), host(full)}, host(full)},
+		{"first serves", []string{host(full), host(empty)}, host(full)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			imgs, mirror, err := ImagesForMirrors(context.Background(), tc.mirrors, v, ImageOptions{})
+			if err != nil {
+				t.Fatalf("ImagesForMirrors: %v", err)
+			}
+			if mirror != tc.want {
+				t.Errorf("mirror = %s, want %s", mirror, tc.want)
+			}
+			for _, img := range imgs {
+				if !strings.HasPrefix(img, tc.want+"/") {
+					t.Errorf("image %s isn't from mirror %s", img, tc.want)
+				}
+			}
+		})
+	}
+
+	if _, _, err := ImagesForMirrors(context.Background(), []string{host(empty), host(down)}, v, ImageOptions{}); err == nil {
+		t.Errorf("ImagesForMirrors without a reachable mirror succeeded, want an error")
+	}
+}
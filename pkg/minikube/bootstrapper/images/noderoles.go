@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"github.com/blang/semver/v4"
+)
+
+// NodeRole is the part a node plays in a cluster, which decides the images it needs
+type NodeRole string
+
+const (
+	// NodeControlPlane is the role of the nodes running the control plane, which run workloads too
+	NodeControlPlane NodeRole = "control-plane"
+	// NodeWorker is the role of the nodes running workloads only
+	NodeWorker NodeRole = "worker"
+)
+
+// controlPlaneOnly are the components run as static pods of the control plane nodes, keyed by component
+var controlPlaneOnly = map[string]bool{
+	"kube-apiserver":          true,
+	"kube-controller-manager": true,
+	"kube-scheduler":          true,
+	"etcd":                    true,
+	"kube-vip":                true,
+}
+
+// ImagesByNodeRole returns the images of StructuredImagesForVersion split by the role of the nodes they are needed on,
+// so that worker nodes aren't given the control plane images. Control plane nodes need every image, worker nodes every
+// image but those of the components which only run on control plane nodes, such as kube-apiserver and etcd.
+// Images given to SetImageList are needed on every node, as their role isn't known.
+func ImagesByNodeRole(repo string, k8sVersion semver.Version, opts ImageOptions) (map[NodeRole][]Image, error) {
+	imgs, err := StructuredImagesForVersion(repo, k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	workers := []Image{}
+	for _, img := range imgs {
+		if img.Role == RoleControlPlane || img.Role == RoleAuxiliary {
+			if controlPlaneOnly[component(img.String())] {
+				continue
+			}
+		}
+		workers = append(workers, img)
+	}
+	return map[NodeRole][]Image{
+		NodeControlPlane: imgs,
+		NodeWorker:       workers,
+	}, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+// componentsOf returns the sorted component of every image
+func componentsOf(imgs []Image) []string {
+	names := []string{}
+	for _, img := range imgs {
+		names = append(names, component(img.String()))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestImagesByNodeRole(t *testing.T) {
+	v := semver.MustParse("1.24.0")
+	tests := []struct {
+		name    string
+		opts    ImageOptions
+		workers []string
+	}{
+		{"kindnet", ImageOptions{CNI: "kindnet"}, []string{"coredns", "kindnetd", "kube-proxy", "pause"}},
+		{"calico", ImageOptions{CNI: "calico"}, []string{"cni", "coredns", "kube-controllers", "kube-proxy", "node", "pause", "pod2daemon-flexvol"}},
+		{"kube-proxyless", ImageOptions{CNI: "cilium", KubeProxyless: true}, []string{"cilium", "coredns", "operator-generic", "pause"}},
+		{"HA", ImageOptions{CNI: "kindnet", HA: true}, []string{"coredns", "kindnetd", "kube-proxy", "pause"}},
+		{"MetricsServer", ImageOptions{CNI: "kindnet", MetricsServer: true}, []string{"coredns", "kindnetd", "kube-proxy", "metrics-server", "pause"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.NoStorageProvisioner = true
+			tc.opts.Offline = true
+			got, err := ImagesByNodeRole("", v, tc.opts)
+			if err != nil {
+				t.Fatalf("ImagesByNodeRole: %v", err)
+			}
+			all, err := StructuredImagesForVersion("", v, tc.opts)
+			if err != nil {
+				t.Fatalf("StructuredImagesForVersion: %v", err)
+			}
+			if diff := cmp.Diff(componentsOf(all), componentsOf(got[NodeControlPlane])); diff != "" {
+				t.Errorf("control plane images mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.workers, componentsOf(got[NodeWorker])); diff != "" {
+				t.Errorf("worker images mismatch (-want +got):\n%s", diff)
+			}
+			for _, img := range got[NodeWorker] {
+				if c := component(img.String()); c == "kube-apiserver" || c == "kube-scheduler" || c == "kube-controller-manager" || c == "etcd" || c == "kube-vip" {
+					t.Errorf("worker nodes are given the control plane image %s", img)
+				}
+			}
+		})
+	}
+}
+
+func TestImagesByNodeRoleListed(t *testing.T) {
+	if err := SetImageList([]string{"k8s.gcr.io/kube-apiserver:v1.24.0", "example.com/app:1.0"}); err != nil {
+		t.Fatalf("SetImageList: %v", err)
+	}
+	defer func() {
+		if err := SetImageList(nil); err != nil {
+			t.Fatalf("reset image list: %v", err)
+		}
+	}()
+	got, err := ImagesByNodeRole("", semver.MustParse("1.24.0"), ImageOptions{})
+	if err != nil {
+		t.Fatalf("ImagesByNodeRole: %v", err)
+	}
+	if diff := cmp.Diff(got[NodeControlPlane], got[NodeWorker], cmp.AllowUnexported(Image{})); diff != "" {
+		t.Errorf("listed images mismatch between the node roles (-control-plane +worker):\n%s", diff)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"path"
+	"sort"
+)
+
+// pullSizeHints is the approximate size in MB of the images minikube pulls, keyed by component
+var pullSizeHints = map[string]int{
+	// Kubernetes
+	"etcd":                    300,
+	"kube-apiserver":          130,
+	"kube-controller-manager": 120,
+	"kube-proxy":              110,
+	"kube-scheduler":          50,
+	"coredns":                 46,
+	"pause":                   1,
+	// minikube
+	"storage-provisioner": 31,
+	"kube-vip":            40,
+	// CNI
+	"cilium":             440,
+	"cni":                230,
+	"node":               220,
+	"kindnetd":           100,
+	"kube-controllers":   70,
+	"flannel":            60,
+	"operator-generic":   60,
+	"pod2daemon-flexvol": 20,
+}
+
+// component returns the component an image reference is of, such as kube-apiserver for k8s.gcr.io/kube-apiserver:v1.24.0
+func component(ref string) string {
+	return path.Base(parseImage(ref, "").Repo)
+}
+
+// PullSizeHint returns the approximate size in MB of the image ref, or 0 if its component is unknown
+func PullSizeHint(ref string) int {
+	return pullSizeHints[component(ref)]
+}
+
+// SortByPullSize returns refs ordered largest first, so failures pulling the large images surface early.
+// Images of the same or unknown size are ordered alphabetically.
+func SortByPullSize(refs []string) []string {
+	sorted := append([]string{}, refs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		si, sj := PullSizeHint(sorted[i]), PullSizeHint(sorted[j])
+		if si != sj {
+			return si > sj
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
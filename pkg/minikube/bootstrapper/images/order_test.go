@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSortByPullSize(t *testing.T) {
+	refs := []string{
+		"k8s.gcr.io/pause:3.7",
+		"example.com/zeta:v1",
+		"k8s.gcr.io/kube-scheduler:v1.24.0",
+		"k8s.gcr.io/kube-apiserver:v1.24.0",
+		"example.com/alpha:v1",
+		"k8s.gcr.io/coredns/coredns:v1.8.6",
+		"k8s.gcr.io/etcd:3.5.3-0",
+	}
+	want := []string{
+		"k8s.gcr.io/etcd:3.5.3-0",
+		"k8s.gcr.io/kube-apiserver:v1.24.0",
+		"k8s.gcr.io/kube-scheduler:v1.24.0",
+		"k8s.gcr.io/coredns/coredns:v1.8.6",
+		"k8s.gcr.io/pause:3.7",
+		"example.com/alpha:v1",
+		"example.com/zeta:v1",
+	}
+	got := SortByPullSize(refs)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SortByPullSize mismatch (-want +got):\n%s", diff)
+	}
+	if refs[0] != "k8s.gcr.io/pause:3.7" {
+		t.Errorf("SortByPullSize modified its input: %v", refs)
+	}
+}
+
+func TestSortByPullSizeApiserverBeforePause(t *testing.T) {
+	imgs, err := Kubeadm("", "v1.24.0")
+	if err != nil {
+		t.Fatalf("Kubeadm: %v", err)
+	}
+	apiserver, pause := -1, -1
+	for i, ref := range SortByPullSize(imgs) {
+		switch component(ref) {
+		case "kube-apiserver":
+			apiserver = i
+		case "pause":
+			pause = i
+		}
+	}
+	if apiserver < 0 || pause < 0 || apiserver > pause {
+		t.Errorf("kube-apiserver at %d, pause at %d, want kube-apiserver first", apiserver, pause)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"path"
+)
+
+// supportedOSes are the operating systems of the nodes images can be listed for
+var supportedOSes = map[string]bool{
+	"linux":   true,
+	"windows": true,
+}
+
+// osVariantImages are the images run on every node, which are published with a tag per operating system
+// other than linux, such as pause:3.5-windows. The other control plane images always run on linux.
+var osVariantImages = map[string]bool{
+	"pause": true,
+}
+
+// targetOS returns the operating system the images of opts are for, defaulting to linux
+func targetOS(opts ImageOptions) string {
+	if opts.OS != "" {
+		return opts.OS
+	}
+	return "linux"
+}
+
+// validateOS returns an error if images can't be listed for nodes running os
+func validateOS(os string) error {
+	if os != "" && !supportedOSes[os] {
+		return fmt.Errorf("unsupported operating system: %q", os)
+	}
+	return nil
+}
+
+// forOS returns the reference ref has on os, suffixing the tag of images published per operating system
+func forOS(ref string, os string) string {
+	img := parseImage(ref, "")
+	if os == "linux" || !osVariantImages[path.Base(img.Repo)] || img.Tag == "" || img.Digest != "" {
+		return ref
+	}
+	return ref + "-" + os
+}
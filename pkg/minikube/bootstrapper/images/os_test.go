@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestEssentialsForOS(t *testing.T) {
+	tests := []struct {
+		os    string
+		pause string
+	}{
+		{"", "k8s.gcr.io/pause:3.5"},
+		{"linux", "k8s.gcr.io/pause:3.5"},
+		{"windows", "k8s.gcr.io/pause:3.5-windows"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.os, func(t *testing.T) {
+			imgs, err := KubeadmWithOptions("", "v1.22.0", ImageOptions{OS: tc.os})
+			if err != nil {
+				t.Fatalf("KubeadmWithOptions: %v", err)
+			}
+			got := map[string]bool{}
+			for _, img := range imgs {
+				got[img] = true
+			}
+			for _, want := range []string{tc.pause, "k8s.gcr.io/etcd:3.5.0-0", "k8s.gcr.io/kube-apiserver:v1.22.0"} {
+				if !got[want] {
+					t.Errorf("missing %s in %v", want, imgs)
+				}
+			}
+		})
+	}
+
+	if _, err := ImagesForVersion("", semver.MustParse("1.22.0"), ImageOptions{OS: "plan9"}); err == nil {
+		t.Errorf("ImagesForVersion with os plan9 succeeded, want an error")
+	}
+}
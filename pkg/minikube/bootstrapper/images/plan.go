@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	// goembed needs this
+	_ "embed"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+)
+
+// PlanStatus is how starting a cluster would obtain an image
+type PlanStatus string
+
+const (
+	// PlanCached is the status of images loaded from the image cache
+	PlanCached PlanStatus = "cached"
+	// PlanWillPull is the status of images pulled from their upstream repository
+	PlanWillPull PlanStatus = "will-pull"
+	// PlanMirrorRewritten is the status of images pulled from a mirror rather than their upstream repository
+	PlanMirrorRewritten PlanStatus = "mirror-rewritten"
+)
+
+// PlannedImage is a required image and how it would be obtained
+type PlannedImage struct {
+	// Image is the reference which would be fetched, after mirror rewriting and digest pinning
+	Image string `json:"image"`
+	// Upstream is the reference of the image in its upstream repository, if Image is rewritten to a mirror
+	Upstream string     `json:"upstream,omitempty"`
+	Role     Role       `json:"role"`
+	Status   PlanStatus `json:"status"`
+}
+
+// ImagePlan is the report of which images starting a cluster would pull, without pulling any
+type ImagePlan struct {
+	Images []PlannedImage `json:"images"`
+}
+
+// imagePlanSchema is the JSON schema of ImagePlan, which external tools validate the plans minikube outputs against
+//
+//go:embed plan_schema.json
+var imagePlanSchema []byte
+
+// ImagePlanSchema returns the JSON schema of ImagePlan, as marshaled to JSON
+func ImagePlanSchema() []byte {
+	return append([]byte{}, imagePlanSchema...)
+}
+
+// PlanImages reports how every image of ImagesForVersion would be obtained, given the images saved in cacheDir
+func PlanImages(repo string, k8sVersion semver.Version, opts ImageOptions, cacheDir string) (ImagePlan, error) {
+	imgs, err := StructuredImagesForVersion(repo, k8sVersion, opts)
+	if err != nil {
+		return ImagePlan{}, err
+	}
+	upstream, err := upstreamImages(k8sVersion, opts)
+	if err != nil {
+		return ImagePlan{}, errors.Wrap(err, "upstream images")
+	}
+
+	missing, err := MissingImages(References(imgs), cacheDir)
+	if err != nil {
+		return ImagePlan{}, errors.Wrap(err, "cached images")
+	}
+	uncached := map[string]bool{}
+	for _, img := range missing {
+		uncached[img] = true
+	}
+
+	plan := ImagePlan{Images: []PlannedImage{}}
+	for _, img := range imgs {
+		p := PlannedImage{Image: img.String(), Role: img.Role, Status: PlanWillPull}
+		if u, ok := upstream[planKey(img)]; ok && (u.Registry != img.Registry || u.Repo != img.Repo) {
+			p.Upstream = u.String()
+			p.Status = PlanMirrorRewritten
+		}
+		if !uncached[p.Image] {
+			p.Status = PlanCached
+		}
+		plan.Images = append(plan.Images, p)
+	}
+	return plan, nil
+}
+
+// ImageRewrite is a required image, along with the upstream reference it was rewritten from
+type ImageRewrite struct {
+	// Original is the reference of the image in its upstream repository, the same as Final unless a mirror rewrote it
+	Original string
+	// Final is the reference which would be fetched, after mirror rewriting and digest pinning
+	Final string
+}
+
+// Rewritten reports whether a mirror rewrote the image
+func (r ImageRewrite) Rewritten() bool {
+	return r.Original != r.Final
+}
+
+// ImageRewrites returns every image of ImagesForVersion, paired with the upstream image it was rewritten from if any
+func ImageRewrites(repo string, k8sVersion semver.Version, opts ImageOptions) ([]ImageRewrite, error) {
+	imgs, err := StructuredImagesForVersion(repo, k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := upstreamImages(k8sVersion, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "upstream images")
+	}
+
+	rewrites := []ImageRewrite{}
+	for _, img := range imgs {
+		r := ImageRewrite{Original: img.String(), Final: img.String()}
+		if u, ok := upstream[planKey(img)]; ok && (u.Registry != img.Registry || u.Repo != img.Repo) {
+			r.Original = u.String()
+		}
+		rewrites = append(rewrites, r)
+	}
+	return rewrites, nil
+}
+
+// upstreamImages returns the images of StructuredImagesForVersion without any mirror, keyed by planKey.
+// They are what the mirror replaces, so the registry policy doesn't apply to them.
+func upstreamImages(k8sVersion semver.Version, opts ImageOptions) (map[string]Image, error) {
+	opts.ComponentRepos = nil
+	imgs, err := classifiedImages("", k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	upstream := map[string]Image{}
+	for _, img := range imgs {
+		upstream[planKey(img)] = img
+	}
+	return upstream, nil
+}
+
+// planKey identifies img regardless of the repository it is pulled from
+func planKey(img Image) string {
+	return string(img.Role) + "/" + component(img.String())
+}
+
+// PreviewImages returns the images ImagesForVersion lists for mirror, k8sVersion and cni, without any network access,
+// so that what a mirror rewrites can be inspected before a cluster uses it. Tags which ImagesForVersion would look up
+// are those already looked up by this process, or their last known good tag.
+func PreviewImages(mirror string, k8sVersion semver.Version, cni string) ([]string, error) {
+	return ImagesForVersion(mirror, k8sVersion, ImageOptions{CNI: cni, Offline: true})
+}
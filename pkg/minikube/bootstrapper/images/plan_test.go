@@ -0,0 +1,269 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+func TestPlanImages(t *testing.T) {
+	dir := t.TempDir()
+	for _, img := range []string{"k8s.gcr.io/pause:3.7", "mirror.example.com/k8s/pause:3.7", "k8s.gcr.io/etcd:3.5.3-0"} {
+		p := localpath.SanitizeCacheDir(filepath.Join(dir, img))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("tarball"), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	etcdDigest := "sha256:" + strings.Repeat("a", 64)
+	v := semver.MustParse("1.24.0")
+
+	tests := []struct {
+		name   string
+		mirror string
+		opts   ImageOptions
+		want   map[string]PlannedImage
+	}{
+		{"upstream", "", ImageOptions{}, map[string]PlannedImage{
+			"pause":          {Image: "k8s.gcr.io/pause:3.7", Role: RoleControlPlane, Status: PlanCached},
+			"etcd":           {Image: "k8s.gcr.io/etcd:3.5.3-0", Role: RoleControlPlane, Status: PlanCached},
+			"kube-apiserver": {Image: "k8s.gcr.io/kube-apiserver:v1.24.0", Role: RoleControlPlane, Status: PlanWillPull},
+		}},
+		{"mirror", "mirror.example.com/k8s", ImageOptions{}, map[string]PlannedImage{
+			"pause":          {Image: "mirror.example.com/k8s/pause:3.7", Upstream: "k8s.gcr.io/pause:3.7", Role: RoleControlPlane, Status: PlanCached},
+			"etcd":           {Image: "mirror.example.com/k8s/etcd:3.5.3-0", Upstream: "k8s.gcr.io/etcd:3.5.3-0", Role: RoleControlPlane, Status: PlanMirrorRewritten},
+			"kube-apiserver": {Image: "mirror.example.com/k8s/kube-apiserver:v1.24.0", Upstream: "k8s.gcr.io/kube-apiserver:v1.24.0", Role: RoleControlPlane, Status: PlanMirrorRewritten},
+		}},
+		{"digest", "", ImageOptions{Digests: map[string]string{"k8s.gcr.io/etcd": etcdDigest}}, map[string]PlannedImage{
+			"pause": {Image: "k8s.gcr.io/pause:3.7", Role: RoleControlPlane, Status: PlanCached},
+			"etcd":  {Image: "k8s.gcr.io/etcd@" + etcdDigest, Role: RoleControlPlane, Status: PlanWillPull},
+		}},
+		{"component repo", "", ImageOptions{ComponentRepos: map[string]string{"etcd": "mirror.example.com/k8s"}}, map[string]PlannedImage{
+			"pause": {Image: "k8s.gcr.io/pause:3.7", Role: RoleControlPlane, Status: PlanCached},
+			"etcd":  {Image: "mirror.example.com/k8s/etcd:3.5.3-0", Upstream: "k8s.gcr.io/etcd:3.5.3-0", Role: RoleControlPlane, Status: PlanMirrorRewritten},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := PlanImages(tc.mirror, v, tc.opts, dir)
+			if err != nil {
+				t.Fatalf("PlanImages: %v", err)
+			}
+			got := map[string]PlannedImage{}
+			for _, img := range plan.Images {
+				if _, ok := tc.want[component(img.Image)]; ok {
+					got[component(img.Image)] = img
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("planned images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImageRewrites(t *testing.T) {
+	opts := ImageOptions{ComponentRepos: map[string]string{"etcd": "mirror.example.com/k8s"}}
+	rewrites, err := ImageRewrites("", semver.MustParse("1.24.0"), opts)
+	if err != nil {
+		t.Fatalf("ImageRewrites: %v", err)
+	}
+	got := map[string]ImageRewrite{}
+	for _, r := range rewrites {
+		got[component(r.Final)] = r
+	}
+
+	want := map[string]struct {
+		rewrite   ImageRewrite
+		rewritten bool
+	}{
+		"etcd":           {ImageRewrite{Original: "k8s.gcr.io/etcd:3.5.3-0", Final: "mirror.example.com/k8s/etcd:3.5.3-0"}, true},
+		"pause":          {ImageRewrite{Original: "k8s.gcr.io/pause:3.7", Final: "k8s.gcr.io/pause:3.7"}, false},
+		"kube-apiserver": {ImageRewrite{Original: "k8s.gcr.io/kube-apiserver:v1.24.0", Final: "k8s.gcr.io/kube-apiserver:v1.24.0"}, false},
+	}
+	for c, w := range want {
+		if diff := cmp.Diff(w.rewrite, got[c]); diff != "" {
+			t.Errorf("%s rewrite mismatch (-want +got):\n%s", c, diff)
+		}
+		if got[c].Rewritten() != w.rewritten {
+			t.Errorf("%s rewritten = %t, want %t", c, got[c].Rewritten(), w.rewritten)
+		}
+	}
+}
+
+func TestPreviewImages(t *testing.T) {
+	useTagCache(t, "")
+	transport := &countingTransport{}
+	useTagLookupTransport(t, transport)
+
+	for _, version := range []string{"1.18.0", "1.21.2", "1.24.0"} {
+		for _, mirror := range []string{"", "registry.corp", "registry.cn-hangzhou.aliyuncs.com/google_containers"} {
+			for _, cni := range []string{"", "calico", "cilium", "flannel", "kindnet"} {
+				t.Run(fmt.Sprintf("%s/%q/%q", version, mirror, cni), func(t *testing.T) {
+					v := semver.MustParse(version)
+					got, err := PreviewImages(mirror, v, cni)
+					if err != nil {
+						t.Fatalf("PreviewImages: %v", err)
+					}
+					want, err := ImagesForVersion(mirror, v, ImageOptions{CNI: cni})
+					if err != nil {
+						t.Fatalf("ImagesForVersion: %v", err)
+					}
+					if diff := cmp.Diff(want, got); diff != "" {
+						t.Errorf("PreviewImages(%q, %s, %q) mismatch (-ImagesForVersion +got):\n%s", mirror, v, cni, diff)
+					}
+				})
+			}
+		}
+	}
+	if _, err := PreviewImages("registry corp", semver.MustParse("1.24.0"), ""); err == nil {
+		t.Error("PreviewImages of an invalid mirror succeeded, want an error")
+	}
+	if n := atomic.LoadInt32(&transport.requests); n != 0 {
+		t.Errorf("made %d requests for pinned versions, want none", n)
+	}
+}
+
+func TestPreviewImagesUnpinnedVersion(t *testing.T) {
+	useTagCache(t, "")
+	transport := &countingTransport{}
+	useTagLookupTransport(t, transport)
+	SetAllowUnknownVersions(true)
+	defer SetAllowUnknownVersions(false)
+
+	v := semver.MustParse("1.99.0")
+	got, err := PreviewImages("registry.corp", v, "")
+	if err != nil {
+		t.Fatalf("PreviewImages: %v", err)
+	}
+	if n := atomic.LoadInt32(&transport.requests); n != 0 {
+		t.Errorf("PreviewImages made %d requests, want none", n)
+	}
+
+	// offline, starting a cluster uses the same last known good tags
+	SetOffline(true)
+	defer SetOffline(false)
+	want, err := ImagesForVersion("registry.corp", v, ImageOptions{})
+	if err != nil {
+		t.Fatalf("ImagesForVersion: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("PreviewImages(%s) mismatch (-offline ImagesForVersion +got):\n%s", v, diff)
+	}
+}
+
+// compileImagePlanSchema returns the compiled ImagePlanSchema
+func compileImagePlanSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("plan_schema.json", bytes.NewReader(ImagePlanSchema())); err != nil {
+		t.Fatalf("AddResource: %v", err)
+	}
+	sch, err := c.Compile("plan_schema.json")
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return sch
+}
+
+func TestImagePlanSchema(t *testing.T) {
+	useTagCache(t, "")
+	sch := compileImagePlanSchema(t)
+
+	plan, err := PlanImages("mirror.example.com/k8s", semver.MustParse("1.24.0"), ImageOptions{CNI: "kindnet"}, t.TempDir())
+	if err != nil {
+		t.Fatalf("PlanImages: %v", err)
+	}
+	planned, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		doc   string
+		valid bool
+	}{
+		{"sample", `{"images": [
+			{"image": "k8s.gcr.io/pause:3.7", "role": "control-plane", "status": "cached"},
+			{"image": "mirror.example.com/k8s/etcd:3.5.3-0", "upstream": "k8s.gcr.io/etcd:3.5.3-0", "role": "control-plane", "status": "mirror-rewritten"},
+			{"image": "docker.io/calico/node:v3.20.0", "role": "cni", "status": "will-pull"},
+			{"image": "registry.corp/app:v1", "role": "listed", "status": "will-pull"}
+		]}`, true},
+		{"PlanImages", string(planned), true},
+		{"no images", `{"images": []}`, true},
+		{"missing images", `{}`, false},
+		{"unknown status", `{"images": [{"image": "k8s.gcr.io/pause:3.7", "role": "control-plane", "status": "pulled"}]}`, false},
+		{"unknown role", `{"images": [{"image": "k8s.gcr.io/pause:3.7", "role": "addon", "status": "cached"}]}`, false},
+		{"missing role", `{"images": [{"image": "k8s.gcr.io/pause:3.7", "status": "cached"}]}`, false},
+		{"empty image", `{"images": [{"image": "", "role": "control-plane", "status": "cached"}]}`, false},
+		{"unknown field", `{"images": [{"image": "k8s.gcr.io/pause:3.7", "role": "control-plane", "status": "cached", "size": 1}]}`, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(tc.doc), &doc); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			err := sch.Validate(doc)
+			if tc.valid && err != nil {
+				t.Errorf("schema rejects a valid plan: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("schema accepts an invalid plan")
+			}
+		})
+	}
+}
+
+func TestImagePlanSchemaEnums(t *testing.T) {
+	var schema struct {
+		Defs struct {
+			PlannedImage struct {
+				Properties struct {
+					Role   struct{ Enum []Role }
+					Status struct{ Enum []PlanStatus }
+				}
+			} `json:"plannedImage"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(ImagePlanSchema(), &schema); err != nil {
+		t.Fatalf("unmarshal schema: %v", err)
+	}
+	props := schema.Defs.PlannedImage.Properties
+	if diff := cmp.Diff([]Role{RoleControlPlane, RoleAuxiliary, RoleCNI, RoleListed}, props.Role.Enum); diff != "" {
+		t.Errorf("schema roles mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]PlanStatus{PlanCached, PlanWillPull, PlanMirrorRewritten}, props.Status.Enum); diff != "" {
+		t.Errorf("schema statuses mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/util"
+)
+
+// Configure applies the image overrides and registry settings stored in cc, as start does before pulling any image
+func Configure(cc config.ClusterConfig) error {
+	if err := SetPauseVersion(cc.KubernetesConfig.PauseImageVersion); err != nil {
+		return errors.Wrap(err, "pause image version")
+	}
+	if err := SetPauseRepository(cc.KubernetesConfig.PauseImageRepository); err != nil {
+		return errors.Wrap(err, "pause image repository")
+	}
+	if err := SetEtcdVersion(cc.KubernetesConfig.EtcdVersion); err != nil {
+		return errors.Wrap(err, "etcd image version")
+	}
+	if err := SetCoreDNSVersion(cc.KubernetesConfig.CoreDNSVersion); err != nil {
+		return errors.Wrap(err, "coredns image version")
+	}
+	SetFlatCoreDNSPath(cc.KubernetesConfig.CoreDNSFlatPath)
+	SetCoreDNSLatestPatch(cc.KubernetesConfig.CoreDNSLatestPatch)
+	if err := SetRegistryTLS(cc.RegistryClientCert, cc.RegistryClientKey, cc.RegistryCACert); err != nil {
+		return errors.Wrap(err, "image repository TLS configuration")
+	}
+	SetDockerConfig(cc.RegistryDockerConfig)
+	if err := SetImageDigests(cc.ImageDigests); err != nil {
+		return errors.Wrap(err, "image digests")
+	}
+	if err := SetStorageProvisionerImage(cc.StorageProvisionerImage); err != nil {
+		return errors.Wrap(err, "storage provisioner image")
+	}
+	if err := SetTagLookupTimeout(cc.RegistryTimeout); err != nil {
+		return errors.Wrap(err, "image repository timeout")
+	}
+	if err := SetRegistryRateLimit(cc.RegistryRateLimit); err != nil {
+		return errors.Wrap(err, "image repository rate limit")
+	}
+	if err := SetCompression(cc.ImageCompression); err != nil {
+		return errors.Wrap(err, "image compression")
+	}
+	if err := SetImageList(cc.ImagesFromFile); err != nil {
+		return errors.Wrap(err, "image list")
+	}
+	SetStrictImages(cc.StrictImages)
+	if err := SetRegistryPolicy(cc.AllowedRegistries, cc.DeniedRegistries); err != nil {
+		return errors.Wrap(err, "registry policy")
+	}
+	SetProfilePinnedOnly(cc.PinnedImageVersions)
+	SetTagCacheTTL(cc.ImageTagCacheTTL)
+	SetIncludePreReleaseTags(cc.PreReleaseImageTags)
+	SetInsecureRegistries(cc.InsecureRegistry)
+	return nil
+}
+
+// KubeadmForCluster returns the images KubeadmWithOptions lists for cc at Kubernetes version, with the image options of cc.
+// Caching, preloading and loading the images of a cluster all take their list from it, so that they agree.
+func KubeadmForCluster(cc config.ClusterConfig, version string) ([]string, error) {
+	return KubeadmWithOptions(cc.KubernetesConfig.ImageRepository, version, ClusterImageOptions(cc))
+}
+
+// ImagesForProfile returns the images needed by the cluster of profile, at the Kubernetes version recorded in its config
+func ImagesForProfile(profile string, miniHome ...string) ([]string, error) {
+	cc, err := config.Load(profile, miniHome...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load profile %s", profile)
+	}
+	return ImagesForCluster(*cc)
+}
+
+// ImagesForCluster returns the images needed by cc, at its Kubernetes version and with the options and image overrides
+// its config records. The overrides are applied with Configure, as start would apply them.
+func ImagesForCluster(cc config.ClusterConfig) ([]string, error) {
+	v, err := ClusterVersion(cc)
+	if err != nil {
+		return nil, err
+	}
+	if err := Configure(cc); err != nil {
+		return nil, errors.Wrapf(err, "profile %s", cc.Name)
+	}
+	return ImagesForVersion(cc.KubernetesConfig.ImageRepository, v, ClusterImageOptions(cc))
+}
+
+// ClusterVersion returns the Kubernetes version recorded in cc
+func ClusterVersion(cc config.ClusterConfig) (semver.Version, error) {
+	version := cc.KubernetesConfig.KubernetesVersion
+	if version == "" || version == constants.NoKubernetesVersion {
+		return semver.Version{}, fmt.Errorf("profile %s runs no Kubernetes version", cc.Name)
+	}
+	v, err := util.ParseKubernetesVersion(version)
+	if err != nil {
+		return semver.Version{}, errors.Wrapf(err, "parse Kubernetes version %q of profile %s", version, cc.Name)
+	}
+	return v, nil
+}
+
+// ClusterImageOptions returns the image options recorded in cc. Node architectures aren't recorded, so Arch is left to default.
+func ClusterImageOptions(cc config.ClusterConfig) ImageOptions {
+	opts := ImageOptions{
+		KubeProxyless:        config.SkipsKubeProxy(cc),
+		HA:                   controlPlanes(cc) > 1,
+		NoStorageProvisioner: config.AddonDisabled(cc, "storage-provisioner"),
+		Gvisor:               cc.Addons["gvisor"],
+		MetricsServer:        cc.Addons["metrics-server"],
+	}
+	// "auto" and custom manifests are resolved by the cni package, only CNIs known to have fixed images are listed
+	if _, err := cniImages(cc.KubernetesConfig.CNI, ""); err == nil {
+		opts.CNI = cc.KubernetesConfig.CNI
+	}
+	return opts
+}
+
+// controlPlanes returns the number of control plane nodes of cc
+func controlPlanes(cc config.ClusterConfig) int {
+	n := 0
+	for _, node := range cc.Nodes {
+		if node.ControlPlane {
+			n++
+		}
+	}
+	return n
+}
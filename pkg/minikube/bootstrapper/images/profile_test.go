@@ -0,0 +1,201 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// saveProfile records cc as the config of profile name, in a minikube home of its own.
+// The image overrides ImagesForProfile applies from it are restored to their defaults after the test.
+func saveProfile(t *testing.T, cc config.ClusterConfig) string {
+	t.Helper()
+	t.Cleanup(func() {
+		if err := Configure(config.ClusterConfig{}); err != nil {
+			t.Errorf("restore image defaults: %v", err)
+		}
+	})
+	miniHome := t.TempDir()
+	if err := config.SaveProfile(cc.Name, &cc, miniHome); err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+	return miniHome
+}
+
+func TestImagesForProfile(t *testing.T) {
+	tests := []struct {
+		name string
+		cc   config.ClusterConfig
+		repo string
+		v    string
+		opts ImageOptions
+	}{
+		{
+			name: "default",
+			cc:   config.ClusterConfig{Name: "p1", KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.24.0"}},
+			v:    "1.24.0",
+		},
+		{
+			name: "older version",
+			cc:   config.ClusterConfig{Name: "p2", KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.20.0"}},
+			v:    "1.20.0",
+		},
+		{
+			name: "mirror and cni",
+			cc: config.ClusterConfig{Name: "p3", KubernetesConfig: config.KubernetesConfig{
+				KubernetesVersion: "v1.23.0",
+				ImageRepository:   "registry.example.com",
+				CNI:               "calico",
+			}},
+			repo: "registry.example.com",
+			v:    "1.23.0",
+			opts: ImageOptions{CNI: "calico"},
+		},
+		{
+			name: "auto cni",
+			cc:   config.ClusterConfig{Name: "p4", KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.24.0", CNI: "auto"}},
+			v:    "1.24.0",
+		},
+		{
+			name: "addons and nodes",
+			cc: config.ClusterConfig{
+				Name:             "p5",
+				KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.24.0"},
+				Addons:           map[string]bool{"storage-provisioner": false, "metrics-server": true, "gvisor": true},
+				Nodes:            []config.Node{{Name: "m01", ControlPlane: true}, {Name: "m02", ControlPlane: true}, {Name: "m03", Worker: true}},
+			},
+			v:    "1.24.0",
+			opts: ImageOptions{NoStorageProvisioner: true, MetricsServer: true, Gvisor: true, HA: true},
+		},
+		{
+			name: "kube-proxy skipped",
+			cc: config.ClusterConfig{Name: "p6", KubernetesConfig: config.KubernetesConfig{
+				KubernetesVersion: "v1.24.0",
+				ExtraOptions:      config.ExtraOptionSlice{{Component: "kubeadm", Key: "skip-phases", Value: "preflight, addon/kube-proxy"}},
+			}},
+			v:    "1.24.0",
+			opts: ImageOptions{KubeProxyless: true},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			miniHome := saveProfile(t, tc.cc)
+			got, err := ImagesForProfile(tc.cc.Name, miniHome)
+			if err != nil {
+				t.Fatalf("ImagesForProfile: %v", err)
+			}
+			want, err := ImagesForVersion(tc.repo, semver.MustParse(tc.v), tc.opts)
+			if err != nil {
+				t.Fatalf("ImagesForVersion: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImagesForProfileOverrides(t *testing.T) {
+	useTagCache(t, "")
+	cc := config.ClusterConfig{
+		Name: "p1",
+		KubernetesConfig: config.KubernetesConfig{
+			KubernetesVersion:    "v1.24.0",
+			EtcdVersion:          "3.5.4-0",
+			PauseImageRepository: "registry.corp/pause-mirror",
+		},
+		StorageProvisionerImage: "registry.corp/storage-provisioner:v9",
+	}
+	got, err := ImagesForProfile(cc.Name, saveProfile(t, cc))
+	if err != nil {
+		t.Fatalf("ImagesForProfile: %v", err)
+	}
+	for _, want := range []string{"k8s.gcr.io/etcd:3.5.4-0", "registry.corp/pause-mirror/pause:3.7", "registry.corp/storage-provisioner:v9"} {
+		found := false
+		for _, img := range got {
+			found = found || img == want
+		}
+		if !found {
+			t.Errorf("ImagesForProfile = %v, want the override %s", got, want)
+		}
+	}
+
+	listed := config.ClusterConfig{
+		Name:             "p2",
+		KubernetesConfig: config.KubernetesConfig{KubernetesVersion: "v1.24.0"},
+		ImagesFromFile:   []string{"registry.corp/pause:3.7", "registry.corp/etcd:3.5.3-0"},
+	}
+	got, err = ImagesForProfile(listed.Name, saveProfile(t, listed))
+	if err != nil {
+		t.Fatalf("ImagesForProfile: %v", err)
+	}
+	if diff := cmp.Diff([]string{"registry.corp/etcd:3.5.3-0", "registry.corp/pause:3.7"}, got); diff != "" {
+		t.Errorf("images of a listed profile mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfigureDockerConfig(t *testing.T) {
+	useTagCache(t, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	if err := Configure(config.ClusterConfig{RegistryDockerConfig: writeDockerConfig(t, server, "user", "secret")}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := Configure(config.ClusterConfig{}); err != nil {
+			t.Errorf("Configure: %v", err)
+		}
+	})
+	got, err := findLatestTagFromRepositoryE(context.Background(), server.URL+"/v2/coredns/tags/list", "v1.8.6")
+	if err != nil {
+		t.Fatalf("lookup with the credentials of the profile: %v", err)
+	}
+	if got != "v1.8.9" {
+		t.Errorf("got %s, want v1.8.9", got)
+	}
+}
+
+func TestImagesForProfileInvalid(t *testing.T) {
+	if _, err := ImagesForProfile("missing", t.TempDir()); err == nil {
+		t.Errorf("ImagesForProfile of a missing profile returned no error")
+	}
+	for _, version := range []string{"", constants.NoKubernetesVersion, "latest"} {
+		cc := config.ClusterConfig{Name: "p1", KubernetesConfig: config.KubernetesConfig{KubernetesVersion: version}}
+		if _, err := ImagesForProfile(cc.Name, saveProfile(t, cc)); err == nil {
+			t.Errorf("ImagesForProfile of version %q returned no error", version)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultRegistryRateLimit is how many requests per second are sent to a registry host, unless SetRegistryRateLimit changes it
+	defaultRegistryRateLimit = 10
+	// maxRetryAfter bounds how long a 429 response's Retry-After header holds the requests to its host back
+	maxRetryAfter = time.Minute
+	// rateLimitRetries is how many times a request answered by 429 is sent again after waiting for its Retry-After
+	rateLimitRetries = 2
+)
+
+// registryRateLimit is how many requests per second are sent to each registry host
+var registryRateLimit float64 = defaultRegistryRateLimit
+
+// SetRegistryRateLimit sets how many requests per second the tag lookups and image checks send to each registry host,
+// so that bulk checks don't trip the rate limits of registries such as Docker Hub. Zero restores the default.
+func SetRegistryRateLimit(perSecond float64) error {
+	if perSecond < 0 || math.IsNaN(perSecond) || math.IsInf(perSecond, 0) {
+		return fmt.Errorf("registry rate limit %v is not a positive number of requests per second", perSecond)
+	}
+	if perSecond == 0 {
+		perSecond = defaultRegistryRateLimit
+	}
+	registryRateLimit = perSecond
+	hostLimiters.Range(func(host, _ interface{}) bool {
+		hostLimiters.Delete(host)
+		return true
+	})
+	return nil
+}
+
+// hostLimiter paces the requests sent to a registry host
+type hostLimiter struct {
+	limiter *rate.Limiter
+
+	mu sync.Mutex
+	// retryAt is when the host said, with Retry-After, that it accepts requests again
+	retryAt time.Time
+}
+
+// hostLimiters holds the hostLimiter of every registry host requests were sent to, shared by all the transports of the package
+var hostLimiters sync.Map
+
+// limiterFor returns the hostLimiter of host
+func limiterFor(host string) *hostLimiter {
+	if l, ok := hostLimiters.Load(host); ok {
+		return l.(*hostLimiter)
+	}
+	burst := int(math.Ceil(registryRateLimit))
+	l, _ := hostLimiters.LoadOrStore(host, &hostLimiter{limiter: rate.NewLimiter(rate.Limit(registryRateLimit), burst)})
+	return l.(*hostLimiter)
+}
+
+// wait blocks until a request may be sent to the host, or req is cancelled
+func (l *hostLimiter) wait(req *http.Request) error {
+	l.mu.Lock()
+	delay := time.Until(l.retryAt)
+	l.mu.Unlock()
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return req.Context().Err()
+		}
+	}
+	return l.limiter.Wait(req.Context())
+}
+
+// holdUntil holds the requests to the host back until t
+func (l *hostLimiter) holdUntil(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t.After(l.retryAt) {
+		l.retryAt = t
+	}
+}
+
+// retryAfter returns how long the Retry-After header of resp asks to wait, as seconds or an HTTP date, bounded by maxRetryAfter
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	h := resp.Header.Get("Retry-After")
+	if h == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(h); err == nil {
+		d = time.Until(t)
+	} else {
+		return 0, false
+	}
+	if d < 0 {
+		d = 0
+	}
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d, true
+}
+
+// withRateLimit returns t, pacing its requests with the hostLimiter of their host
+func withRateLimit(t http.RoundTripper) http.RoundTripper {
+	if _, ok := t.(*rateLimitTransport); ok {
+		return t
+	}
+	return &rateLimitTransport{next: t}
+}
+
+// rateLimitTransport paces requests per registry host, and sends the requests answered by 429 again once their Retry-After has passed
+type rateLimitTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip sends req once its host's limiter allows it
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := limiterFor(req.URL.Host)
+	for attempt := 0; ; attempt++ {
+		if err := l.wait(req); err != nil {
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		d, ok := retryAfter(resp)
+		if !ok {
+			return resp, nil
+		}
+		l.holdUntil(time.Now().Add(d))
+		// only requests without a body can be sent again as they are
+		if attempt >= rateLimitRetries || (req.Body != nil && req.Body != http.NoBody) {
+			return resp, nil
+		}
+		klog.V(3).Infof("%s is rate limited, retrying after %s", req.URL.Host, d)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useRateLimit sets the registry rate limit to perSecond for the duration of the test
+func useRateLimit(t *testing.T, perSecond float64) {
+	t.Helper()
+	if err := SetRegistryRateLimit(perSecond); err != nil {
+		t.Fatalf("SetRegistryRateLimit(%v): %v", perSecond, err)
+	}
+	t.Cleanup(func() {
+		if err := SetRegistryRateLimit(0); err != nil {
+			t.Errorf("SetRegistryRateLimit(0): %v", err)
+		}
+	})
+}
+
+func TestRateLimitSpacesRequests(t *testing.T) {
+	useRateLimit(t, 10)
+
+	var mu sync.Mutex
+	arrivals := []time.Time{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		arrivals = append(arrivals, time.Now())
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: withRateLimit(http.DefaultTransport)}
+	for i := 0; i < 15; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	// the first second's worth of requests is sent at once, the others 100ms apart
+	for i := 11; i < len(arrivals); i++ {
+		if gap := arrivals[i].Sub(arrivals[i-1]); gap < 90*time.Millisecond {
+			t.Errorf("request %d was sent %s after the previous one, want at least 100ms at 10 requests per second", i, gap)
+		}
+	}
+	if total := arrivals[len(arrivals)-1].Sub(arrivals[0]); total < 450*time.Millisecond {
+		t.Errorf("15 requests were sent within %s, want at least 500ms at 10 requests per second", total)
+	}
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	useRateLimit(t, 100)
+
+	var requests int32
+	var first, second time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		second = time.Now()
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: withRateLimit(http.DefaultTransport)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %s, want the retried request to succeed", resp.Status)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("made %d requests, want the rate limited one sent again", n)
+	}
+	if gap := second.Sub(first); gap < 950*time.Millisecond {
+		t.Errorf("retried %s after a Retry-After of 1s", gap)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"0", 0, true},
+		{"3600", maxRetryAfter, true},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tc := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tc.header != "" {
+			resp.Header.Set("Retry-After", tc.header)
+		}
+		got, ok := retryAfter(resp)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("retryAfter(%q) = %s, %t, want %s, %t", tc.header, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestSetRegistryRateLimitInvalid(t *testing.T) {
+	if err := SetRegistryRateLimit(-1); err == nil {
+		t.Error("SetRegistryRateLimit(-1) succeeded, want an error")
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+)
+
+// ValidateReferences returns an error naming the first of imgs which isn't a valid image reference, such as one with
+// an empty path component or an upper case repository, so that a bad mirror or rewrite fails before anything is pulled.
+// An empty tag, as the storage-provisioner has in builds without its version, is left to ValidatePinned.
+func ValidateReferences(imgs []string) error {
+	for _, ref := range imgs {
+		if _, err := reference.ParseNormalizedNamed(strings.TrimSuffix(ref, ":")); err != nil {
+			return fmt.Errorf("invalid image reference %q: %v", ref, err)
+		}
+	}
+	return nil
+}
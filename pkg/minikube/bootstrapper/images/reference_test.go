@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/distribution/reference"
+)
+
+func TestValidateReferences(t *testing.T) {
+	tests := []struct {
+		ref   string
+		valid bool
+	}{
+		{"k8s.gcr.io/pause:3.7", true},
+		{"pause:3.7", true},
+		{"registry.corp:5000/k8s/nested/pause:3.7", true},
+		{"k8s.gcr.io/etcd:3.5.3-0@sha256:13f53ed1d91e2e11aac476ee9a0269fdda6cc4874eba903efd40daf50c55eee5", true},
+		// left to ValidatePinned
+		{"gcr.io/k8s-minikube/storage-provisioner:", true},
+		{"mirror.corp//pause:3.7", false},
+		{"mirror.corp/pause:3.7/", false},
+		{"mirror.corp/MyOrg/pause:3.7", false},
+		{"mirror.corp/pause:-3.7", false},
+		{"mirror.corp/pause:3.7 ", false},
+		{"mirror.corp:port/pause:3.7", false},
+		{"k8s.gcr.io/etcd@sha256:13f53ed1", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			err := ValidateReferences([]string{"k8s.gcr.io/kube-proxy:v1.24.0", tc.ref})
+			if tc.valid && err != nil {
+				t.Errorf("ValidateReferences(%q) error: %v", tc.ref, err)
+			}
+			if !tc.valid && (err == nil || !strings.Contains(err.Error(), tc.ref)) {
+				t.Errorf("ValidateReferences(%q) = %v, want an error naming the reference", tc.ref, err)
+			}
+		})
+	}
+}
+
+func TestKubeadmInvalidReferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		mirror string
+		opts   ImageOptions
+		valid  bool
+	}{
+		{"PortedMirror", "https://mirror.corp:5000/k8s/", ImageOptions{}, true},
+		{"NestedMirror", "mirror.corp/a/b/c", ImageOptions{}, true},
+		{"UpperCaseMirror", "mirror.corp/MyOrg", ImageOptions{}, false},
+		{"UpperCaseComponentRepo", "", ImageOptions{ComponentRepos: map[string]string{"etcd": "mirror.corp/Etcd"}}, false},
+		{"DoubleSlashRewrite", "", ImageOptions{Rewrite: func(ref string) string { return strings.Replace(ref, "/", "//", 1) }}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.NoStorageProvisioner = true
+			_, err := KubeadmWithOptions(tc.mirror, "v1.24.0", tc.opts)
+			if tc.valid && err != nil {
+				t.Errorf("KubeadmWithOptions(%q) error: %v", tc.mirror, err)
+			}
+			if !tc.valid && (err == nil || !strings.Contains(err.Error(), "invalid image reference")) {
+				t.Errorf("KubeadmWithOptions(%q) = %v, want an invalid image reference error", tc.mirror, err)
+			}
+		})
+	}
+}
+
+func FuzzBuildReference(f *testing.F) {
+	mirrors := []string{"", "k8s.gcr.io", "https://mirror.corp:5000/k8s/", "mirror.corp/a/b/c", "mirror.corp/MyOrg", "mirror.corp//x", "localhost:5000", "mirrör.corp"}
+	versions := []string{"v1.24.0", "1.25.0", "1.21.0-alpha.1", "1.22.3+build.1", "stable", "latest", "v1.99.0", "1.2"}
+	for _, mirror := range mirrors {
+		for _, version := range versions {
+			f.Add(mirror, version)
+		}
+	}
+	f.Fuzz(func(t *testing.T, mirror string, version string) {
+		imgs, err := KubeadmWithOptions(mirror, version, ImageOptions{Offline: true, NoStorageProvisioner: true})
+		if err != nil {
+			return
+		}
+		for _, img := range imgs {
+			named, err := reference.ParseNormalizedNamed(img)
+			if err != nil {
+				t.Fatalf("KubeadmWithOptions(%q, %q) returned the invalid reference %q: %v", mirror, version, img, err)
+			}
+			if _, ok := named.(reference.Tagged); !ok {
+				if _, ok := named.(reference.Digested); !ok {
+					t.Fatalf("KubeadmWithOptions(%q, %q) returned %q, want it tagged or pinned to a digest", mirror, version, img)
+				}
+			}
+		}
+	})
+}
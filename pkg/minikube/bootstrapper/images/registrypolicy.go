@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// dockerHubAliases are the hosts Docker Hub is also referred to by, which the registry policy treats as docker.io
+var dockerHubAliases = map[string]bool{
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+// allowedRegistries, if set, are the only registries required images may be hosted on
+var allowedRegistries []string
+
+// deniedRegistries are registries required images may never be hosted on, whatever allowedRegistries
+var deniedRegistries []string
+
+// SetRegistryPolicy restricts the registries, given as host[:port], that the required images may be hosted on.
+// An image on a denied registry is an error even if the registry is also allowed, and when allow is set, so is an
+// image on any registry it doesn't list. Images without a registry are hosted on docker.io.
+func SetRegistryPolicy(allow []string, deny []string) error {
+	a, err := normalizeRegistries(allow)
+	if err != nil {
+		return err
+	}
+	d, err := normalizeRegistries(deny)
+	if err != nil {
+		return err
+	}
+	allowedRegistries, deniedRegistries = a, d
+	return nil
+}
+
+// normalizeRegistries returns registries in the form the hosts of image references are compared against
+func normalizeRegistries(registries []string) ([]string, error) {
+	normalized := []string{}
+	for _, r := range registries {
+		host := strings.ToLower(strings.TrimSpace(r))
+		if host == "" || strings.ContainsAny(host, "/@") {
+			return nil, fmt.Errorf("invalid registry %q: expected host[:port]", r)
+		}
+		if dockerHubAliases[host] {
+			host = dockerHubRegistry
+		}
+		normalized = append(normalized, host)
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// registryOf returns the registry hosting ref, as the registry policy compares it
+func registryOf(ref string) string {
+	host := strings.ToLower(parseImage(ref, "").Registry)
+	if host == "" || dockerHubAliases[host] {
+		return dockerHubRegistry
+	}
+	return host
+}
+
+// matchesRegistry reports whether host, which may carry a port, is one of registries.
+// Registries given without a port match every port of their host.
+func matchesRegistry(host string, registries []string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, r := range registries {
+		if r == host || r == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRegistries returns an error naming every image hosted on a registry the registry policy denies or doesn't allow
+func ValidateRegistries(imgs []string) error {
+	violations := []string{}
+	for _, ref := range imgs {
+		host := registryOf(ref)
+		switch {
+		case matchesRegistry(host, deniedRegistries):
+			violations = append(violations, fmt.Sprintf("%s (%s is denied)", ref, host))
+		case allowedRegistries != nil && !matchesRegistry(host, allowedRegistries):
+			violations = append(violations, fmt.Sprintf("%s (%s isn't allowed)", ref, host))
+		}
+	}
+	if len(violations) > 0 {
+		return withKind(ErrRegistryNotAllowed, fmt.Errorf("images are hosted on registries the registry policy rejects, configure an image mirror for them: %s", strings.Join(violations, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+// useRegistryPolicy sets the registry policy for the duration of the test
+func useRegistryPolicy(t *testing.T, allow []string, deny []string) {
+	t.Helper()
+	if err := SetRegistryPolicy(allow, deny); err != nil {
+		t.Fatalf("SetRegistryPolicy(%v, %v): %v", allow, deny, err)
+	}
+	t.Cleanup(func() {
+		if err := SetRegistryPolicy(nil, nil); err != nil {
+			t.Errorf("reset registry policy: %v", err)
+		}
+	})
+}
+
+func TestValidateRegistries(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		images  []string
+		wantErr bool
+	}{
+		{"no policy", nil, nil, []string{"kindest/kindnetd:v20210326-1e038dc5", "k8s.gcr.io/pause:3.7"}, false},
+		{"denied docker hub", nil, []string{"docker.io"}, []string{"k8s.gcr.io/pause:3.7", "kindest/kindnetd:v20210326-1e038dc5"}, true},
+		{"denied docker hub alias", nil, []string{"index.docker.io"}, []string{"docker.io/kindest/kindnetd:v20210326-1e038dc5"}, true},
+		{"denied host case", nil, []string{"Quay.io"}, []string{"quay.io/coreos/flannel:v0.12.0-amd64"}, true},
+		{"other registry", nil, []string{"docker.io"}, []string{"k8s.gcr.io/pause:3.7", "quay.io/coreos/flannel:v0.12.0-amd64"}, false},
+		{"allowed", []string{"k8s.gcr.io", "gcr.io"}, nil, []string{"k8s.gcr.io/pause:3.7", "gcr.io/k8s-minikube/storage-provisioner:v5"}, false},
+		{"not allowed", []string{"k8s.gcr.io"}, nil, []string{"k8s.gcr.io/pause:3.7", "gcr.io/k8s-minikube/storage-provisioner:v5"}, true},
+		{"allowed any port", []string{"registry.corp"}, nil, []string{"registry.corp:5000/pause:3.7"}, false},
+		{"allowed port only", []string{"registry.corp:5000"}, nil, []string{"registry.corp:5001/pause:3.7"}, true},
+		{"denied over allowed", []string{"registry.corp"}, []string{"registry.corp"}, []string{"registry.corp/pause:3.7"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useRegistryPolicy(t, tc.allow, tc.deny)
+			err := ValidateRegistries(tc.images)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateRegistries(%v) = %v, want error: %t", tc.images, err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrRegistryNotAllowed) {
+				t.Errorf("ValidateRegistries(%v) = %v, want ErrRegistryNotAllowed", tc.images, err)
+			}
+		})
+	}
+}
+
+func TestSetRegistryPolicyInvalid(t *testing.T) {
+	for _, r := range []string{"", "registry.corp/path", "registry.corp@sha256:abc"} {
+		if err := SetRegistryPolicy([]string{r}, nil); err == nil {
+			t.Errorf("SetRegistryPolicy(%q) succeeded, want an error", r)
+		}
+	}
+	if allowedRegistries != nil || deniedRegistries != nil {
+		t.Errorf("invalid registry policy was kept: allow %v, deny %v", allowedRegistries, deniedRegistries)
+	}
+}
+
+func TestRegistryPolicyImages(t *testing.T) {
+	useTagCache(t, "")
+	v := semver.MustParse("1.24.0")
+
+	useRegistryPolicy(t, nil, []string{"docker.io"})
+	if _, err := ImagesForVersion("", v, ImageOptions{CNI: "kindnet"}); !errors.Is(err, ErrRegistryNotAllowed) {
+		t.Errorf("ImagesForVersion with kindnet from docker.io = %v, want ErrRegistryNotAllowed", err)
+	}
+	if _, err := ImagesForVersion("", v, ImageOptions{}); err != nil {
+		t.Errorf("ImagesForVersion without docker.io images: %v", err)
+	}
+
+	useRegistryPolicy(t, []string{"registry.example.com"}, nil)
+	if _, err := ImagesForVersion("", v, ImageOptions{CNI: "kindnet"}); !errors.Is(err, ErrRegistryNotAllowed) {
+		t.Errorf("ImagesForVersion from the default registries = %v, want ErrRegistryNotAllowed", err)
+	}
+	if _, err := KubeadmWithOptions("", "v1.24.0", ImageOptions{}); !errors.Is(err, ErrRegistryNotAllowed) {
+		t.Errorf("KubeadmWithOptions from the default registries = %v, want ErrRegistryNotAllowed", err)
+	}
+	if _, err := KubeadmWithOptions("registry.example.com", "v1.24.0", ImageOptions{}); err != nil {
+		t.Errorf("KubeadmWithOptions from an allowed mirror: %v", err)
+	}
+	imgs, err := ImagesForVersion("registry.example.com", v, ImageOptions{CNI: "kindnet"})
+	if err != nil {
+		t.Fatalf("ImagesForVersion from an allowed mirror: %v", err)
+	}
+	for _, img := range imgs {
+		if registryOf(img) != "registry.example.com" {
+			t.Errorf("image %s isn't hosted on the allowed mirror", img)
+		}
+	}
+}
+
+func TestRegistryPolicyImageList(t *testing.T) {
+	if err := SetImageList([]string{"docker.io/library/busybox:1.35", "k8s.gcr.io/pause:3.7"}); err != nil {
+		t.Fatalf("SetImageList: %v", err)
+	}
+	defer func() {
+		if err := SetImageList(nil); err != nil {
+			t.Errorf("reset image list: %v", err)
+		}
+	}()
+	useRegistryPolicy(t, nil, []string{"docker.io"})
+	if _, err := KubeadmWithOptions("", "v1.24.0", ImageOptions{}); !errors.Is(err, ErrRegistryNotAllowed) {
+		t.Errorf("KubeadmWithOptions of a listed docker.io image = %v, want ErrRegistryNotAllowed", err)
+	}
+	if _, err := StructuredImagesForVersion("", semver.MustParse("1.24.0"), ImageOptions{}); !errors.Is(err, ErrRegistryNotAllowed) {
+		t.Errorf("StructuredImagesForVersion of a listed docker.io image = %v, want ErrRegistryNotAllowed", err)
+	}
+}
+
+func TestRegistryPolicyMirrorPlans(t *testing.T) {
+	useTagCache(t, "")
+	SetOffline(true)
+	defer SetOffline(false)
+	useRegistryPolicy(t, []string{"registry.corp"}, nil)
+	v := semver.MustParse("1.24.0")
+	opts := ImageOptions{CNI: "kindnet"}
+
+	rewrites, err := ImageRewrites("registry.corp", v, opts)
+	if err != nil {
+		t.Fatalf("ImageRewrites from an allowed mirror: %v", err)
+	}
+	for _, r := range rewrites {
+		if !r.Rewritten() {
+			t.Errorf("image %s isn't rewritten to the mirror", r.Final)
+		}
+	}
+	if _, err := PlanImages("registry.corp", v, opts, t.TempDir()); err != nil {
+		t.Errorf("PlanImages from an allowed mirror: %v", err)
+	}
+	if _, err := AuditImages(context.Background(), "registry.corp", v, opts); err != nil {
+		t.Errorf("AuditImages from an allowed mirror: %v", err)
+	}
+	if _, err := PlanImages("", v, opts, t.TempDir()); !errors.Is(err, ErrRegistryNotAllowed) {
+		t.Errorf("PlanImages from the default registries = %v, want ErrRegistryNotAllowed", err)
+	}
+}
@@ -16,23 +16,218 @@ limitations under the License.
 
 package images
 
-import "path"
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+)
 
 // DefaultKubernetesRepo is the default Kubernetes repository
 const DefaultKubernetesRepo = "k8s.gcr.io"
 
-// kubernetesRepo returns the official Kubernetes repository, or an alternate
-func kubernetesRepo(mirror string) string {
+// RegistryK8sIORepo is the default Kubernetes repository from RegistryK8sIOMinVersion onwards
+const RegistryK8sIORepo = "registry.k8s.io"
+
+// RegistryK8sIOMinVersion is the first Kubernetes version whose images are pulled from RegistryK8sIORepo by default,
+// matching the default imageRepository of kubeadm since v1.25
+const RegistryK8sIOMinVersion = "1.25.0-alpha.1"
+
+// registryK8sIOMinVersion is RegistryK8sIOMinVersion, parsed
+var registryK8sIOMinVersion = semver.MustParse(RegistryK8sIOMinVersion)
+
+// DefaultKubernetesRepoForVersion returns the default Kubernetes repository for the Kubernetes version v
+func DefaultKubernetesRepoForVersion(v semver.Version) string {
+	if v.GTE(registryK8sIOMinVersion) {
+		return RegistryK8sIORepo
+	}
+	return DefaultKubernetesRepo
+}
+
+// RegistryK8sIOPauseTag is the first pause tag pulled from RegistryK8sIORepo by default, the one kubeadm moved the pause image with.
+// The container runtimes pull the pause image as their sandbox image, so it moves with its own tag rather than RegistryK8sIOMinVersion.
+const RegistryK8sIOPauseTag = "3.8"
+
+// registryK8sIOPauseTag is RegistryK8sIOPauseTag, parsed as a semantic version
+var registryK8sIOPauseTag = semver.MustParse(RegistryK8sIOPauseTag + ".0")
+
+// DefaultPauseRepoForVersion returns the default repository of the pause image for the Kubernetes version v, derived from
+// the pause tag pinned for v. Versions without a pinned pause tag use the default Kubernetes repository of v.
+func DefaultPauseRepoForVersion(v semver.Version) string {
+	tag, ok := pinnedTag(v, "pause", pauseVersion, "")
+	if !ok {
+		return DefaultKubernetesRepoForVersion(v)
+	}
+	if pv, err := semver.ParseTolerant(tag); err == nil && pv.GTE(registryK8sIOPauseTag) {
+		return RegistryK8sIORepo
+	}
+	return DefaultKubernetesRepo
+}
+
+// pauseRepository replaces the repository of the pause image when set, whatever the Kubernetes version and mirror
+var pauseRepository string
+
+// SetPauseRepository pulls the pause image from repo (e.g. registry.k8s.io) rather than the repository the other
+// Kubernetes images are pulled from, an empty repo restores the default
+func SetPauseRepository(repo string) error {
+	if err := ValidateMirror(repo); err != nil {
+		return errors.Wrap(err, "pause image repository")
+	}
+	pauseRepository = normalizeMirror(repo)
+	return nil
+}
+
+// pauseMirror returns the mirror the pause image is pulled from instead of mirror, the one given to SetPauseRepository if any
+func pauseMirror(mirror string) string {
+	if pauseRepository != "" {
+		return pauseRepository
+	}
+	return mirror
+}
+
+// pauseRepo returns the official repository of the pause image for the Kubernetes version v, or an alternate
+func pauseRepo(mirror string, v semver.Version) string {
+	mirror = normalizeMirror(mirror)
 	if mirror != "" {
 		return mirror
 	}
-	return DefaultKubernetesRepo
+	return DefaultPauseRepoForVersion(v)
+}
+
+// kubernetesRepo returns the official Kubernetes repository for the Kubernetes version v, or an alternate
+func kubernetesRepo(mirror string, v semver.Version) string {
+	mirror = normalizeMirror(mirror)
+	if mirror != "" {
+		return mirror
+	}
+	return DefaultKubernetesRepoForVersion(v)
 }
 
 // minikubeRepo returns the official minikube repository, or an alternate
 func minikubeRepo(mirror string) string {
+	mirror = normalizeMirror(mirror)
 	if mirror == "" {
 		mirror = "gcr.io"
 	}
 	return path.Join(mirror, "k8s-minikube")
 }
+
+// kubeVipRepo returns the official kube-vip repository, or an alternate
+func kubeVipRepo(mirror string) string {
+	mirror = normalizeMirror(mirror)
+	if mirror == "" {
+		mirror = "ghcr.io"
+	}
+	return path.Join(mirror, "kube-vip")
+}
+
+// mirrorExpression matches a normalized mirror in the host[:port][/path] form
+var mirrorExpression = regexp.MustCompile(`^[a-zA-Z0-9]([-a-zA-Z0-9.]*[a-zA-Z0-9])?(:([0-9]+))?(/[a-zA-Z0-9][-a-zA-Z0-9._]*)*$`)
+
+// normalizeMirror strips the scheme and trailing slashes of mirror, so that it can be joined with image names
+func normalizeMirror(mirror string) string {
+	mirror = strings.TrimSpace(mirror)
+	if i := strings.Index(mirror, "://"); i >= 0 {
+		mirror = mirror[i+len("://"):]
+	}
+	return strings.TrimRight(mirror, "/")
+}
+
+// ValidateMirror returns an ErrInvalidMirror if mirror, once normalized, is not of the host[:port][/path] form
+func ValidateMirror(mirror string) error {
+	if mirror == "" {
+		return nil
+	}
+	m := normalizeMirror(mirror)
+	groups := mirrorExpression.FindStringSubmatch(m)
+	if groups == nil {
+		return withKind(ErrInvalidMirror, fmt.Errorf("invalid mirror %q: expected host[:port][/path]", mirror))
+	}
+	if port := groups[3]; port != "" {
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return withKind(ErrInvalidMirror, fmt.Errorf("invalid mirror %q: port %s out of range", mirror, port))
+		}
+	}
+	return nil
+}
+
+// canonicalRepos returns the repositories minikube pulls its images from in mirror for the Kubernetes version v,
+// mapped to the canonical repository of each image
+func canonicalRepos(mirror string, v semver.Version) map[string]string {
+	repos := map[string]string{}
+	for _, name := range []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kube-proxy", "etcd"} {
+		repos[path.Join(kubernetesRepo(mirror, v), name)] = path.Join(kubernetesRepo("", v), name)
+	}
+	repos[path.Join(kubernetesRepo(mirror, v), coreDNSPath(v, mirror))] = path.Join(kubernetesRepo("", v), coreDNSImageName(v))
+	repos[path.Join(pauseRepo(mirror, v), "pause")] = path.Join(pauseRepo("", v), "pause")
+	kicBase := path.Join(minikubeRepo(""), "kicbase")
+	repos[KicBase(kicBase, mirror)] = kicBase
+	for _, image := range []func(string) string{
+		func(m string) string { return path.Join(minikubeRepo(m), "storage-provisioner") },
+		Gvisor, BridgePlugins, MetricsServer, KubeVip, KindNet, flannel, Cilium, CiliumOperator,
+		CalicoDaemonSet, CalicoDeployment, CalicoFelixDriver, CalicoBin,
+	} {
+		repos[repository(image(mirror))] = repository(image(""))
+	}
+	return repos
+}
+
+// repository returns the repository of the image ref, without its tag or digest
+func repository(ref string) string {
+	img := parseImage(ref, "")
+	return path.Join(img.Registry, img.Repo)
+}
+
+// CanonicalName returns the image ref pulled from mirror for the Kubernetes version v as it is named in its canonical
+// repository, e.g. registry.corp/etcd:3.5.3-0 -> k8s.gcr.io/etcd:3.5.3-0, so that logs can be matched with the upstream docs.
+// References which aren't in mirror, or aren't images minikube knows, are returned unchanged.
+// It is only meant for display: the images are still pulled from the mirror.
+func CanonicalName(ref string, mirror string, v semver.Version) string {
+	mirror = normalizeMirror(mirror)
+	if mirror == "" {
+		return ref
+	}
+	canonical, ok := canonicalRepos(mirror, v)[repository(ref)]
+	if !ok {
+		return ref
+	}
+	return canonical + strings.TrimPrefix(ref, repository(ref))
+}
+
+// dockerHubRegistry is the registry of references without a registry host
+const dockerHubRegistry = "docker.io"
+
+// qualify returns ref with a registry host, adding the docker.io registry to the references which resolve to it
+// (e.g. kindest/kindnetd:v1 -> docker.io/kindest/kindnetd:v1, busybox -> docker.io/library/busybox)
+func qualify(ref string) string {
+	i := strings.Index(ref, "/")
+	if i < 0 {
+		return path.Join(dockerHubRegistry, "library", ref)
+	}
+	// like docker, the first component is only a registry if it looks like a host
+	if host := ref[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+		return ref
+	}
+	return path.Join(dockerHubRegistry, ref)
+}
+
+// RewriteFunc returns the reference to use instead of the image reference ref
+type RewriteFunc func(ref string) string
+
+// rewriteAll returns imgs after rewrite
+func rewriteAll(imgs []string, rewrite RewriteFunc) []string {
+	rewritten := []string{}
+	for _, img := range imgs {
+		rewritten = append(rewritten, rewrite(img))
+	}
+	return rewritten
+}
+
+// qualifyAll returns imgs after qualify
+func qualifyAll(imgs []string) []string {
+	return rewriteAll(imgs, qualify)
+}
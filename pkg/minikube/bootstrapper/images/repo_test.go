@@ -17,27 +17,61 @@ limitations under the License.
 package images
 
 import (
+	"path"
+	"strings"
 	"testing"
 
+	"github.com/blang/semver/v4"
+	"github.com/docker/distribution/reference"
 	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/version"
 )
 
 func Test_kubernetesRepo(t *testing.T) {
 	tests := []struct {
-		mirror string
-		want   string
+		mirror  string
+		version semver.Version
+		want    string
 	}{
 		{
 			"",
+			semver.MustParse("1.24.9"),
+			DefaultKubernetesRepo,
+		},
+		{
+			"",
+			semver.MustParse("1.25.0-alpha.0"),
 			DefaultKubernetesRepo,
 		},
+		{
+			"",
+			semver.MustParse("1.25.0-alpha.1"),
+			RegistryK8sIORepo,
+		},
+		{
+			"",
+			semver.MustParse("1.25.0"),
+			RegistryK8sIORepo,
+		},
 		{
 			"mirror.k8s.io",
+			semver.MustParse("1.24.9"),
 			"mirror.k8s.io",
 		},
+		{
+			"mirror.k8s.io",
+			semver.MustParse("1.25.0"),
+			"mirror.k8s.io",
+		},
+		{
+			"k8s.gcr.io",
+			semver.MustParse("1.25.0"),
+			"k8s.gcr.io",
+		},
 	}
 	for _, tc := range tests {
-		got := kubernetesRepo(tc.mirror)
+		got := kubernetesRepo(tc.mirror, tc.version)
 		if !cmp.Equal(got, tc.want) {
 			t.Errorf("mirror miss match, want: %s, got: %s", tc.want, got)
 		}
@@ -68,3 +102,423 @@ func Test_minikubeRepo(t *testing.T) {
 	}
 
 }
+
+func Test_kubeVipRepo(t *testing.T) {
+	tests := []struct {
+		mirror string
+		want   string
+	}{
+		{
+			"",
+			"ghcr.io/kube-vip",
+		},
+		{
+			"mirror.k8s.io",
+			"mirror.k8s.io/kube-vip",
+		},
+	}
+
+	for _, tc := range tests {
+		got := kubeVipRepo(tc.mirror)
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("mirror miss match, want: %s, got: %s", tc.want, got)
+		}
+	}
+
+}
+
+func Test_normalizeMirror(t *testing.T) {
+	tests := []struct {
+		mirror string
+		want   string
+	}{
+		{"", ""},
+		{"test.mirror", "test.mirror"},
+		{"test.mirror/", "test.mirror"},
+		{"test.mirror//", "test.mirror"},
+		{"https://test.mirror", "test.mirror"},
+		{"http://test.mirror:5000/", "test.mirror:5000"},
+		{"test.mirror:5000/google_containers/", "test.mirror:5000/google_containers"},
+		{" test.mirror ", "test.mirror"},
+	}
+	for _, tc := range tests {
+		if got := normalizeMirror(tc.mirror); got != tc.want {
+			t.Errorf("normalizeMirror(%q) = %q, want %q", tc.mirror, got, tc.want)
+		}
+	}
+}
+
+func TestValidateMirror(t *testing.T) {
+	tests := []struct {
+		mirror  string
+		wantErr bool
+	}{
+		{"", false},
+		{"test.mirror", false},
+		{"test.mirror/", false},
+		{"https://test.mirror", false},
+		{"localhost:5000", false},
+		{"test.mirror:5000/google_containers", false},
+		{"https://", true},
+		{"test.mirror:port", true},
+		{"test.mirror:99999", true},
+		{"test mirror", true},
+		{"-test.mirror", true},
+		{"test.mirror/foo bar", true},
+	}
+	for _, tc := range tests {
+		err := ValidateMirror(tc.mirror)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ValidateMirror(%q) = %v, want error: %t", tc.mirror, err, tc.wantErr)
+		}
+	}
+}
+
+func FuzzNormalizeMirror(f *testing.F) {
+	for _, mirror := range []string{
+		"", "test.mirror", "test.mirror/", "test.mirror//", "https://test.mirror", "http://test.mirror:5000/",
+		"test.mirror:5000/google_containers/", " test.mirror ", "localhost:5000", "https://", "test.mirror:port",
+		"test.mirror:99999", "test mirror", "-test.mirror", "test.mirror/foo bar", "mirror.corp/a/b/c",
+		"mirror.corp/MyOrg", "https://mirror.corp:5000/k8s/", "mirror.corp:0", "http://a://b", "mirrör.corp/ñ",
+	} {
+		f.Add(mirror)
+	}
+	f.Fuzz(func(t *testing.T, mirror string) {
+		m := normalizeMirror(mirror)
+		if err := ValidateMirror(mirror); err != nil || m == "" {
+			return
+		}
+		if strings.Contains(m, "://") || strings.HasSuffix(m, "/") || strings.TrimSpace(m) != m {
+			t.Fatalf("normalizeMirror(%q) = %q, want no scheme, trailing slash or surrounding space", mirror, m)
+		}
+		if again := normalizeMirror(m); again != m {
+			t.Fatalf("normalizeMirror(%q) = %q, want the normalized mirror %q unchanged", m, again, m)
+		}
+		ref := path.Join(m, "pause") + ":3.6"
+		_, parseErr := reference.ParseNormalizedNamed(ref)
+		if err := ValidateReferences([]string{ref}); (err != nil) != (parseErr != nil) {
+			t.Fatalf("ValidateReferences(%q) = %v, want it to agree with parsing the reference: %v", ref, err, parseErr)
+		}
+	})
+}
+
+func TestMirrorNormalizedInImages(t *testing.T) {
+	for _, mirror := range []string{"test.mirror", "test.mirror/", "https://test.mirror"} {
+		t.Run(mirror, func(t *testing.T) {
+			want := []string{
+				"test.mirror/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+				"test.mirror/kindnetd:v20210326-1e038dc5",
+				"test.mirror/node:" + calicoVersion,
+			}
+			got := append(auxiliary(mirror), KindNet(mirror), CalicoDaemonSet(mirror))
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_qualify(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"busybox:1.35", "docker.io/library/busybox:1.35"},
+		{"kindest/kindnetd:v20210326-1e038dc5", "docker.io/kindest/kindnetd:v20210326-1e038dc5"},
+		{"myorg/kube-apiserver:v1.24.0", "docker.io/myorg/kube-apiserver:v1.24.0"},
+		{"k8s.gcr.io/pause:3.7", "k8s.gcr.io/pause:3.7"},
+		{"registry.corp:5000/etcd:3.5.3-0", "registry.corp:5000/etcd:3.5.3-0"},
+		{"localhost/coredns/coredns:v1.8.6", "localhost/coredns/coredns:v1.8.6"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			if got := qualify(tc.ref); got != tc.want {
+				t.Errorf("qualify(%s) = %s, want %s", tc.ref, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFullyQualifiedImages(t *testing.T) {
+	for _, mirror := range []string{"", "myorg", "registry.corp:5000"} {
+		for _, cni := range []string{"kindnet", "calico", "flannel"} {
+			t.Run(mirror+"/"+cni, func(t *testing.T) {
+				imgs, err := ImagesForVersion(mirror, semver.MustParse("1.24.0"), ImageOptions{CNI: cni, FullyQualified: true})
+				if err != nil {
+					t.Fatalf("ImagesForVersion: %v", err)
+				}
+				kubeadm, err := KubeadmWithOptions(mirror, "v1.24.0", ImageOptions{FullyQualified: true})
+				if err != nil {
+					t.Fatalf("KubeadmWithOptions: %v", err)
+				}
+				for _, img := range append(imgs, kubeadm...) {
+					host := strings.SplitN(img, "/", 2)[0]
+					if !strings.ContainsAny(host, ".:") && host != "localhost" {
+						t.Errorf("image %s has no registry host", img)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFullyQualifiedOnlyDockerHubImages(t *testing.T) {
+	v := semver.MustParse("1.24.0")
+	opts := ImageOptions{CNI: "calico", Offline: true}
+	short, err := ImagesForVersion("", v, opts)
+	if err != nil {
+		t.Fatalf("ImagesForVersion: %v", err)
+	}
+	opts.FullyQualified = true
+	qualified, err := ImagesForVersion("", v, opts)
+	if err != nil {
+		t.Fatalf("ImagesForVersion fully qualified: %v", err)
+	}
+
+	want := map[string]bool{}
+	for _, img := range short {
+		host := strings.SplitN(img, "/", 2)[0]
+		switch {
+		case !strings.Contains(img, "/"):
+			img = "docker.io/library/" + img
+		case !strings.ContainsAny(host, ".:") && host != "localhost":
+			img = "docker.io/" + img
+		}
+		want[img] = true
+	}
+	got := map[string]bool{}
+	for _, img := range qualified {
+		got[img] = true
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("images mismatch (-want +got):\n%s", diff)
+	}
+	for _, img := range []string{
+		"docker.io/calico/node:" + calicoVersion,
+		"k8s.gcr.io/pause:3.7",
+		"gcr.io/k8s-minikube/storage-provisioner:" + version.GetStorageProvisionerVersion(),
+	} {
+		if !got[img] {
+			t.Errorf("fully qualified images lack %s: %v", img, qualified)
+		}
+	}
+}
+
+func TestCanonicalName(t *testing.T) {
+	v := semver.MustParse("1.24.0")
+	tests := []struct {
+		ref    string
+		mirror string
+		v      semver.Version
+		want   string
+	}{
+		{"registry.corp:5000/etcd:3.5.3-0", "registry.corp:5000", v, "k8s.gcr.io/etcd:3.5.3-0"},
+		{"registry.corp/k8s/etcd:3.5.3-0", "https://registry.corp/k8s/", v, "k8s.gcr.io/etcd:3.5.3-0"},
+		{"registry.corp/etcd:3.5.4-0", "registry.corp", semver.MustParse("1.25.0"), "registry.k8s.io/etcd:3.5.4-0"},
+		{"registry.corp/pause:3.7", "registry.corp", semver.MustParse("1.25.0"), "k8s.gcr.io/pause:3.7"},
+		{"registry.corp/coredns/coredns:v1.8.6", "registry.corp", v, "k8s.gcr.io/coredns/coredns:v1.8.6"},
+		{"registry.corp/k8s-minikube/storage-provisioner:v5", "registry.corp", v, "gcr.io/k8s-minikube/storage-provisioner:v5"},
+		{"registry.corp/node:v3.20.0", "registry.corp", v, "docker.io/calico/node:v3.20.0"},
+		{"registry.corp/cilium:v1.9.9@sha256:" + strings.Repeat("a", 64), "registry.corp", v, "quay.io/cilium/cilium:v1.9.9@sha256:" + strings.Repeat("a", 64)},
+		{aliyunMirror + "/kicbase:v0.0.25", aliyunMirror, v, "gcr.io/k8s-minikube/kicbase:v0.0.25"},
+		// not mirrored, or unknown to minikube
+		{"k8s.gcr.io/etcd:3.5.3-0", "", v, "k8s.gcr.io/etcd:3.5.3-0"},
+		{"k8s.gcr.io/etcd:3.5.3-0", "registry.corp", v, "k8s.gcr.io/etcd:3.5.3-0"},
+		{"registry.corporate/etcd:3.5.3-0", "registry.corp", v, "registry.corporate/etcd:3.5.3-0"},
+		{"registry.corp/busybox:1.35", "registry.corp", v, "registry.corp/busybox:1.35"},
+		{"registry.corp/team/node:18", "registry.corp", v, "registry.corp/team/node:18"},
+		{"registry.corp/cni/plugins:v1", "registry.corp", v, "registry.corp/cni/plugins:v1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			if got := CanonicalName(tc.ref, tc.mirror, tc.v); got != tc.want {
+				t.Errorf("CanonicalName(%s, %s, %s) = %s, want %s", tc.ref, tc.mirror, tc.v, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalNameOfMirroredImages(t *testing.T) {
+	for _, version := range []string{"v1.24.0", "v1.25.0"} {
+		t.Run(version, func(t *testing.T) {
+			defaults, err := KubeadmWithOptions("", version, ImageOptions{})
+			if err != nil {
+				t.Fatalf("KubeadmWithOptions: %v", err)
+			}
+			mirrored, err := KubeadmWithOptions("registry.corp", version, ImageOptions{})
+			if err != nil {
+				t.Fatalf("KubeadmWithOptions: %v", err)
+			}
+			v := semver.MustParse(strings.TrimPrefix(version, "v"))
+			got := []string{}
+			for _, img := range mirrored {
+				got = append(got, CanonicalName(img, "registry.corp", v))
+			}
+			if diff := cmp.Diff(defaults, got); diff != "" {
+				t.Errorf("canonical names mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRewriteImages(t *testing.T) {
+	rewrite := func(ref string) string {
+		switch {
+		case strings.HasPrefix(ref, "k8s.gcr.io/etcd:"):
+			return "etcd.corp:5000/" + strings.TrimPrefix(ref, "k8s.gcr.io/")
+		case strings.HasPrefix(ref, "k8s.gcr.io/pause:"):
+			return "registry.corp/k8s/sandbox/" + strings.TrimPrefix(ref, "k8s.gcr.io/")
+		}
+		return ref
+	}
+	want := map[string]string{
+		"k8s.gcr.io/etcd:3.5.3-0": "etcd.corp:5000/etcd:3.5.3-0",
+		"k8s.gcr.io/pause:3.7":    "registry.corp/k8s/sandbox/pause:3.7",
+	}
+
+	defaults, err := KubeadmWithOptions("", "v1.24.0", ImageOptions{})
+	if err != nil {
+		t.Fatalf("KubeadmWithOptions: %v", err)
+	}
+	got, err := KubeadmWithOptions("", "v1.24.0", ImageOptions{Rewrite: rewrite})
+	if err != nil {
+		t.Fatalf("KubeadmWithOptions: %v", err)
+	}
+	expected := []string{}
+	for _, img := range defaults {
+		if r, ok := want[img]; ok {
+			img = r
+		}
+		expected = append(expected, img)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("rewritten images mismatch (-want +got):\n%s", diff)
+	}
+
+	imgs, err := StructuredImagesForVersion("", semver.MustParse("1.24.0"), ImageOptions{Rewrite: rewrite})
+	if err != nil {
+		t.Fatalf("StructuredImagesForVersion: %v", err)
+	}
+	roles := map[string]Role{}
+	for _, img := range imgs {
+		roles[img.String()] = img.Role
+	}
+	for original, rewritten := range want {
+		if _, ok := roles[original]; ok {
+			t.Errorf("image %s was not rewritten", original)
+		}
+		if roles[rewritten] != RoleControlPlane {
+			t.Errorf("image %s role = %q, want %q", rewritten, roles[rewritten], RoleControlPlane)
+		}
+	}
+}
+
+// essentialsRepos returns the repository every essential image of v is pulled from, keyed by component
+func essentialsRepos(t *testing.T, mirror string, v semver.Version, opts ImageOptions) map[string]string {
+	t.Helper()
+	repos := map[string]string{}
+	for _, img := range essentialsWithOptions(mirror, v, opts) {
+		parsed := parseImage(img, "")
+		name := component(img)
+		repos[name] = strings.TrimSuffix(strings.TrimSuffix(path.Join(parsed.Registry, parsed.Repo), "/"+name), "/coredns")
+	}
+	return repos
+}
+
+func TestPauseRepoAtMigrationBoundary(t *testing.T) {
+	tests := []struct {
+		version      string
+		pauseVersion string
+		pauseRepo    string
+	}{
+		{"1.24.0", "", DefaultKubernetesRepo},
+		{"1.24.9", "", DefaultKubernetesRepo},
+		{"1.25.0-alpha.0", "", DefaultKubernetesRepo},
+		// the other images moved, the pause tag pinned for v1.25 didn't
+		{"1.25.0-alpha.1", "", DefaultKubernetesRepo},
+		{"1.25.0", "", DefaultKubernetesRepo},
+		{"1.25.0", RegistryK8sIOPauseTag, RegistryK8sIORepo},
+		// the pause image moved first
+		{"1.24.9", RegistryK8sIOPauseTag, RegistryK8sIORepo},
+		{"1.24.9", "3.9", RegistryK8sIORepo},
+		// no pinned pause tag
+		{"1.26.0", "", RegistryK8sIORepo},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version+"/"+tc.pauseVersion, func(t *testing.T) {
+			if err := SetPauseVersion(tc.pauseVersion); err != nil {
+				t.Fatalf("SetPauseVersion: %v", err)
+			}
+			defer func() {
+				if err := SetPauseVersion(""); err != nil {
+					t.Errorf("SetPauseVersion: %v", err)
+				}
+			}()
+			v := semver.MustParse(tc.version)
+			got := essentialsRepos(t, "", v, ImageOptions{Offline: true, Arch: "amd64"})
+
+			want := map[string]string{"pause": tc.pauseRepo}
+			for _, name := range []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kube-proxy", "etcd", "coredns"} {
+				want[name] = DefaultKubernetesRepoForVersion(v)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("repositories mismatch (-want +got):\n%s", diff)
+			}
+			if pause := Pause(v, ""); !strings.HasPrefix(pause, tc.pauseRepo+"/pause:") {
+				t.Errorf("Pause(%s) = %s, want it pulled from %s", v, pause, tc.pauseRepo)
+			}
+		})
+	}
+}
+
+func TestSetPauseRepository(t *testing.T) {
+	if err := SetPauseRepository("https://pause.example.com/sandbox/"); err != nil {
+		t.Fatalf("SetPauseRepository: %v", err)
+	}
+	defer func() {
+		if err := SetPauseRepository(""); err != nil {
+			t.Fatalf("reset pause repository: %v", err)
+		}
+	}()
+
+	tests := []struct {
+		name   string
+		mirror string
+		v      string
+		opts   ImageOptions
+		want   string
+		others string
+	}{
+		{"before migration", "", "1.24.9", ImageOptions{}, "pause.example.com/sandbox", DefaultKubernetesRepo},
+		{"after migration", "", "1.25.0", ImageOptions{}, "pause.example.com/sandbox", RegistryK8sIORepo},
+		{"pre-release", "", "1.25.0-alpha.1", ImageOptions{}, "pause.example.com/sandbox", RegistryK8sIORepo},
+		{"mirror", "mirror.example.com", "1.25.0", ImageOptions{}, "pause.example.com/sandbox", "mirror.example.com"},
+		{"component repository", "mirror.example.com", "1.25.0", ImageOptions{ComponentRepos: map[string]string{"pause": "component.example.com"}}, "component.example.com", "mirror.example.com"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := semver.MustParse(tc.v)
+			tc.opts.Offline = true
+			tc.opts.Arch = "amd64"
+			got := essentialsRepos(t, tc.mirror, v, tc.opts)
+
+			want := map[string]string{}
+			for name := range got {
+				want[name] = tc.others
+			}
+			want["pause"] = tc.want
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("repositories mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+	if got := Pause(semver.MustParse("1.25.0"), "mirror.example.com"); !strings.HasPrefix(got, "pause.example.com/sandbox/pause:") {
+		t.Errorf("Pause() = %s, want it pulled from the pause repository", got)
+	}
+	if err := SetPauseRepository("pause.example.com:99999"); err == nil {
+		t.Errorf("SetPauseRepository with an out of range port = nil, want error")
+	}
+	if pauseRepository != "pause.example.com/sandbox" {
+		t.Errorf("pause repository = %q after an invalid repository, want it unchanged", pauseRepository)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// bytesPerMB converts the sizes of pullSizeHints to bytes, in the decimal megabytes registries and docker report
+const bytesPerMB = 1000 * 1000
+
+// EstimatedDownloadSize returns the approximate number of bytes pulling images downloads, to warn about metered connections.
+// Unless live, the sizes are taken from the per-component table of PullSizeHint, and images of unknown components are
+// counted as empty. If live, the config and layer sizes of the manifest of each image for the architecture minikube
+// runs on are summed instead, failing if any manifest can't be fetched.
+func EstimatedDownloadSize(images []string, live bool) (int64, error) {
+	images = dedupe(images)
+	if !live {
+		var total int64
+		for _, img := range images {
+			hint := PullSizeHint(img)
+			if hint == 0 {
+				klog.V(3).Infof("no size is known for %s, not counting it", img)
+			}
+			total += int64(hint) * bytesPerMB
+		}
+		return total, nil
+	}
+	if offline {
+		return 0, errors.New("can't query image sizes in offline mode")
+	}
+
+	ctx := lookupContext
+	sizes := make([]int64, len(images))
+	errs := make([]error, len(images))
+	runBounded(ctx, len(images), func(i int) {
+		sizes[i], errs[i] = manifestSize(ctx, images[i])
+	})
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var total int64
+	for i, img := range images {
+		if errs[i] != nil {
+			return 0, errors.Wrapf(errs[i], "size of %s", img)
+		}
+		total += sizes[i]
+	}
+	return total, nil
+}
+
+// manifestSize returns the sum of the config and layer sizes of the manifest of img for the architecture minikube runs on
+func manifestSize(ctx context.Context, img string) (int64, error) {
+	ref, err := name.ParseReference(img, name.WeakValidation)
+	if err != nil {
+		return 0, errors.Wrap(err, "parse reference")
+	}
+	platform := v1.Platform{OS: "linux", Architecture: runtime.GOARCH}
+	image, err := remote.Image(ref, append(remoteOptions(ctx), remote.WithPlatform(platform))...)
+	if err != nil {
+		return 0, registryError(ctx, err)
+	}
+	manifest, err := image.Manifest()
+	if err != nil {
+		return 0, errors.Wrap(err, "manifest")
+	}
+	size := manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+	return size, nil
+}
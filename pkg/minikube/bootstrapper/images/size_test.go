@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestEstimatedDownloadSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		images []string
+		want   int64
+	}{
+		{"none", nil, 0},
+		{"essentials", []string{
+			"k8s.gcr.io/kube-apiserver:v1.24.0",
+			"k8s.gcr.io/kube-controller-manager:v1.24.0",
+			"k8s.gcr.io/kube-scheduler:v1.24.0",
+			"k8s.gcr.io/kube-proxy:v1.24.0",
+			"k8s.gcr.io/pause:3.7",
+			"k8s.gcr.io/etcd:3.5.3-0",
+			"k8s.gcr.io/coredns/coredns:v1.8.6",
+		}, (130 + 120 + 50 + 110 + 1 + 300 + 46) * bytesPerMB},
+		{"mirrored", []string{"registry.corp/etcd:3.5.3-0", "registry.corp/k8s-minikube/storage-provisioner:v5"}, (300 + 31) * bytesPerMB},
+		{"unknown counted as empty", []string{"k8s.gcr.io/pause:3.7", "registry.corp/unknown:v1"}, 1 * bytesPerMB},
+		{"duplicates counted once", []string{"k8s.gcr.io/etcd:3.5.3-0", "k8s.gcr.io/etcd:3.5.3-0"}, 300 * bytesPerMB},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := EstimatedDownloadSize(tc.images, false)
+			if err != nil {
+				t.Fatalf("EstimatedDownloadSize: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("EstimatedDownloadSize(%v) = %d, want %d", tc.images, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEstimatedDownloadSizeLive(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	var want int64
+	imgs := []string{}
+	for i, repo := range []string{"etcd", "coredns/coredns"} {
+		image, err := random.Image(1024, int64(i+2))
+		if err != nil {
+			t.Fatalf("random.Image: %v", err)
+		}
+		ref, err := name.ParseReference(host+"/"+repo+":v1", name.WeakValidation)
+		if err != nil {
+			t.Fatalf("ParseReference: %v", err)
+		}
+		if err := remote.Write(ref, image); err != nil {
+			t.Fatalf("remote.Write(%s): %v", ref, err)
+		}
+		manifest, err := image.Manifest()
+		if err != nil {
+			t.Fatalf("Manifest: %v", err)
+		}
+		want += manifest.Config.Size
+		for _, l := range manifest.Layers {
+			want += l.Size
+		}
+		imgs = append(imgs, ref.String())
+	}
+
+	got, err := EstimatedDownloadSize(imgs, true)
+	if err != nil {
+		t.Fatalf("EstimatedDownloadSize: %v", err)
+	}
+	if got != want {
+		t.Errorf("EstimatedDownloadSize(%v, live) = %d, want the manifest sizes %d", imgs, got, want)
+	}
+
+	if _, err := EstimatedDownloadSize([]string{host + "/missing:v1"}, true); err == nil {
+		t.Error("EstimatedDownloadSize of a missing image succeeded, want an error")
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"fmt"
+	"strings"
+)
+
+// strictImages makes the required image lists an error if any image isn't pinned to a version
+var strictImages bool
+
+// SetStrictImages sets whether Kubeadm and StructuredImagesForVersion fail on images without an explicit tag, or tagged latest
+func SetStrictImages(strict bool) {
+	strictImages = strict
+}
+
+// ValidatePinned returns an error naming every image which has neither a digest nor an explicit tag other than latest
+func ValidatePinned(imgs []string) error {
+	unpinned := []string{}
+	for _, ref := range imgs {
+		img := parseImage(ref, "")
+		if img.Digest != "" {
+			continue
+		}
+		if img.Tag == "" || img.Tag == "latest" {
+			unpinned = append(unpinned, ref)
+		}
+	}
+	if len(unpinned) > 0 {
+		return fmt.Errorf("images aren't pinned to a version: %s", strings.Join(unpinned, ", "))
+	}
+	return nil
+}
+
+// checkStrict returns ValidatePinned of imgs if strict images are required
+func checkStrict(imgs []string) error {
+	if !strictImages {
+		return nil
+	}
+	return ValidatePinned(imgs)
+}
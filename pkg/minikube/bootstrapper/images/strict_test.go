@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+func TestValidatePinned(t *testing.T) {
+	tests := []struct {
+		name    string
+		images  []string
+		wantErr bool
+	}{
+		{"pinned", []string{"k8s.gcr.io/pause:3.7", "registry.corp:5000/etcd:3.5.3-0", "k8s.gcr.io/etcd@sha256:abc"}, false},
+		{"untagged", []string{"k8s.gcr.io/pause:3.7", "registry.corp:5000/etcd"}, true},
+		{"empty tag", []string{"gcr.io/k8s-minikube/storage-provisioner:"}, true},
+		{"latest", []string{"k8s.gcr.io/pause:latest"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePinned(tc.images)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidatePinned(%v) = %v, want error: %t", tc.images, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestStrictImages(t *testing.T) {
+	useTagCache(t, "")
+	SetStrictImages(true)
+	defer SetStrictImages(false)
+
+	v := semver.MustParse("1.24.0")
+	if _, err := StructuredImagesForVersion("", v, ImageOptions{NoStorageProvisioner: true}); err != nil {
+		t.Errorf("StructuredImagesForVersion of pinned images: %v", err)
+	}
+
+	if err := SetStorageProvisionerImage("myrepo/storage-provisioner"); err != nil {
+		t.Fatalf("SetStorageProvisionerImage: %v", err)
+	}
+	defer func() {
+		if err := SetStorageProvisionerImage(""); err != nil {
+			t.Errorf("reset storage provisioner image: %v", err)
+		}
+	}()
+	if _, err := StructuredImagesForVersion("", v, ImageOptions{}); err == nil {
+		t.Errorf("StructuredImagesForVersion with an untagged image succeeded, want an error")
+	}
+	if _, err := KubeadmWithOptions("", "v1.24.0", ImageOptions{}); err == nil {
+		t.Errorf("KubeadmWithOptions with an untagged image succeeded, want an error")
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog/v2"
+)
+
+// Role is why an image is required by a cluster
+type Role string
+
+const (
+	// RoleControlPlane is the role of the images bootstrapping Kubernetes, such as etcd and kube-proxy
+	RoleControlPlane Role = "control-plane"
+	// RoleAuxiliary is the role of the images minikube runs alongside Kubernetes, such as storage-provisioner
+	RoleAuxiliary Role = "auxiliary"
+	// RoleCNI is the role of the images of the CNI
+	RoleCNI Role = "cni"
+	// RoleListed is the role of the images given to SetImageList, which minikube doesn't know the role of
+	RoleListed Role = "listed"
+)
+
+// Image is a required image, split into the parts of its reference
+type Image struct {
+	// Registry is the registry host of the image, empty for Docker Hub images referenced without one
+	Registry string `json:"registry"`
+	Repo     string `json:"repo"`
+	Tag      string `json:"tag"`
+	Digest   string `json:"digest,omitempty"`
+	Role     Role   `json:"role"`
+
+	// reference is the reference the image was parsed from, if any
+	reference string
+}
+
+// String returns the reference of the image
+func (i Image) String() string {
+	if i.reference != "" {
+		return i.reference
+	}
+	ref := i.Repo
+	if i.Registry != "" {
+		ref = i.Registry + "/" + ref
+	}
+	if i.Tag != "" {
+		ref += ":" + i.Tag
+	}
+	if i.Digest != "" {
+		ref += "@" + i.Digest
+	}
+	return ref
+}
+
+// References returns the reference of every image
+func References(imgs []Image) []string {
+	refs := []string{}
+	for _, img := range imgs {
+		refs = append(refs, img.String())
+	}
+	return refs
+}
+
+// parseImage splits the image reference ref into an Image with the given role
+func parseImage(ref string, role Role) Image {
+	img := Image{Role: role, reference: ref}
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref, img.Digest = ref[:i], ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, img.Tag = ref[:i], ref[i+1:]
+	}
+	// like docker, the first component is only a registry if it looks like a host
+	if i := strings.Index(ref, "/"); i >= 0 {
+		if host := ref[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref, img.Registry = ref[i+1:], host
+		}
+	}
+	img.Repo = ref
+	return img
+}
+
+// StructuredImagesForVersion returns the images of ImagesForVersion, classified by the role they play in the cluster
+func StructuredImagesForVersion(repo string, k8sVersion semver.Version, opts ImageOptions) ([]Image, error) {
+	imgs, err := classifiedImages(repo, k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateRegistries(References(imgs)); err != nil {
+		return nil, err
+	}
+	return imgs, nil
+}
+
+// classifiedImages returns the images of StructuredImagesForVersion without checking them against the registry policy,
+// which only applies to the images actually pulled, not to the upstream images a mirror replaces
+func classifiedImages(repo string, k8sVersion semver.Version, opts ImageOptions) ([]Image, error) {
+	refs, roles, err := imageRefs(repo, k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	imgs := []Image{}
+	for _, ref := range dedupe(refs) {
+		imgs = append(imgs, parseImage(ref, roles[ref]))
+	}
+	return imgs, nil
+}
+
+// imageRefs returns every image needed by k8sVersion with opts in the order they are pulled, along with the role of each of them.
+// It builds the images of both KubeadmWithOptions and StructuredImagesForVersion.
+func imageRefs(repo string, k8sVersion semver.Version, opts ImageOptions) ([]string, map[string]Role, error) {
+	if err := checkVersion(k8sVersion); err != nil {
+		return nil, nil, err
+	}
+	roles := map[string]Role{}
+	classify := func(refs []string, role Role) {
+		for _, ref := range refs {
+			if _, ok := roles[ref]; !ok {
+				roles[ref] = role
+			}
+		}
+	}
+	if imageList != nil {
+		refs := append([]string{}, imageList...)
+		classify(refs, RoleListed)
+		return refs, roles, nil
+	}
+	if err := validateMirrors(repo, opts); err != nil {
+		return nil, nil, err
+	}
+	if err := validateArch(opts.Arch); err != nil {
+		return nil, nil, err
+	}
+	if err := validateOS(opts.OS); err != nil {
+		return nil, nil, err
+	}
+
+	refs := essentialsWithOptions(repo, k8sVersion, opts)
+	classify(refs, RoleControlPlane)
+	aux := auxiliaryWithOptions(repo, opts)
+	classify(aux, RoleAuxiliary)
+	refs = append(refs, aux...)
+	if opts.CNI != "" {
+		cni, err := cniImages(opts.CNI, repo)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := CheckCNICompatibility(opts.CNI, k8sVersion); err != nil {
+			klog.Warningf("the images of the %s CNI may not work: %v", opts.CNI, err)
+		}
+		cni = withArch(cni, targetArch(opts))
+		classify(cni, RoleCNI)
+		refs = append(refs, cni...)
+	}
+
+	if opts.FullyQualified {
+		qualified := qualifyAll(refs)
+		for i, ref := range refs {
+			roles[qualified[i]] = roles[ref]
+		}
+		refs = qualified
+	}
+	if opts.Rewrite != nil {
+		rewritten := rewriteAll(refs, opts.Rewrite)
+		for i, ref := range refs {
+			roles[rewritten[i]] = roles[ref]
+		}
+		refs = rewritten
+	}
+	if err := ValidateReferences(refs); err != nil {
+		return nil, nil, err
+	}
+	if err := checkStrict(refs); err != nil {
+		return nil, nil, err
+	}
+	return refs, roles, nil
+}
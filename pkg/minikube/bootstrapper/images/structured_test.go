@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStructuredImagesForVersion(t *testing.T) {
+	v := semver.MustParse("1.22.0")
+	opts := ImageOptions{CNI: "calico"}
+	imgs, err := StructuredImagesForVersion("", v, opts)
+	if err != nil {
+		t.Fatalf("StructuredImagesForVersion: %v", err)
+	}
+
+	roles := map[string]Role{}
+	for _, img := range imgs {
+		roles[img.Repo] = img.Role
+	}
+	want := map[string]Role{
+		"etcd":                             RoleControlPlane,
+		"kube-proxy":                       RoleControlPlane,
+		"k8s-minikube/storage-provisioner": RoleAuxiliary,
+		"calico/node":                      RoleCNI,
+	}
+	for repo, role := range want {
+		if roles[repo] != role {
+			t.Errorf("%s role = %q, want %q", repo, roles[repo], role)
+		}
+	}
+
+	refs, err := ImagesForVersion("", v, opts)
+	if err != nil {
+		t.Fatalf("ImagesForVersion: %v", err)
+	}
+	if diff := cmp.Diff(refs, References(imgs)); diff != "" {
+		t.Errorf("references mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want Image
+	}{
+		{"k8s.gcr.io/etcd:3.5.0-0", Image{Registry: "k8s.gcr.io", Repo: "etcd", Tag: "3.5.0-0"}},
+		{"localhost:5000/coredns/coredns:v1.8.4", Image{Registry: "localhost:5000", Repo: "coredns/coredns", Tag: "v1.8.4"}},
+		{"kindest/kindnetd:v20210326-1e038dc5", Image{Repo: "kindest/kindnetd", Tag: "v20210326-1e038dc5"}},
+		{"quay.io/cilium/cilium:v1.9.9@sha256:abc", Image{Registry: "quay.io", Repo: "cilium/cilium", Tag: "v1.9.9", Digest: "sha256:abc"}},
+		{"busybox", Image{Repo: "busybox"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			got := parseImage(tc.ref, RoleCNI)
+			if got.String() != tc.ref {
+				t.Errorf("String() = %s, want %s", got.String(), tc.ref)
+			}
+			// without the parsed reference, String() rebuilds it from its parts
+			got.reference = ""
+			if got.String() != tc.ref {
+				t.Errorf("rebuilt reference = %s, want %s", got.String(), tc.ref)
+			}
+			tc.want.Role = RoleCNI
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(Image{})); diff != "" {
+				t.Errorf("image mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImageJSON(t *testing.T) {
+	b, err := json.Marshal(parseImage("k8s.gcr.io/etcd:3.5.0-0", RoleControlPlane))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"registry":"k8s.gcr.io","repo":"etcd","tag":"3.5.0-0","role":"control-plane"}`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("json mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/juju/mutex"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+// tagCachePath returns the file latest tag lookups are cached in, an empty path disables the cache
+var tagCachePath = func() string {
+	return localpath.MakeMiniPath("cache", "image-tags.json")
+}
+
+// defaultTagCacheTTL is the tagCacheTTL used unless SetTagCacheTTL is given another
+const defaultTagCacheTTL = 24 * time.Hour
+
+// tagCacheTTL is how long a cached lookup is used before asking the registry again
+var tagCacheTTL = defaultTagCacheTTL
+
+// SetTagCacheTTL sets how long latest tag lookups are cached for, zero restores the default and a negative ttl disables the cache
+func SetTagCacheTTL(ttl time.Duration) {
+	if ttl == 0 {
+		ttl = defaultTagCacheTTL
+	}
+	tagCacheTTL = ttl
+}
+
+// clock tells the time the tag cache is checked against, so that tests can move it
+type clock interface {
+	Now() time.Time
+}
+
+// wallClock is the clock used outside of tests
+type wallClock struct{}
+
+// Now returns the current wall time
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+// tagClock is the clock cached lookups are timestamped and expired with
+var tagClock clock = wallClock{}
+
+// cachedTag is a latest tag lookup result, keyed by repository url in the cache file
+type cachedTag struct {
+	Tag       string    `json:"tag"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// loadTagCache returns the cached lookups, or an empty cache if there is none
+func loadTagCache(path string) map[string]cachedTag {
+	cache := map[string]cachedTag{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Warningf("failed to read tag cache %s: %v", path, err)
+		}
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		klog.Warningf("ignoring malformed tag cache %s: %v", path, err)
+		return map[string]cachedTag{}
+	}
+	return cache
+}
+
+// cachedLatestTag returns the cached latest tag for url, and whether it was checked within tagCacheTTL
+func cachedLatestTag(url string) (tag string, found bool, fresh bool) {
+	path := tagCachePath()
+	if path == "" || tagCacheTTL <= 0 {
+		return "", false, false
+	}
+	entry, ok := loadTagCache(path)[tagCacheKey(url)]
+	if !ok || entry.Tag == "" {
+		return "", false, false
+	}
+	return entry.Tag, true, tagClock.Now().Sub(entry.CheckedAt) < tagCacheTTL
+}
+
+// tagCacheKey returns the cache key of url, pre-release lookups are cached separately as they may pick a different tag
+func tagCacheKey(url string) string {
+	if includePreReleases {
+		return url + "#prerelease"
+	}
+	return url
+}
+
+// tagCacheMutex serializes the saves of this process, which the file lock of the cache would otherwise poll for
+var tagCacheMutex sync.Mutex
+
+// saveLatestTag records tag as the latest tag for url. The cache is locked from reading it until it is written,
+// so that concurrent saves, of this process or another, don't drop each other's entries.
+func saveLatestTag(url string, tag string) error {
+	path := tagCachePath()
+	if path == "" || tagCacheTTL <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "mkdir")
+	}
+	tagCacheMutex.Lock()
+	defer tagCacheMutex.Unlock()
+	releaser, err := mutex.Acquire(lock.PathMutexSpec(path))
+	if err != nil {
+		return errors.Wrapf(err, "failed to acquire lock for %s", path)
+	}
+	defer releaser.Release()
+
+	cache := loadTagCache(path)
+	cache[tagCacheKey(url)] = cachedTag{Tag: tag, CheckedAt: tagClock.Now()}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// useTagCache points the tag cache at path for the duration of the test, an empty path disables it
+func useTagCache(t *testing.T, path string) {
+	t.Helper()
+	orig := tagCachePath
+	tagCachePath = func() string { return path }
+	t.Cleanup(func() { tagCachePath = orig })
+}
+
+func writeTagCache(t *testing.T, path string, cache map[string]cachedTag) {
+	t.Helper()
+	data, err := json.Marshal(cache)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write cache: %v", err)
+	}
+}
+
+func TestLatestTagCache(t *testing.T) {
+	defer func(d time.Duration) { tagLookupInterval = d }(tagLookupInterval)
+	tagLookupInterval = time.Millisecond
+
+	var testCases = []struct {
+		name         string
+		cached       *cachedTag
+		serverStatus int
+		wantRequests int
+		expect       string
+		wantCached   string
+	}{
+		{name: "Miss", serverStatus: http.StatusOK, wantRequests: 1, expect: "v1.8.9", wantCached: "v1.8.9"},
+		{name: "MissUnreachable", serverStatus: http.StatusNotFound, wantRequests: 1, expect: "v1.8.6"},
+		{name: "Hit", cached: &cachedTag{Tag: "v1.8.7", CheckedAt: time.Now().Add(-time.Hour)}, serverStatus: http.StatusOK, wantRequests: 0, expect: "v1.8.7", wantCached: "v1.8.7"},
+		{name: "Expired", cached: &cachedTag{Tag: "v1.8.7", CheckedAt: time.Now().Add(-25 * time.Hour)}, serverStatus: http.StatusOK, wantRequests: 1, expect: "v1.8.9", wantCached: "v1.8.9"},
+		{name: "ExpiredUnreachable", cached: &cachedTag{Tag: "v1.8.7", CheckedAt: time.Now().Add(-25 * time.Hour)}, serverStatus: http.StatusNotFound, wantRequests: 1, expect: "v1.8.7", wantCached: "v1.8.7"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(tc.serverStatus)
+				if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+					t.Errorf("failed to write https response")
+				}
+			}))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "cache", "image-tags.json")
+			useTagCache(t, path)
+			if tc.cached != nil {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				writeTagCache(t, path, map[string]cachedTag{server.URL: *tc.cached})
+			}
+
+			got := findLatestTagFromRepository(context.Background(), server.URL, "v1.8.6")
+			if diff := cmp.Diff(tc.expect, got); diff != "" {
+				t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+			}
+			if requests != tc.wantRequests {
+				t.Errorf("got %d requests, want %d", requests, tc.wantRequests)
+			}
+			if diff := cmp.Diff(tc.wantCached, loadTagCache(path)[server.URL].Tag); diff != "" {
+				t.Errorf("Incorrect cached version (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLatestTagCacheDisabled(t *testing.T) {
+	defer SetTagCacheTTL(tagCacheTTL)
+	SetTagCacheTTL(-time.Second)
+
+	path := filepath.Join(t.TempDir(), "image-tags.json")
+	useTagCache(t, path)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	writeTagCache(t, path, map[string]cachedTag{server.URL: {Tag: "v1.8.7", CheckedAt: time.Now()}})
+	if got := findLatestTagFromRepository(context.Background(), server.URL, "v1.8.6"); got != "v1.8.9" {
+		t.Errorf("got %s, want the registry version v1.8.9 when the cache is disabled", got)
+	}
+}
+
+func TestSaveLatestTagConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image-tags.json")
+	useTagCache(t, path)
+
+	const saves = 20
+	var wg sync.WaitGroup
+	for i := 0; i < saves; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := saveLatestTag(fmt.Sprintf("https://registry.corp/v2/image%d/tags/list", i), fmt.Sprintf("v1.%d.0", i)); err != nil {
+				t.Errorf("saveLatestTag: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cache := loadTagCache(path)
+	for i := 0; i < saves; i++ {
+		url := fmt.Sprintf("https://registry.corp/v2/image%d/tags/list", i)
+		if got, want := cache[url].Tag, fmt.Sprintf("v1.%d.0", i); got != want {
+			t.Errorf("cached tag of %s = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestSetTagCacheTTL(t *testing.T) {
+	defer SetTagCacheTTL(tagCacheTTL)
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{ttl: 0, want: defaultTagCacheTTL},
+		{ttl: time.Hour, want: time.Hour},
+		{ttl: -time.Second, want: -time.Second},
+	}
+	for _, tc := range tests {
+		SetTagCacheTTL(tc.ttl)
+		if tagCacheTTL != tc.want {
+			t.Errorf("SetTagCacheTTL(%s): got %s, want %s", tc.ttl, tagCacheTTL, tc.want)
+		}
+	}
+}
+
+// fakeClock is a clock that only moves when the test advances it
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestLatestTagCacheClock(t *testing.T) {
+	defer func(c clock) { tagClock = c }(tagClock)
+	fake := &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tagClock = fake
+
+	path := filepath.Join(t.TempDir(), "image-tags.json")
+	useTagCache(t, path)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	lookup := func() {
+		t.Helper()
+		if got := findLatestTagFromRepository(context.Background(), server.URL, "v1.8.6"); got != "v1.8.9" {
+			t.Errorf("got %s, want v1.8.9", got)
+		}
+	}
+
+	lookup()
+	if got := loadTagCache(path)[server.URL].CheckedAt; !got.Equal(fake.now) {
+		t.Errorf("cached lookup checked at %v, want the fake clock time %v", got, fake.now)
+	}
+	fake.now = fake.now.Add(tagCacheTTL - time.Minute)
+	lookup()
+	if requests != 1 {
+		t.Errorf("got %d requests within the TTL, want 1", requests)
+	}
+	fake.now = fake.now.Add(2 * time.Minute)
+	lookup()
+	if requests != 2 {
+		t.Errorf("got %d requests past the TTL, want 2", requests)
+	}
+}
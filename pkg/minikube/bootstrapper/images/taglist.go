@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// tagParser returns the tags listed in the tag list response body from url
+type tagParser func(url string, body []byte) ([]string, error)
+
+// tagListParser extracts the tags from a tag list response, reporting whether the response has its shape
+type tagListParser struct {
+	name  string
+	parse func(body []byte) ([]string, bool)
+}
+
+// tagListParsers are tried in order until one recognizes the shape of a tag list response
+var tagListParsers = []tagListParser{
+	{"docker-v2", parseDockerV2Tags},
+	{"quay", parseQuayTags},
+	{"harbor", parseHarborTags},
+}
+
+// parseTagList returns the tags listed in body, as parsed by the first of tagListParsers to recognize it.
+// Responses no parser recognizes list no tags.
+func parseTagList(url string, body []byte) ([]string, error) {
+	if !json.Valid(body) {
+		return nil, errors.New("malformed tag list")
+	}
+	for _, p := range tagListParsers {
+		if tags, ok := p.parse(body); ok {
+			klog.V(3).Infof("parsed tag list of %s as %s", url, p.name)
+			return tags, nil
+		}
+	}
+	klog.Warningf("unrecognized tag list at %s: %s", url, body)
+	return nil, nil
+}
+
+// parseDockerV2Tags parses the tag list of the Docker registry and OCI distribution APIs: {"name": "etcd", "tags": ["3.5.3-0"]}
+func parseDockerV2Tags(body []byte) ([]string, bool) {
+	var list map[string]json.RawMessage
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, false
+	}
+	raw, ok := list["tags"]
+	if !ok {
+		return nil, false
+	}
+	var tags []string
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return nil, false
+	}
+	return tags, true
+}
+
+// namedTag is a tag listed as an object, such as {"name": "3.5.3-0", "manifest_digest": "sha256:..."}
+type namedTag struct {
+	Name string `json:"name"`
+}
+
+// namedTags returns the names of tags, reporting whether every tag has one
+func namedTags(tags []namedTag) ([]string, bool) {
+	names := []string{}
+	for _, t := range tags {
+		if t.Name == "" {
+			return nil, false
+		}
+		names = append(names, t.Name)
+	}
+	return names, true
+}
+
+// parseQuayTags parses the tag list of the quay API: {"tags": [{"name": "3.5.3-0", ...}], "page": 1}
+func parseQuayTags(body []byte) ([]string, bool) {
+	var list map[string]json.RawMessage
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, false
+	}
+	raw, ok := list["tags"]
+	if !ok {
+		return nil, false
+	}
+	var tags []namedTag
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return nil, false
+	}
+	return namedTags(tags)
+}
+
+// parseHarborTags parses the tag list of the Harbor API: [{"name": "3.5.3-0", ...}]
+func parseHarborTags(body []byte) ([]string, bool) {
+	var tags []namedTag
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, false
+	}
+	return namedTags(tags)
+}
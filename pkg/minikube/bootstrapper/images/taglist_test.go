@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTagListShapes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"docker-v2", `{"name": "coredns/coredns", "tags": ["v1.8.4", "v1.8.9"]}`},
+		{"quay", `{"tags": [{"name": "v1.8.4", "manifest_digest": "sha256:aa"}, {"name": "v1.8.9", "manifest_digest": "sha256:bb"}], "page": 1, "has_additional": false}`},
+		{"harbor", `[{"id": 1, "name": "v1.8.4", "immutable": false}, {"id": 2, "name": "v1.8.9", "immutable": false}]`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := w.Write([]byte(tc.body)); err != nil {
+					t.Errorf("failed to write https response")
+				}
+			}))
+			defer server.Close()
+
+			got, err := findLatestTagWithRetries(context.Background(), server.URL, "v1.8.6", 1, parseTagList)
+			if err != nil {
+				t.Fatalf("findLatestTagWithRetries: %v", err)
+			}
+			if got != "v1.8.9" {
+				t.Errorf("latest tag = %s, want v1.8.9", got)
+			}
+		})
+	}
+}
+
+func TestParseTagListUnrecognized(t *testing.T) {
+	for _, body := range []string{`{"name": "nah", "nope": ["v1.8.9"]}`, `{"tags": [{"digest": "sha256:aa"}]}`, `["v1.8.9"]`} {
+		tags, err := parseTagList("test", []byte(body))
+		if err != nil {
+			t.Errorf("parseTagList(%s) error: %v", body, err)
+		}
+		if len(tags) != 0 {
+			t.Errorf("parseTagList(%s) = %v, want no tags", body, tags)
+		}
+	}
+	if _, err := parseTagList("test", []byte(`{tags: ["v1.8.9"]}`)); err == nil {
+		t.Errorf("parseTagList of invalid JSON succeeded, want an error")
+	}
+}
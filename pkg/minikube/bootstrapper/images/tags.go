@@ -0,0 +1,510 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/util/retry"
+)
+
+const (
+	// builds a docker v2 repository API call in the format https://k8s.gcr.io/v2/coredns/coredns/tags/list, see tagListURL
+	tagURLTemplate = "https://%s/v2/%s/tags/list"
+
+	// tagLookupAttempts is how many times a tag list page is requested before reverting to the last known good version
+	tagLookupAttempts = 3
+
+	// maxTagPages bounds how many pages of a paginated tag list are followed
+	maxTagPages = 100
+
+	// maxConcurrentTagLookups bounds how many repositories are asked for their latest tag at once
+	maxConcurrentTagLookups = 4
+
+	// prefetchTimeout bounds how long prefetchLatestTags waits for all of its lookups
+	prefetchTimeout = 2 * time.Minute
+)
+
+// tagLookupInterval is the initial wait between tag list requests, increased exponentially on every retry
+var tagLookupInterval = time.Second
+
+// defaultTagLookupTimeout is the tagLookupTimeout used unless SetTagLookupTimeout is given another
+const defaultTagLookupTimeout = 10 * time.Second
+
+// tagLookupTimeout bounds every tag list request, so that a hung registry or proxy doesn't stall start
+var tagLookupTimeout = defaultTagLookupTimeout
+
+// SetTagLookupTimeout sets how long every tag list request may take before reverting to the last known good version, zero restores the default
+func SetTagLookupTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("timeout %s is negative", timeout)
+	}
+	if timeout == 0 {
+		timeout = defaultTagLookupTimeout
+	}
+	tagLookupTimeout = timeout
+	return nil
+}
+
+// tagLookupTransport sends the tag list requests, through the proxy given by HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+var tagLookupTransport http.RoundTripper = newProxyTransport()
+
+// newProxyTransport returns a transport honoring the proxy environment variables, whatever the default transport is
+func newProxyTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	return t
+}
+
+// fixes 13136 by getting the latest image version from the k8s.gcr.io repository instead of hardcoded
+func findLatestTagFromRepository(ctx context.Context, url string, lastKnownGood string) string {
+	tag, err := findLatestTagFromRepositoryE(ctx, url, lastKnownGood)
+	if err != nil {
+		klog.Warningf("Failed to get latest image version for %s, reverting to version %s. Error %v", url, lastKnownGood, err)
+	}
+	return tag
+}
+
+// findLatestTagFromRepositoryE is findLatestTagFromRepository, also returning why lastKnownGood was returned instead of the latest tag.
+// The registry is given up on once ctx is done. Lookups are cached for tagCacheTTL,
+// and an expired cached tag is preferred over lastKnownGood if the registry can't be reached.
+func findLatestTagFromRepositoryE(ctx context.Context, url string, lastKnownGood string) (string, error) {
+	return findLatestTagParsedE(ctx, url, lastKnownGood, parseTagList)
+}
+
+// findLatestTagParsedE is findLatestTagFromRepositoryE, extracting the tags of every tag list page with parse
+func findLatestTagParsedE(ctx context.Context, url string, lastKnownGood string, parse tagParser) (string, error) {
+	if suppressedByPolicy(url, lastKnownGood) {
+		tracef(TraceFallback, url, "tag %s, lookups are suppressed by policy", lastKnownGood)
+		return lastKnownGood, nil
+	}
+	if offline {
+		tracef(TraceFallback, url, "tag %s, offline", lastKnownGood)
+		return lastKnownGood, nil
+	}
+	cached, found, fresh := cachedLatestTag(url)
+	if fresh {
+		tracef(TraceResolved, url, "tag %s, from the tag cache", cached)
+		lookedUpTags.Store(url, cached)
+		return cached, nil
+	}
+	tag, err := findLatestTagWithRetries(ctx, url, lastKnownGood, tagLookupAttempts, parse)
+	if err != nil {
+		if found {
+			klog.Warningf("Failed to refresh latest image version for %s, using cached version %s. Error %v", url, cached, err)
+			tracef(TraceFallback, url, "tag %s, from the tag cache as the lookup failed: %v", cached, err)
+			return cached, nil
+		}
+		tracef(TraceFallback, url, "tag %s, as the lookup failed: %v", tag, err)
+		return tag, err
+	}
+	tracef(TraceResolved, url, "tag %s, looked up", tag)
+	lookedUpTags.Store(url, tag)
+	if err := saveLatestTag(url, tag); err != nil {
+		klog.Warningf("Failed to cache latest image version for %s: %v", url, err)
+	}
+	return tag, nil
+}
+
+// errNoTags is returned by lookups of repositories which list no tags, a misconfigured mirror rather than an unreachable registry
+var errNoTags = errors.New("no tags found")
+
+// findLatestTagWithRetries is findLatestTagParsedE, trying each page up to attempts times on network and server errors
+func findLatestTagWithRetries(ctx context.Context, url string, lastKnownGood string, attempts int, parse tagParser) (string, error) {
+	client := &http.Client{Transport: withRegistryAuth(withCompressionPreference(lookupTransport())), Timeout: tagLookupTimeout}
+
+	var tags []string
+	next := url
+	for page := 0; next != ""; page++ {
+		if page == maxTagPages {
+			return lastKnownGood, fmt.Errorf("tag list exceeds %d pages", maxTagPages)
+		}
+		pageTags, link, err := fetchTagsPage(ctx, client, next, attempts, parse)
+		if err != nil {
+			return lastKnownGood, err
+		}
+		tags = append(tags, pageTags...)
+		next = link
+	}
+
+	if len(tags) < 1 {
+		return lastKnownGood, errNoTags
+	}
+	klog.V(4).Infof("candidate tags of %s: %v", url, tags)
+	tag, err := latestSemverTag(tags, lastKnownGood)
+	if err == nil {
+		klog.V(3).Infof("selected tag %s of %s", tag, url)
+	}
+	return tag, err
+}
+
+// fetchTagsPage returns the tags listed at pageURL as extracted by parse, and the url of the next page if the list is paginated
+func fetchTagsPage(ctx context.Context, client *http.Client, pageURL string, attempts int, parse tagParser) ([]string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "request")
+	}
+	var body []byte
+	var next string
+	fetch := func() error {
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return backoff.Permanent(ctx.Err())
+			}
+			return withKind(ErrRegistryUnreachable, errors.Wrap(err, "registry unreachable"))
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			err := fmt.Errorf("unexpected response status: %s", resp.Status)
+			// only server side errors are worth retrying, anything else (such as 404) will not change
+			if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+				return withKind(ErrRegistryUnreachable, err)
+			}
+			return backoff.Permanent(err)
+		}
+
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return errors.Wrap(err, "reading response")
+		}
+		next, err = nextPage(resp)
+		return backoff.Permanent(err)
+	}
+
+	if attempts < 1 {
+		attempts = 1
+	}
+	if err := retry.Expo(fetch, tagLookupInterval, 2*time.Minute, uint64(attempts-1)); err != nil {
+		return nil, "", err
+	}
+
+	tags, err := parse(pageURL, body)
+	if err != nil {
+		return nil, "", err
+	}
+	return tags, next, nil
+}
+
+// nextPage returns the url of the next page of a paginated response, as given by its `Link: <url>; rel="next"` header
+func nextPage(resp *http.Response) (string, error) {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if rel := strings.TrimPrefix(strings.TrimSpace(param), "rel="); strings.Trim(rel, `"`) != "next" {
+				continue
+			}
+			u, err := url.Parse(strings.Trim(target, "<>"))
+			if err != nil {
+				return "", errors.Wrap(err, "malformed Link header")
+			}
+			return resp.Request.URL.ResolveReference(u).String(), nil
+		}
+	}
+	return "", nil
+}
+
+// lookupContext bounds the lookups of the image helpers which don't take a context, such as Kubeadm
+var lookupContext = context.Background()
+
+// SetLookupContext makes the latest tag lookups of the image helpers give up once ctx is done
+func SetLookupContext(ctx context.Context) {
+	lookupContext = ctx
+}
+
+// offline disables every registry lookup, the last known good tags are used instead
+var offline bool
+
+// SetOffline sets whether latest tag lookups are skipped, so no network requests are made for image versions
+func SetOffline(o bool) {
+	offline = o
+}
+
+// pinnedOnly forbids resolving image versions dynamically, the last known good tags are used instead.
+// Unlike offline, it is a policy rather than a lack of connectivity, so every suppressed lookup is logged.
+var pinnedOnly bool
+
+// SetPinnedOnly sets whether latest tag lookups are suppressed by policy, so only the versions pinned by this release or by flags are used
+func SetPinnedOnly(p bool) {
+	pinnedOnly = p
+}
+
+// profilePinnedOnly is pinnedOnly for the profile whose images are listed, on top of the global policy
+var profilePinnedOnly bool
+
+// SetProfilePinnedOnly sets whether the profile whose images are listed suppresses latest tag lookups, as SetPinnedOnly does for every profile.
+// Lookups are suppressed if either is set.
+func SetProfilePinnedOnly(p bool) {
+	profilePinnedOnly = p
+}
+
+// pinnedVersionsOnly reports whether latest tag lookups are suppressed, globally or for the profile
+func pinnedVersionsOnly() bool {
+	return pinnedOnly || profilePinnedOnly
+}
+
+// suppressedByPolicy reports whether the lookup of url is suppressed by SetPinnedOnly or SetProfilePinnedOnly, logging that tag is used instead for auditing
+func suppressedByPolicy(url string, tag string) bool {
+	if !pinnedVersionsOnly() {
+		return false
+	}
+	klog.Infof("dynamic version resolution of %s suppressed by policy, using %s", url, tag)
+	return true
+}
+
+// includePreReleases allows pre-release and build metadata tags to be picked as the latest tag
+var includePreReleases bool
+
+// SetIncludePreReleaseTags opts in to picking pre-release tags, such as v1.9.0-beta.1, as the latest tag
+func SetIncludePreReleaseTags(include bool) {
+	includePreReleases = include
+}
+
+// isPreRelease reports whether v is a pre-release or carries build metadata.
+// Purely numeric suffixes, such as the -0 of etcd's 3.5.3-0, are image revisions rather than pre-releases.
+func isPreRelease(v semver.Version) bool {
+	if len(v.Build) > 0 {
+		return true
+	}
+	for _, pr := range v.Pre {
+		if !pr.IsNum {
+			return true
+		}
+	}
+	return false
+}
+
+// latestSemverTag returns the highest semver of tags, skipping any tag which isn't a valid version or is a pre-release.
+// Tags are compared as versions with any leading v ignored, so 1.8.10 is later than v1.8.9.
+func latestSemverTag(tags []string, lastKnownGood string) (string, error) {
+	var latest string
+	var latestVersion semver.Version
+	for _, tag := range tags {
+		v, err := semver.ParseTolerant(tag)
+		if err != nil {
+			klog.V(4).Infof("skipping tag %q, not a version: %v", tag, err)
+			continue
+		}
+		if !includePreReleases && isPreRelease(v) {
+			klog.V(4).Infof("skipping pre-release tag %q", tag)
+			continue
+		}
+		if latest == "" || v.GT(latestVersion) {
+			latest = tag
+			latestVersion = v
+		}
+	}
+	if latest == "" {
+		return lastKnownGood, fmt.Errorf("no valid version in tags %v", tags)
+	}
+	return latest, nil
+}
+
+// tagLookup is a lookup of the latest tag listed at url, reverting to lastKnownGood.
+// The tag list is parsed with parseTagList unless parse is set.
+type tagLookup struct {
+	url           string
+	lastKnownGood string
+	parse         tagParser
+}
+
+// findLatestTags looks up the latest tag of every lookup concurrently, using at most maxConcurrentTagLookups workers.
+// The tags are returned in the order of lookups, and lookups not started before ctx is done revert to their lastKnownGood.
+func findLatestTags(ctx context.Context, lookups []tagLookup) []string {
+	tags := make([]string, len(lookups))
+	workers := maxConcurrentTagLookups
+	if len(lookups) < workers {
+		workers = len(lookups)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				parse := lookups[i].parse
+				if parse == nil {
+					parse = parseTagList
+				}
+				tag, err := findLatestTagParsedE(ctx, lookups[i].url, lookups[i].lastKnownGood, parse)
+				if errors.Is(err, errNoTags) {
+					klog.Warningf("using %s for %s, the repository lists no tags", tag, lookups[i].url)
+				} else if err != nil {
+					klog.V(3).Infof("using %s for %s, failed to get latest version: %v", tag, lookups[i].url, err)
+				}
+				tags[i] = tag
+			}
+		}()
+	}
+
+	for i := range lookups {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			tags[i] = lookups[i].lastKnownGood
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return tags
+}
+
+// resolvedTags holds the tags found by prefetchLatestTags, keyed by url
+var resolvedTags sync.Map
+
+// prefetchLatestTags concurrently resolves lookups ahead of latestTag, which would otherwise look them up one at a time
+func prefetchLatestTags(lookups []tagLookup) {
+	if offline || pinnedVersionsOnly() {
+		return
+	}
+	pending := []tagLookup{}
+	for _, l := range lookups {
+		if _, ok := resolvedTags.Load(l.url); !ok {
+			pending = append(pending, l)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(lookupContext, prefetchTimeout)
+	defer cancel()
+	for i, tag := range findLatestTags(ctx, pending) {
+		resolvedTags.Store(pending[i].url, tag)
+	}
+}
+
+// withinMinor returns parse, keeping only the tags with the major and minor version of v
+func withinMinor(parse tagParser, v semver.Version) tagParser {
+	return func(url string, body []byte) ([]string, error) {
+		tags, err := parse(url, body)
+		if err != nil {
+			return nil, err
+		}
+		matching := []string{}
+		for _, tag := range tags {
+			if tv, err := semver.ParseTolerant(tag); err == nil && tv.Major == v.Major && tv.Minor == v.Minor {
+				matching = append(matching, tag)
+			}
+		}
+		return matching, nil
+	}
+}
+
+// tagListURL returns the url of the tag list of imageName in repo. The registry API is served from the host of repo,
+// so the namespace of a repo such as registry.example.com/google_containers leads the repository path after /v2/.
+func tagListURL(repo string, imageName string) string {
+	host, namespace := splitRepo(repo)
+	return fmt.Sprintf(tagURLTemplate, host, path.Join(namespace, imageName))
+}
+
+// expandTagURLTemplate returns the tag list url template with %s standing for the host of repo, moving the namespace of repo after /v2/
+func expandTagURLTemplate(template string, repo string) string {
+	host, namespace := splitRepo(repo)
+	u := fmt.Sprintf(template, host)
+	if namespace == "" {
+		return u
+	}
+	return strings.Replace(u, "/v2/", "/v2/"+namespace+"/", 1)
+}
+
+// splitRepo returns the registry host of repo, and the namespace following it if any
+func splitRepo(repo string) (host string, namespace string) {
+	if i := strings.Index(repo, "/"); i >= 0 {
+		return repo[:i], repo[i+1:]
+	}
+	return repo, ""
+}
+
+// latestPatchTag returns the latest tag of imageName in repo with the major and minor version of pinned, or pinned if there is none.
+// noLookup returns a tag already looked up by this process, or pinned, without any registry lookup.
+func latestPatchTag(repo string, imageName string, pinned string, noLookup bool) string {
+	pv, err := semver.ParseTolerant(pinned)
+	if err != nil || offline {
+		return pinned
+	}
+	url := tagListURL(repo, imageName)
+	key := fmt.Sprintf("%s#v%d.%d", url, pv.Major, pv.Minor)
+	if noLookup {
+		if tag, ok := resolvedTags.Load(key); ok && !pinnedVersionsOnly() {
+			return tag.(string)
+		}
+		return pinned
+	}
+	if suppressedByPolicy(url, pinned) {
+		return pinned
+	}
+	if tag, ok := resolvedTags.Load(key); ok {
+		return tag.(string)
+	}
+	tag, err := findLatestTagWithRetries(lookupContext, url, pinned, tagLookupAttempts, withinMinor(parseTagList, pv))
+	if err != nil {
+		klog.V(3).Infof("using %s:%s, failed to get latest v%d.%d version: %v", imageName, tag, pv.Major, pv.Minor, err)
+		tracef(TraceFallback, url, "tag %s, as the lookup of the latest v%d.%d patch failed: %v", tag, pv.Major, pv.Minor, err)
+	} else {
+		tracef(TraceResolved, url, "tag %s, the latest v%d.%d patch looked up", tag, pv.Major, pv.Minor)
+	}
+	resolvedTags.Store(key, tag)
+	return tag
+}
+
+// latestTag returns the latest tag of imageName in repo, or the tag a cluster of the minor version of v last started with,
+// or else lastKnownGood, if it can't be determined.
+// noLookup returns a tag already looked up by this process, or one of the fallback tags, without any registry lookup.
+func latestTag(v semver.Version, repo string, imageName string, lastKnownGood string, noLookup bool) string {
+	url := tagListURL(repo, imageName)
+	// tags resolved while another profile was configured aren't used by a profile resolving pinned versions only
+	if (noLookup && pinnedVersionsOnly()) || (!noLookup && suppressedByPolicy(url, lastKnownGood)) {
+		tracef(TraceFallback, url, "tag %s, lookups are suppressed by policy", lastKnownGood)
+		return lastKnownGood
+	}
+	if tag, ok := resolvedTags.Load(url); ok {
+		return tag.(string)
+	}
+	lastKnownGood = lastGoodTagFor(url, v, lastKnownGood)
+	if noLookup {
+		tracef(TraceFallback, url, "tag %s, not looked up", lastKnownGood)
+		return lastKnownGood
+	}
+	tag, err := findLatestTagFromRepositoryE(lookupContext, url, lastKnownGood)
+	if errors.Is(err, errNoTags) {
+		klog.Warningf("using %s:%s, %s lists no tags", imageName, tag, repo)
+	} else if err != nil {
+		klog.V(3).Infof("using %s:%s, failed to get latest version: %v", imageName, tag, err)
+	}
+	return tag
+}
@@ -0,0 +1,693 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/klog/v2"
+)
+
+func TestGetLatestTag(t *testing.T) {
+	useTagCache(t, "")
+	serverResp := "{tags: [\"1.8.7\"]}"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(serverResp))
+		if err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	var testCases = []struct {
+		name          string
+		url           string
+		lastKnownGood string
+		wsResponse    string
+		expect        string
+	}{
+		{name: "VersionGetSuccess", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": ["v1.8.9"]}`, expect: "v1.8.9"},
+		{name: "VersionGetFail", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "nah", "nope": ["v1.8.9"]}`, expect: "v1.8.6"},
+		{name: "VersionGetFailNone", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: ``, expect: "v1.8.6"},
+		{name: "VersionGetFailEmptyTags", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": []}`, expect: "v1.8.6"},
+		{name: "VersionGetFailNullTags", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": null}`, expect: "v1.8.6"},
+		{name: "VersionGetFailEmptyTag", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": [""]}`, expect: "v1.8.6"},
+		{name: "VersionGetSuccessMultiple", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": ["1.8.7","v1.8.9"]}`, expect: "v1.8.9"},
+		{name: "VersionGetSuccessDoubleDigitPatch", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": ["v1.8.9","1.8.10","v1.8.2"]}`, expect: "1.8.10"},
+		{name: "VersionGetSuccessDoubleDigitMinor", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": ["v1.10.0","v1.9.3","v1.8.12"]}`, expect: "v1.10.0"},
+		{name: "VersionGetSuccessMalformedMixed", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": ["latest","v1.8.x","1.8.10","v1.8.9-","sha256-abc"]}`, expect: "1.8.10"},
+		{name: "VersionGetFailOnlyMalformed", url: server.URL, lastKnownGood: "v1.8.6", wsResponse: `{"name": "coredns", "tags": ["latest","v1.8.x"]}`, expect: "v1.8.6"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serverResp = tc.wsResponse
+			resp := findLatestTagFromRepository(context.Background(), tc.url, tc.lastKnownGood)
+			if diff := cmp.Diff(tc.expect, resp); diff != "" {
+				t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetLatestTagE(t *testing.T) {
+	serverResp := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serverResp == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if _, err := w.Write([]byte(serverResp)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	var testCases = []struct {
+		name       string
+		url        string
+		wsResponse string
+		expect     string
+		wantErr    string
+	}{
+		{name: "Success", url: server.URL, wsResponse: `{"name": "coredns", "tags": ["v1.8.9"]}`, expect: "v1.8.9"},
+		{name: "Malformed", url: server.URL, wsResponse: `{tags: ["v1.8.9"]}`, expect: "v1.8.6", wantErr: "malformed tag list"},
+		{name: "NoTags", url: server.URL, wsResponse: `{"name": "nah", "nope": ["v1.8.9"]}`, expect: "v1.8.6", wantErr: "no tags found"},
+		{name: "EmptyTags", url: server.URL, wsResponse: `{"name": "coredns", "tags": []}`, expect: "v1.8.6", wantErr: "no tags found"},
+		{name: "NotFound", url: server.URL, wsResponse: "missing", expect: "v1.8.6", wantErr: "404"},
+		{name: "Unreachable", url: unreachable.URL, expect: "v1.8.6", wantErr: "registry unreachable"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serverResp = tc.wsResponse
+			resp, err := findLatestTagWithRetries(context.Background(), tc.url, "v1.8.6", 1, parseTagList)
+			if diff := cmp.Diff(tc.expect, resp); diff != "" {
+				t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+			}
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("got error %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetLatestTagRetries(t *testing.T) {
+	defer func(d time.Duration) { tagLookupInterval = d }(tagLookupInterval)
+	tagLookupInterval = time.Millisecond
+
+	var testCases = []struct {
+		name         string
+		failures     int
+		failStatus   int
+		attempts     int
+		wantRequests int
+		expect       string
+	}{
+		{name: "SucceedAfterTwoFailures", failures: 2, failStatus: http.StatusServiceUnavailable, attempts: 3, wantRequests: 3, expect: "v1.8.9"},
+		{name: "GiveUpAfterAttempts", failures: 5, failStatus: http.StatusServiceUnavailable, attempts: 3, wantRequests: 3, expect: "v1.8.6"},
+		{name: "NoRetryOnNotFound", failures: 5, failStatus: http.StatusNotFound, attempts: 3, wantRequests: 1, expect: "v1.8.6"},
+		{name: "SingleAttempt", failures: 1, failStatus: http.StatusServiceUnavailable, attempts: 1, wantRequests: 1, expect: "v1.8.6"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tc.failures {
+					w.WriteHeader(tc.failStatus)
+					return
+				}
+				if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+					t.Errorf("failed to write https response")
+				}
+			}))
+			defer server.Close()
+
+			resp, _ := findLatestTagWithRetries(context.Background(), server.URL, "v1.8.6", tc.attempts, parseTagList)
+			if diff := cmp.Diff(tc.expect, resp); diff != "" {
+				t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+			}
+			if requests != tc.wantRequests {
+				t.Errorf("got %d requests, want %d", requests, tc.wantRequests)
+			}
+		})
+	}
+}
+
+func TestLatestSemverTag(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		tags        []string
+		preReleases bool
+		expect      string
+	}{
+		{name: "DoubleDigitPatch", tags: []string{"1.8.9", "1.8.10", "1.8.2"}, expect: "1.8.10"},
+		{name: "DoubleDigitMinor", tags: []string{"v1.9.0", "v1.10.0", "v1.8.10"}, expect: "v1.10.0"},
+		{name: "SkipPreRelease", tags: []string{"v1.8.9", "v1.9.0-beta.1", "v1.9.0-rc.0"}, expect: "v1.8.9"},
+		{name: "SkipBuildMetadata", tags: []string{"v1.8.9", "v1.8.10+build.1"}, expect: "v1.8.9"},
+		{name: "IncludePreRelease", tags: []string{"v1.8.9", "v1.9.0-beta.1", "v1.8.10"}, preReleases: true, expect: "v1.9.0-beta.1"},
+		{name: "PreReleaseOrdering", tags: []string{"v1.9.0-beta.2", "v1.9.0-beta.10", "v1.9.0-alpha.3"}, preReleases: true, expect: "v1.9.0-beta.10"},
+		{name: "ReleaseBeatsPreRelease", tags: []string{"v1.9.0-rc.1", "v1.9.0"}, preReleases: true, expect: "v1.9.0"},
+		{name: "NumericRevision", tags: []string{"3.5.1-0", "3.5.3-0", "3.5.10-0", "3.6.0-alpha.0"}, expect: "3.5.10-0"},
+		{name: "OnlyPreReleases", tags: []string{"v1.9.0-beta.1"}, expect: "v1.8.6"},
+		{name: "SkipInvalid", tags: []string{"latest", "v1.8.9", "sha256-abc"}, expect: "v1.8.9"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer SetIncludePreReleaseTags(includePreReleases)
+			SetIncludePreReleaseTags(tc.preReleases)
+
+			got, _ := latestSemverTag(tc.tags, "v1.8.6")
+			if diff := cmp.Diff(tc.expect, got); diff != "" {
+				t.Errorf("Incorrect latest version (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetLatestTagProxyTimeout(t *testing.T) {
+	useTagCache(t, "")
+	defer func(d time.Duration) { tagLookupInterval = d }(tagLookupInterval)
+	defer func(d time.Duration) { tagLookupTimeout = d }(tagLookupTimeout)
+	tagLookupInterval = time.Millisecond
+	tagLookupTimeout = 100 * time.Millisecond
+
+	// a proxy accepting connections but never answering them
+	hung := make(chan struct{})
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer proxy.Close()
+	defer close(hung)
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatalf("parse proxy url: %v", err)
+	}
+	transport := newProxyTransport()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	useTagLookupTransport(t, transport)
+
+	start := time.Now()
+	got, err := findLatestTagFromRepositoryE(context.Background(), "http://registry.invalid/v2/coredns/coredns/tags/list", "v1.8.6")
+	if elapsed := time.Since(start); elapsed > time.Duration(tagLookupAttempts)*tagLookupTimeout+time.Second {
+		t.Errorf("lookup through a hung proxy took %s, want it to time out", elapsed)
+	}
+	if err == nil {
+		t.Errorf("expected an error looking up tags through a hung proxy")
+	}
+	if diff := cmp.Diff("v1.8.6", got); diff != "" {
+		t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetTagLookupTimeout(t *testing.T) {
+	useTagCache(t, "")
+	defer func(d time.Duration) { tagLookupInterval = d }(tagLookupInterval)
+	defer func(d time.Duration) { tagLookupTimeout = d }(tagLookupTimeout)
+	tagLookupInterval = time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    string
+	}{
+		{"short", 20 * time.Millisecond, "v1.8.6"},
+		{"generous", 5 * time.Second, "v1.8.9"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetTagLookupTimeout(tc.timeout); err != nil {
+				t.Fatalf("SetTagLookupTimeout: %v", err)
+			}
+			got := findLatestTagFromRepository(context.Background(), server.URL, "v1.8.6")
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if err := SetTagLookupTimeout(0); err != nil || tagLookupTimeout != defaultTagLookupTimeout {
+		t.Errorf("SetTagLookupTimeout(0) = %v with timeout %s, want the default %s", err, tagLookupTimeout, defaultTagLookupTimeout)
+	}
+	if err := SetTagLookupTimeout(-time.Second); err == nil {
+		t.Errorf("SetTagLookupTimeout of a negative timeout succeeded, want an error")
+	}
+}
+
+func TestGetLatestTagPaginated(t *testing.T) {
+	useTagCache(t, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := `{"name": "coredns/coredns", "tags": ["v1.8.9"]}`
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/coredns/coredns/tags/list?n=2&last=v1.8.6>; rel="next"`)
+			resp = `{"name": "coredns/coredns", "tags": ["v1.8.4", "v1.8.6"]}`
+		}
+		if _, err := w.Write([]byte(resp)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	got, err := findLatestTagFromRepositoryE(context.Background(), server.URL+"/v2/coredns/coredns/tags/list?n=2", "v1.8.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("v1.8.9", got); diff != "" {
+		t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+	}
+}
+
+func TestFindLatestTags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image-tags.json")
+	useTagCache(t, path)
+	const delay = 200 * time.Millisecond
+
+	lookups := []tagLookup{}
+	want := []string{}
+	for i := 0; i < 2*maxConcurrentTagLookups; i++ {
+		tag := fmt.Sprintf("v1.8.%d", i)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			if _, err := fmt.Fprintf(w, `{"name": "coredns", "tags": [%q]}`, tag); err != nil {
+				t.Errorf("failed to write https response")
+			}
+		}))
+		defer server.Close()
+		lookups = append(lookups, tagLookup{url: server.URL, lastKnownGood: "v1.8.0"})
+		want = append(want, tag)
+	}
+
+	start := time.Now()
+	got := findLatestTags(context.Background(), lookups)
+	if elapsed := time.Since(start); elapsed >= time.Duration(len(lookups)-1)*delay {
+		t.Errorf("lookups took %s, want them to run concurrently", elapsed)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Incorrect response versions (-want +got):\n%s", diff)
+	}
+	cache := loadTagCache(path)
+	for i, l := range lookups {
+		if cached := cache[l.url].Tag; cached != want[i] {
+			t.Errorf("cached tag of lookup %d = %q, want %q", i, cached, want[i])
+		}
+	}
+
+	// the cached tags would otherwise be used without looking them up
+	useTagCache(t, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	got = findLatestTags(ctx, lookups)
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("cancelled lookups took %s, want them to stop immediately", elapsed)
+	}
+	for i, tag := range got {
+		if tag != "v1.8.0" {
+			t.Errorf("cancelled lookup %d = %s, want the last known good version", i, tag)
+		}
+	}
+}
+
+func TestGetLatestTagCancelled(t *testing.T) {
+	useTagCache(t, "")
+	defer func(timeout time.Duration) { tagLookupTimeout = timeout }(tagLookupTimeout)
+	tagLookupTimeout = time.Minute
+
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	start := time.Now()
+	got, err := findLatestTagFromRepositoryE(ctx, server.URL, "v1.8.6")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled lookup took %s, want it to return promptly", elapsed)
+	}
+	if got != "v1.8.6" {
+		t.Errorf("cancelled lookup = %s, want the last known good version", got)
+	}
+	if err == nil {
+		t.Errorf("cancelled lookup succeeded, want an error")
+	}
+}
+
+// countingTransport counts the requests made through it, failing all of them
+type countingTransport struct {
+	requests int32
+}
+
+func (c *countingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return nil, fmt.Errorf("unexpected request")
+}
+
+func TestOffline(t *testing.T) {
+	useTagCache(t, "")
+	transport := &countingTransport{}
+	useTagLookupTransport(t, transport)
+	SetOffline(true)
+	defer SetOffline(false)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&transport.requests, 1)
+	}))
+	defer server.Close()
+
+	if got := findLatestTagFromRepository(context.Background(), server.URL, "v1.8.0"); got != "v1.8.0" {
+		t.Errorf("findLatestTagFromRepository() = %s, want the last known good version", got)
+	}
+	if got := findLatestTags(context.Background(), []tagLookup{{url: server.URL, lastKnownGood: "v1.8.0"}}); got[0] != "v1.8.0" {
+		t.Errorf("findLatestTags() = %v, want the last known good version", got)
+	}
+	if _, err := Kubeadm("", "v1.24.0"); err != nil {
+		t.Fatalf("Kubeadm() failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&transport.requests); n != 0 {
+		t.Errorf("made %d requests while offline, want none", n)
+	}
+}
+
+func TestPinnedOnly(t *testing.T) {
+	useTagCache(t, "")
+	transport := &countingTransport{}
+	useTagLookupTransport(t, transport)
+	SetPinnedOnly(true)
+	defer SetPinnedOnly(false)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&transport.requests, 1)
+	}))
+	defer server.Close()
+
+	logs := captureLogs(t, "0")
+	if got := findLatestTagFromRepository(context.Background(), server.URL, "v1.8.0"); got != "v1.8.0" {
+		t.Errorf("findLatestTagFromRepository() = %s, want the last known good version", got)
+	}
+	if _, err := Kubeadm("", "v1.24.0"); err != nil {
+		t.Fatalf("Kubeadm() failed: %v", err)
+	}
+	klog.Flush()
+	if n := atomic.LoadInt32(&transport.requests); n != 0 {
+		t.Errorf("made %d requests with pinned versions only, want none", n)
+	}
+	want := "dynamic version resolution of " + server.URL + " suppressed by policy, using v1.8.0"
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("logs don't contain %q:\n%s", want, logs.String())
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	var testCases = []struct {
+		link   string
+		expect string
+	}{
+		{link: "", expect: ""},
+		{link: `</v2/coredns/tags/list?last=b>; rel="next"`, expect: "https://k8s.gcr.io/v2/coredns/tags/list?last=b"},
+		{link: `<https://other.io/v2/coredns/tags/list?last=b>; rel=next`, expect: "https://other.io/v2/coredns/tags/list?last=b"},
+		{link: `</v2/coredns/tags/list?last=b>; rel="last"`, expect: ""},
+		{link: `</v2/coredns/tags/list?last=a>; rel="prev", </v2/coredns/tags/list?last=c>; rel="next"`, expect: "https://k8s.gcr.io/v2/coredns/tags/list?last=c"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.link, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "https://k8s.gcr.io/v2/coredns/tags/list", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := &http.Response{Header: http.Header{}, Request: req}
+			resp.Header.Set("Link", tc.link)
+			got, err := nextPage(resp)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expect {
+				t.Errorf("nextPage() = %q, want %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestGetLatestTagLogsCandidates(t *testing.T) {
+	useTagCache(t, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9", "latest", "v1.9.0-rc.1"]}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	logs := captureLogs(t, "4")
+	got := findLatestTagFromRepository(context.Background(), server.URL, "v1.8.6")
+	klog.Flush()
+	if got != "v1.8.9" {
+		t.Errorf("findLatestTagFromRepository() = %s, want v1.8.9", got)
+	}
+	for _, want := range []string{
+		"candidate tags of " + server.URL + ": [v1.8.9 latest v1.9.0-rc.1]",
+		`skipping tag "latest", not a version`,
+		`skipping pre-release tag "v1.9.0-rc.1"`,
+		"selected tag v1.8.9 of " + server.URL,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs don't contain %q:\n%s", want, logs.String())
+		}
+	}
+}
+
+// captureLogs returns the buffer klog writes to at verbosity until the end of the test
+func captureLogs(t *testing.T, verbosity string) *bytes.Buffer {
+	var logs bytes.Buffer
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	if err := fs.Set("v", verbosity); err != nil {
+		t.Fatalf("set verbosity: %v", err)
+	}
+	if err := fs.Set("logtostderr", "false"); err != nil {
+		t.Fatalf("set logtostderr: %v", err)
+	}
+	klog.SetOutput(&logs)
+	t.Cleanup(func() {
+		klog.SetOutput(os.Stderr)
+		_ = fs.Set("v", "0")
+		_ = fs.Set("logtostderr", "true")
+	})
+	return &logs
+}
+
+func TestGetLatestTagsEmptyTagsWarns(t *testing.T) {
+	useTagCache(t, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": []}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	logs := captureLogs(t, "0")
+	got := findLatestTags(context.Background(), []tagLookup{{url: server.URL, lastKnownGood: "v1.8.6"}})
+	klog.Flush()
+	if diff := cmp.Diff([]string{"v1.8.6"}, got); diff != "" {
+		t.Errorf("findLatestTags() mismatch (-want +got):\n%s", diff)
+	}
+	want := "using v1.8.6 for " + server.URL + ", the repository lists no tags"
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("logs don't contain %q:\n%s", want, logs.String())
+	}
+}
+
+func TestGetLatestTagWithinMinor(t *testing.T) {
+	serverResp := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(serverResp)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	defer server.Close()
+
+	var testCases = []struct {
+		name       string
+		wsResponse string
+		expect     string
+	}{
+		{name: "NewestPatch", wsResponse: `{"name": "coredns", "tags": ["v1.8.4", "v1.8.7", "v1.9.0", "v1.8.6"]}`, expect: "v1.8.7"},
+		{name: "DoubleDigitPatch", wsResponse: `{"name": "coredns", "tags": ["v1.8.9", "v1.8.10", "v1.10.1"]}`, expect: "v1.8.10"},
+		{name: "SkipPreRelease", wsResponse: `{"name": "coredns", "tags": ["v1.8.6", "v1.8.7-rc.1", "v1.9.0"]}`, expect: "v1.8.6"},
+		{name: "NoneInMinor", wsResponse: `{"name": "coredns", "tags": ["v1.9.0", "v1.7.1"]}`, expect: "v1.8.6"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serverResp = tc.wsResponse
+			got, _ := findLatestTagWithRetries(context.Background(), server.URL, "v1.8.6", 1, withinMinor(parseTagList, semver.MustParse("1.8.6")))
+			if got != tc.expect {
+				t.Errorf("findLatestTagWithRetries() = %s, want %s", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestCoreDNSLatestPatchOffline(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+	SetCoreDNSLatestPatch(true)
+	defer SetCoreDNSLatestPatch(false)
+
+	if got := CoreDNSVersion(semver.MustParse("1.24.0")); got != "v1.8.6" {
+		t.Errorf("CoreDNSVersion(v1.24.0) = %s, want the pinned v1.8.6", got)
+	}
+}
+
+func TestTagListURL(t *testing.T) {
+	tests := []struct {
+		repo      string
+		imageName string
+		want      string
+	}{
+		{"k8s.gcr.io", "coredns/coredns", "https://k8s.gcr.io/v2/coredns/coredns/tags/list"},
+		{"registry.corp", "etcd", "https://registry.corp/v2/etcd/tags/list"},
+		{"registry.cn-hangzhou.aliyuncs.com/google_containers", "coredns/coredns", "https://registry.cn-hangzhou.aliyuncs.com/v2/google_containers/coredns/coredns/tags/list"},
+		{"localhost:5000/team/k8s", "pause", "https://localhost:5000/v2/team/k8s/pause/tags/list"},
+	}
+	for _, tc := range tests {
+		if got := tagListURL(tc.repo, tc.imageName); got != tc.want {
+			t.Errorf("tagListURL(%q, %q) = %q, want %q", tc.repo, tc.imageName, got, tc.want)
+		}
+	}
+}
+
+func TestGetLatestTagNamespacedRepository(t *testing.T) {
+	useTagCache(t, "")
+	defer SetInsecureRegistries(nil)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/google_containers/coredns/coredns/tags/list" {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := w.Write([]byte(`{"name": "google_containers/coredns/coredns", "tags": ["v1.8.6", "v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write response")
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	SetInsecureRegistries([]string{host})
+
+	repo := host + "/google_containers"
+	if got := latestTag(semver.MustParse("1.24.0"), repo, "coredns/coredns", "v1.8.0", false); got != "v1.8.9" {
+		t.Errorf("latestTag(%s, coredns/coredns) = %s, want v1.8.9", repo, got)
+	}
+
+	lookups := NewTagLookups()
+	if err := lookups.Register("coredns", TagLookup{URLTemplate: "https://%s/v2/coredns/coredns/tags/list", LastKnownGood: "v1.8.0"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if got := lookups.ResolveAll(context.Background(), repo)["coredns"]; got != "v1.8.9" {
+		t.Errorf("ResolveAll(%s) resolved coredns to %s, want v1.8.9", repo, got)
+	}
+}
+
+func TestProfilePinnedOnly(t *testing.T) {
+	useTagCache(t, "")
+	defer SetInsecureRegistries(nil)
+	SetAllowUnknownVersions(true)
+	defer SetAllowUnknownVersions(false)
+	t.Cleanup(func() {
+		resolvedTags.Range(func(url, _ interface{}) bool {
+			resolvedTags.Delete(url)
+			return true
+		})
+	})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/etcd/tags/list") {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := w.Write([]byte(`{"name": "etcd", "tags": ["3.5.0-0", "3.5.9-0"]}`)); err != nil {
+			t.Errorf("failed to write response")
+		}
+	}))
+	defer server.Close()
+	mirror := strings.TrimPrefix(server.URL, "https://")
+	SetInsecureRegistries([]string{mirror})
+
+	// the profiles are configured in turn, as starting each of them would
+	profiles := []struct {
+		name   string
+		pinned bool
+		want   string
+	}{
+		{"online", false, mirror + "/etcd:3.5.9-0"},
+		{"air-gapped", true, mirror + "/etcd:" + defaultEtcdVersion},
+		{"online again", false, mirror + "/etcd:3.5.9-0"},
+	}
+	for _, p := range profiles {
+		t.Run(p.name, func(t *testing.T) {
+			SetProfilePinnedOnly(p.pinned)
+			defer SetProfilePinnedOnly(false)
+			imgs, err := Kubeadm(mirror, "v1.99.0")
+			if err != nil {
+				t.Fatalf("Kubeadm: %v", err)
+			}
+			found := false
+			for _, img := range imgs {
+				found = found || img == p.want
+			}
+			if !found {
+				t.Errorf("Kubeadm() = %v, want %s", imgs, p.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// SetRegistryTLS configures the tag lookups to present the client certificate certFile and keyFile to registries,
+// and to trust the registries signed by the CA bundle caFile. Empty paths restore the defaults.
+func SetRegistryTLS(certFile string, keyFile string, caFile string) error {
+	cfg, err := registryTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		return err
+	}
+	t := newProxyTransport()
+	if cfg != nil {
+		t.TLSClientConfig = cfg
+	}
+	setRegistryTransport(t)
+	return nil
+}
+
+// registryTLSConfig returns the TLS config for the given client certificate and CA bundle, or nil if none is given
+func registryTLSConfig(certFile string, keyFile string, caFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("registry client certificate and key must be given together")
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading registry client certificate")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading registry CA bundle")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in registry CA bundle %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// insecureRegistries are the registries, as host[:port] or CIDR, whose tags are looked up without TLS verification
+var insecureRegistries []string
+
+// SetInsecureRegistries allows the tags of registries, given as host[:port] or CIDR, to be looked up over plain HTTP
+// or without verifying their certificate. Every other registry is only reached over verified HTTPS.
+func SetInsecureRegistries(registries []string) {
+	insecureRegistries = registries
+	setRegistryTransport(tagLookupTransport)
+}
+
+// isInsecureRegistry reports whether host, which may carry a port, is one of insecureRegistries
+func isInsecureRegistry(host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	ip := net.ParseIP(hostname)
+	for _, r := range insecureRegistries {
+		if r == host || r == hostname {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(r); err == nil && ip != nil && cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupTransport returns the rate limited transport of tag lookups, reaching insecureRegistries with an insecureRegistryTransport
+func lookupTransport() http.RoundTripper {
+	return withRateLimit(registryTransport())
+}
+
+// registryTransport returns the transport reaching registries with the TLS settings of SetRegistryTLS and SetInsecureRegistries
+func registryTransport() http.RoundTripper {
+	return registryRoundTripper
+}
+
+// registryRoundTripper is the transport returned by registryTransport, built by setRegistryTransport whenever the TLS settings change
+var registryRoundTripper = tagLookupTransport
+
+// setRegistryTransport sends the registry requests with secure, reaching insecureRegistries with an insecureRegistryTransport built around it
+func setRegistryTransport(secure http.RoundTripper) {
+	tagLookupTransport = secure
+	if len(insecureRegistries) == 0 {
+		registryRoundTripper = secure
+		return
+	}
+	insecure := newProxyTransport()
+	if t, ok := secure.(*http.Transport); ok {
+		insecure = t.Clone()
+	}
+	if insecure.TLSClientConfig == nil {
+		insecure.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	insecure.TLSClientConfig.InsecureSkipVerify = true // only used for the registries opted in to with SetInsecureRegistries
+	registryRoundTripper = &insecureRegistryTransport{secure: secure, insecure: insecure}
+}
+
+// insecureRegistryTransport sends the requests to insecure registries without verifying their certificate,
+// retrying them over plain HTTP if the registry doesn't speak TLS. Requests to other registries are sent by secure.
+type insecureRegistryTransport struct {
+	secure   http.RoundTripper
+	insecure http.RoundTripper
+}
+
+// RoundTrip sends req
+func (t *insecureRegistryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isInsecureRegistry(req.URL.Host) {
+		return t.secure.RoundTrip(req)
+	}
+	resp, err := t.insecure.RoundTrip(req)
+	if err == nil || req.URL.Scheme != "https" || req.Context().Err() != nil {
+		return resp, err
+	}
+	klog.V(3).Infof("retrying %s over plain HTTP: %v", req.URL, err)
+	plain := req.Clone(req.Context())
+	plain.URL.Scheme = "http"
+	return t.insecure.RoundTrip(plain)
+}
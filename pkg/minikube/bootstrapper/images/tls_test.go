@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// useTagLookupTransport sends the registry requests with rt for the duration of the test
+func useTagLookupTransport(t *testing.T, rt http.RoundTripper) {
+	t.Helper()
+	orig := tagLookupTransport
+	setRegistryTransport(rt)
+	t.Cleanup(func() { setRegistryTransport(orig) })
+}
+
+// writeClientCert writes a self-signed client certificate and its key to dir, returning their paths and the certificate
+func writeClientCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "minikube"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestGetLatestTagMutualTLS(t *testing.T) {
+	useTagCache(t, "")
+	defer func(d time.Duration) { tagLookupInterval = d }(tagLookupInterval)
+	useTagLookupTransport(t, tagLookupTransport)
+	tagLookupInterval = time.Millisecond
+
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeClientCert(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write https response")
+		}
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatalf("write CA bundle: %v", err)
+	}
+
+	if err := SetRegistryTLS("", "", caFile); err != nil {
+		t.Fatalf("SetRegistryTLS: %v", err)
+	}
+	if _, err := findLatestTagWithRetries(context.Background(), server.URL, "v1.8.6", 1, parseTagList); err == nil {
+		t.Errorf("expected the registry to reject a lookup without a client certificate")
+	}
+
+	if err := SetRegistryTLS(certFile, keyFile, caFile); err != nil {
+		t.Fatalf("SetRegistryTLS: %v", err)
+	}
+	got, err := findLatestTagWithRetries(context.Background(), server.URL, "v1.8.6", 1, parseTagList)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("v1.8.9", got); diff != "" {
+		t.Errorf("Incorrect response version (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetRegistryTLSInvalid(t *testing.T) {
+	useTagLookupTransport(t, tagLookupTransport)
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeClientCert(t, dir)
+
+	tests := []struct {
+		name     string
+		cert     string
+		key      string
+		ca       string
+		wantFail bool
+	}{
+		{"none", "", "", "", false},
+		{"cert-and-key", certFile, keyFile, "", false},
+		{"cert-without-key", certFile, "", "", true},
+		{"key-without-cert", "", keyFile, "", true},
+		{"missing-ca", "", "", filepath.Join(dir, "missing.crt"), true},
+		{"ca-without-certificates", "", "", keyFile, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetRegistryTLS(tc.cert, tc.key, tc.ca); (err != nil) != tc.wantFail {
+				t.Errorf("SetRegistryTLS() = %v, want error: %t", err, tc.wantFail)
+			}
+		})
+	}
+}
+
+func TestGetLatestTagInsecureRegistry(t *testing.T) {
+	useTagCache(t, "")
+	defer SetInsecureRegistries(nil)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(`{"name": "coredns", "tags": ["v1.8.9"]}`)); err != nil {
+			t.Errorf("failed to write response")
+		}
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	selfSigned := httptest.NewTLSServer(handler)
+	defer selfSigned.Close()
+
+	host := func(s *httptest.Server) string {
+		return strings.TrimPrefix(strings.TrimPrefix(s.URL, "http://"), "https://")
+	}
+	tests := []struct {
+		name     string
+		server   *httptest.Server
+		insecure []string
+		want     string
+	}{
+		{"plain http unlisted", plain, nil, "v1.8.6"},
+		{"plain http listed", plain, []string{host(plain)}, "v1.8.9"},
+		{"plain http listed cidr", plain, []string{"127.0.0.0/8"}, "v1.8.9"},
+		{"self-signed unlisted", selfSigned, nil, "v1.8.6"},
+		{"self-signed other host listed", selfSigned, []string{host(plain)}, "v1.8.6"},
+		{"self-signed listed", selfSigned, []string{host(selfSigned)}, "v1.8.9"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetInsecureRegistries(tc.insecure)
+			url := fmt.Sprintf(tagURLTemplate, host(tc.server), "coredns/coredns")
+			got, _ := findLatestTagWithRetries(context.Background(), url, "v1.8.6", 1, parseTagList)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Incorrect latest version (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRegistryTransportBuiltOnce(t *testing.T) {
+	useTagLookupTransport(t, tagLookupTransport)
+	defer SetInsecureRegistries(nil)
+
+	if got := registryTransport(); got != tagLookupTransport {
+		t.Errorf("registryTransport() without insecure registries = %T, want tagLookupTransport", got)
+	}
+	SetInsecureRegistries([]string{"registry.corp"})
+	insecure := registryTransport()
+	if _, ok := insecure.(*insecureRegistryTransport); !ok {
+		t.Fatalf("registryTransport() with insecure registries = %T, want an insecureRegistryTransport", insecure)
+	}
+	if got := registryTransport(); got != insecure {
+		t.Errorf("registryTransport() built a new transport, want the one built by SetInsecureRegistries")
+	}
+
+	if err := SetRegistryTLS("", "", ""); err != nil {
+		t.Fatalf("SetRegistryTLS: %v", err)
+	}
+	rebuilt, ok := registryTransport().(*insecureRegistryTransport)
+	if !ok || rebuilt == insecure || rebuilt.secure != tagLookupTransport {
+		t.Errorf("registryTransport() after SetRegistryTLS = %v, want an insecureRegistryTransport around the new tagLookupTransport", registryTransport())
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/util/lock"
+)
+
+// TraceDecision is the kind of decision resolving the images a TraceEntry records
+type TraceDecision string
+
+const (
+	// TraceVersion is the decision of which image tags the kubeadm images table pins for the Kubernetes version
+	TraceVersion TraceDecision = "version"
+	// TraceRepository is the decision of which repository, mirror or default, images are pulled from
+	TraceRepository TraceDecision = "repository"
+	// TracePinned is the decision to use a tag pinned by an override or the kubeadm images table
+	TracePinned TraceDecision = "pinned"
+	// TraceResolved is the decision to use a tag looked up in the image repository
+	TraceResolved TraceDecision = "resolved"
+	// TraceFallback is the decision to use a known good tag, as the latest tag wasn't or couldn't be looked up
+	TraceFallback TraceDecision = "fallback"
+)
+
+// TraceEntry is a decision made resolving the images, about Image if it concerns a single image
+type TraceEntry struct {
+	Decision TraceDecision `json:"decision"`
+	Image    string        `json:"image,omitempty"`
+	Detail   string        `json:"detail"`
+}
+
+// ImageTrace is every decision made resolving the images since tracing was enabled, in order
+type ImageTrace struct {
+	Entries []TraceEntry `json:"entries"`
+}
+
+// tracing is 1 while decisions are traced, checked without locking so that untraced resolutions don't pay for it
+var tracing int32
+
+// traceMu guards traceEntries and tracePath
+var traceMu sync.Mutex
+
+// traceEntries are the decisions traced since SetTracing enabled tracing
+var traceEntries []TraceEntry
+
+// tracePath, if set, is the file the trace is written to after every traced decision
+var tracePath string
+
+// SetTracing sets whether every decision made resolving the images is traced, clearing the trace
+func SetTracing(enabled bool) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	traceEntries = nil
+	if enabled {
+		atomic.StoreInt32(&tracing, 1)
+	} else {
+		atomic.StoreInt32(&tracing, 0)
+	}
+}
+
+// traceEnabled returns whether decisions are traced
+func traceEnabled() bool {
+	return atomic.LoadInt32(&tracing) == 1
+}
+
+// tracef traces the decision about image, with a detail formatted as by fmt.Sprintf, if tracing is enabled
+func tracef(decision TraceDecision, image string, format string, args ...interface{}) {
+	if !traceEnabled() {
+		return
+	}
+	e := TraceEntry{Decision: decision, Image: image, Detail: fmt.Sprintf(format, args...)}
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	traceEntries = append(traceEntries, e)
+	if tracePath != "" {
+		if err := writeTrace(tracePath, traceEntries); err != nil {
+			klog.Warningf("unable to write the image resolution trace: %v", err)
+		}
+	}
+}
+
+// SetTraceFile writes the trace to path, replacing what it held, and again after every traced decision,
+// so that the trace is kept even if minikube exits without returning. An empty path stops writing the trace.
+func SetTraceFile(path string) error {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	tracePath = path
+	if path == "" {
+		return nil
+	}
+	return writeTrace(path, traceEntries)
+}
+
+// Trace returns the decisions traced since tracing was enabled
+func Trace() ImageTrace {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	return ImageTrace{Entries: append([]TraceEntry{}, traceEntries...)}
+}
+
+// WriteTrace writes Trace to path as JSON, for it to be attached to issues about the images minikube picked
+func WriteTrace(path string) error {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	return writeTrace(path, traceEntries)
+}
+
+// writeTrace writes the trace of entries to path, the caller holds traceMu
+func writeTrace(path string, entries []TraceEntry) error {
+	data, err := json.MarshalIndent(ImageTrace{Entries: append([]TraceEntry{}, entries...)}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "mkdir")
+	}
+	return lock.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTracePinnedVersion(t *testing.T) {
+	SetTracing(true)
+	defer SetTracing(false)
+
+	if _, err := KubeadmWithOptions("", "v1.22.0", ImageOptions{NoStorageProvisioner: true}); err != nil {
+		t.Fatalf("KubeadmWithOptions: %v", err)
+	}
+	want := []TraceEntry{
+		{Decision: TraceVersion, Detail: "Kubernetes v1.22.0 matched the image tags pinned for v1.22"},
+		{Decision: TraceRepository, Detail: "pulling from k8s.gcr.io, the default repository of Kubernetes v1.22.0"},
+		{Decision: TracePinned, Image: "pause", Detail: "tag 3.5, from the kubeadm images table"},
+		{Decision: TracePinned, Image: "etcd", Detail: "tag 3.5.0-0, from the kubeadm images table"},
+		{Decision: TracePinned, Image: "coredns/coredns", Detail: "tag v1.8.4, from the kubeadm images table"},
+	}
+	if diff := cmp.Diff(want, Trace().Entries); diff != "" {
+		t.Errorf("trace mismatch (-want +got):\n%s", diff)
+	}
+
+	SetTracing(true)
+	if err := SetEtcdVersion("3.5.3-0"); err != nil {
+		t.Fatalf("SetEtcdVersion: %v", err)
+	}
+	defer func() {
+		if err := SetEtcdVersion(""); err != nil {
+			t.Fatalf("reset etcd version: %v", err)
+		}
+	}()
+	if _, err := KubeadmWithOptions("https://mirror.corp/k8s/", "v1.22.0", ImageOptions{NoStorageProvisioner: true}); err != nil {
+		t.Fatalf("KubeadmWithOptions: %v", err)
+	}
+	want[1] = TraceEntry{Decision: TraceRepository, Detail: "pulling from the mirror mirror.corp/k8s"}
+	want[3] = TraceEntry{Decision: TracePinned, Image: "etcd", Detail: "tag 3.5.3-0, overridden"}
+	if diff := cmp.Diff(want, Trace().Entries); diff != "" {
+		t.Errorf("trace with a mirror mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTraceUnpinnedVersion(t *testing.T) {
+	useTagCache(t, "")
+	useLastGoodTags(t, "")
+	SetAllowUnknownVersions(true)
+	defer SetAllowUnknownVersions(false)
+	SetOffline(true)
+	defer SetOffline(false)
+	SetTracing(true)
+	defer SetTracing(false)
+
+	v := semver.MustParse("1.99.0")
+	essentials("", v)
+
+	got := map[TraceDecision][]string{}
+	for _, e := range Trace().Entries {
+		got[e.Decision] = append(got[e.Decision], e.Image)
+	}
+	want := map[TraceDecision][]string{
+		TraceVersion:    {""},
+		TraceRepository: {""},
+		TraceFallback: {
+			tagListURL(RegistryK8sIORepo, "pause"),
+			tagListURL(RegistryK8sIORepo, "etcd"),
+			tagListURL(RegistryK8sIORepo, "coredns/coredns"),
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("traced decisions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTraceDisabled(t *testing.T) {
+	SetTracing(false)
+	essentials("", semver.MustParse("1.22.0"))
+	if got := Trace().Entries; len(got) != 0 {
+		t.Errorf("traced %v while tracing is disabled, want nothing", got)
+	}
+}
+
+func TestWriteTrace(t *testing.T) {
+	SetTracing(true)
+	defer SetTracing(false)
+	essentials("", semver.MustParse("1.22.0"))
+
+	path := filepath.Join(t.TempDir(), "logs", "imageTrace.json")
+	if err := WriteTrace(path); err != nil {
+		t.Fatalf("WriteTrace: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read trace: %v", err)
+	}
+	var got ImageTrace
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal trace: %v", err)
+	}
+	if diff := cmp.Diff(Trace(), got); diff != "" {
+		t.Errorf("written trace mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetTraceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "imageTrace.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"entries":[{"decision":"pinned","detail":"previous start"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	read := func() ImageTrace {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read trace: %v", err)
+		}
+		var got ImageTrace
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("unmarshal trace: %v", err)
+		}
+		return got
+	}
+
+	SetTracing(true)
+	defer SetTracing(false)
+	if err := SetTraceFile(path); err != nil {
+		t.Fatalf("SetTraceFile: %v", err)
+	}
+	defer func() {
+		if err := SetTraceFile(""); err != nil {
+			t.Errorf("reset trace file: %v", err)
+		}
+	}()
+	if got := read(); len(got.Entries) != 0 {
+		t.Errorf("trace of a previous start was kept: %+v", got)
+	}
+
+	// written without WriteTrace, as a start exiting on an error never returns to write it
+	essentials("", semver.MustParse("1.22.0"))
+	if diff := cmp.Diff(Trace(), read()); diff != "" {
+		t.Errorf("written trace mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// maxConcurrentImageChecks is the most manifests VerifyImagesExist requests at once
+const maxConcurrentImageChecks = 8
+
+// VerifyImagesExist requests the manifest of every image from its registry, returning the images which don't exist.
+// Registries are reached with the same proxy and TLS settings as latest tag lookups.
+func VerifyImagesExist(ctx context.Context, images []string) ([]string, error) {
+	return VerifyImagesExistForArch(ctx, images, "")
+}
+
+// VerifyImagesExistForArch is VerifyImagesExist, also returning the images whose manifest list has no manifest for arch,
+// or whose manifest for arch doesn't exist. An empty arch accepts any manifest list.
+func VerifyImagesExistForArch(ctx context.Context, images []string, arch string) ([]string, error) {
+	return VerifyImagesExistWithProgress(ctx, images, arch, nil)
+}
+
+// ImageCheck is the result of checking that a single image exists
+type ImageCheck struct {
+	Image   string
+	Missing bool
+	// Err is why the image couldn't be checked, such as ctx being done before its check started
+	Err error
+}
+
+// ProgressFunc is told the result of every image check as it completes
+type ProgressFunc func(check ImageCheck)
+
+// VerifyImagesExistWithProgress is VerifyImagesExistForArch, calling progress exactly once for every image as its check completes.
+// The checks run concurrently, but progress is never called concurrently, so it needs no locking of its own. A nil progress is not called.
+func VerifyImagesExistWithProgress(ctx context.Context, images []string, arch string, progress ProgressFunc) ([]string, error) {
+	if offline {
+		return nil, errors.New("can't verify images in offline mode")
+	}
+	if err := validateArch(arch); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	reported := make([]bool, len(images))
+	report := func(i int, check ImageCheck) {
+		if progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		reported[i] = true
+		progress(check)
+	}
+
+	missing := make([]bool, len(images))
+	errs := make([]error, len(images))
+	runBounded(ctx, len(images), func(i int) {
+		missing[i], errs[i] = imageMissingForArch(ctx, images[i], arch)
+		report(i, ImageCheck{Image: images[i], Missing: missing[i], Err: errs[i]})
+	})
+	if err := ctx.Err(); err != nil {
+		for i, img := range images {
+			if progress != nil && !reported[i] {
+				report(i, ImageCheck{Image: img, Err: err})
+			}
+		}
+		return nil, err
+	}
+
+	result := []string{}
+	for i, img := range images {
+		if errs[i] != nil {
+			return nil, errors.Wrapf(errs[i], "checking %s", img)
+		}
+		if missing[i] {
+			result = append(result, img)
+		}
+	}
+	return result, nil
+}
+
+// runBounded calls fn for every index below n, using at most maxConcurrentImageChecks workers.
+// Indexes not started before ctx is done are skipped.
+func runBounded(ctx context.Context, n int, fn func(i int)) {
+	workers := maxConcurrentImageChecks
+	if n < workers {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// headImage returns the descriptor the registry of img serves for it, without fetching the manifest
+func headImage(ctx context.Context, img string) (*v1.Descriptor, error) {
+	ref, err := name.ParseReference(img, name.WeakValidation)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse reference")
+	}
+	return remote.Head(ref, remoteOptions(ctx)...)
+}
+
+// remoteOptions returns the options of the registry requests, reaching registries with the settings of latest tag lookups
+func remoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithTransport(withCompressionPreference(lookupTransport())),
+		remote.WithAuthFromKeychain(registryKeychain()),
+	}
+}
+
+// registryError returns err as an ErrRegistryUnreachable if the registry couldn't be reached, or failed on its side
+func registryError(ctx context.Context, err error) error {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		if terr.StatusCode >= http.StatusInternalServerError || terr.StatusCode == http.StatusTooManyRequests {
+			return withKind(ErrRegistryUnreachable, err)
+		}
+		return err
+	}
+	var berr *name.ErrBadName
+	if errors.As(err, &berr) || ctx.Err() != nil {
+		return err
+	}
+	// the registry ping flattens connection failures and server errors into a plain error
+	return withKind(ErrRegistryUnreachable, err)
+}
+
+// imageMissing reports whether the registry of img says it has no manifest for img
+func imageMissing(ctx context.Context, img string) (bool, error) {
+	return imageMissingForArch(ctx, img, "")
+}
+
+// imageMissingForArch reports whether the registry of img has no manifest for img, or no manifest for arch in the manifest list of img
+func imageMissingForArch(ctx context.Context, img string, arch string) (bool, error) {
+	desc, err := headImage(ctx, img)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			klog.V(3).Infof("image %s is missing: %v", img, err)
+			return true, nil
+		}
+		return false, registryError(ctx, err)
+	}
+	if arch == "" || !desc.MediaType.IsIndex() {
+		return false, nil
+	}
+
+	ref, err := name.ParseReference(img, name.WeakValidation)
+	if err != nil {
+		return false, errors.Wrap(err, "parse reference")
+	}
+	index, err := remote.Index(ref, remoteOptions(ctx)...)
+	if err != nil {
+		return false, errors.Wrap(err, "manifest list")
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return false, errors.Wrap(err, "manifest list")
+	}
+	for _, m := range manifest.Manifests {
+		if m.Platform == nil || m.Platform.Architecture != arch {
+			continue
+		}
+		return imageMissing(ctx, ref.Context().Digest(m.Digest.String()).String())
+	}
+	klog.V(3).Infof("image %s has no %s manifest", img, arch)
+	return true, nil
+}
+
+// MirroredImage is an image pulled from a mirror, along with the upstream image it is a copy of
+type MirroredImage struct {
+	Image    string
+	Upstream string
+}
+
+// DigestMismatch is a mirrored image whose digest differs from the digest of its upstream image
+type DigestMismatch struct {
+	MirroredImage
+	Digest         string
+	UpstreamDigest string
+}
+
+// MirroredImages returns every image of ImagesForVersion which mirror rewrites, paired with its upstream image
+func MirroredImages(mirror string, k8sVersion semver.Version, opts ImageOptions) ([]MirroredImage, error) {
+	rewrites, err := ImageRewrites(mirror, k8sVersion, opts)
+	if err != nil {
+		return nil, err
+	}
+	mirrored := []MirroredImage{}
+	for _, r := range rewrites {
+		if r.Rewritten() {
+			mirrored = append(mirrored, MirroredImage{Image: r.Final, Upstream: r.Original})
+		}
+	}
+	return mirrored, nil
+}
+
+// VerifyMirroredDigests requests the digest of every mirrored image and of its upstream image, returning the images whose digests differ.
+// This surfaces a stale or tampered mirror before its images are deployed.
+func VerifyMirroredDigests(ctx context.Context, images []MirroredImage) ([]DigestMismatch, error) {
+	if offline {
+		return nil, errors.New("can't verify mirrored images in offline mode")
+	}
+
+	refs := make([]string, 0, 2*len(images))
+	for _, img := range images {
+		refs = append(refs, img.Image, img.Upstream)
+	}
+	digests := make([]string, len(refs))
+	errs := make([]error, len(refs))
+	runBounded(ctx, len(refs), func(i int) {
+		desc, err := headImage(ctx, refs[i])
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		digests[i] = desc.Digest.String()
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := []DigestMismatch{}
+	for i, img := range images {
+		for _, j := range []int{2 * i, 2*i + 1} {
+			if errs[j] != nil {
+				return nil, errors.Wrapf(errs[j], "checking %s", refs[j])
+			}
+		}
+		if digests[2*i] != digests[2*i+1] {
+			klog.Warningf("%s has digest %s, but upstream %s has digest %s", img.Image, digests[2*i], img.Upstream, digests[2*i+1])
+			result = append(result, DigestMismatch{MirroredImage: img, Digest: digests[2*i], UpstreamDigest: digests[2*i+1]})
+		}
+	}
+	return result, nil
+}
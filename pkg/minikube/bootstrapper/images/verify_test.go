@@ -0,0 +1,295 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVerifyImagesExist(t *testing.T) {
+	present := map[string]bool{
+		"/v2/kube-apiserver/manifests/v1.24.0": true,
+		"/v2/coredns/coredns/manifests/v1.8.6": true,
+	}
+	server := newManifestRegistry(present)
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "http://")
+	imgs := []string{
+		registry + "/kube-apiserver:v1.24.0",
+		registry + "/etcd:3.5.3-0",
+		registry + "/coredns/coredns:v1.8.6",
+		registry + "/pause:3.7",
+	}
+	got, err := VerifyImagesExist(context.Background(), imgs)
+	if err != nil {
+		t.Fatalf("VerifyImagesExist: %v", err)
+	}
+	want := []string{registry + "/etcd:3.5.3-0", registry + "/pause:3.7"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("missing images mismatch (-want +got):\n%s", diff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := VerifyImagesExist(ctx, imgs); err == nil {
+		t.Errorf("VerifyImagesExist with a cancelled context succeeded, want an error")
+	}
+}
+
+// newManifestRegistry returns a registry serving a manifest list at every path of present, and nothing else
+func newManifestRegistry(present map[string]bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			return
+		}
+		if !present[r.URL.Path] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.list.v2+json")
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("a", 64))
+		w.Header().Set("Content-Length", "0")
+	}))
+}
+
+func TestVerifyImagesExistWithProgress(t *testing.T) {
+	useRateLimit(t, 1000)
+	present := map[string]bool{}
+	imgs := []string{}
+	wantChecks := map[string]bool{}
+	server := newManifestRegistry(present)
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+	for i := 0; i < 3*maxConcurrentImageChecks; i++ {
+		p := fmt.Sprintf("/v2/image-%d/manifests/v1.0.0", i)
+		img := fmt.Sprintf("%s/image-%d:v1.0.0", registry, i)
+		present[p] = i%3 != 0
+		imgs = append(imgs, img)
+		wantChecks[img] = i%3 == 0
+	}
+
+	gotChecks := map[string]bool{}
+	calls := 0
+	missing, err := VerifyImagesExistWithProgress(context.Background(), imgs, "", func(check ImageCheck) {
+		calls++
+		if check.Err != nil {
+			t.Errorf("check of %s failed: %v", check.Image, check.Err)
+		}
+		if _, ok := gotChecks[check.Image]; ok {
+			t.Errorf("progress called more than once for %s", check.Image)
+		}
+		gotChecks[check.Image] = check.Missing
+	})
+	if err != nil {
+		t.Fatalf("VerifyImagesExistWithProgress: %v", err)
+	}
+	if calls != len(imgs) {
+		t.Errorf("progress called %d times, want %d", calls, len(imgs))
+	}
+	if diff := cmp.Diff(wantChecks, gotChecks); diff != "" {
+		t.Errorf("per-image results mismatch (-want +got):\n%s", diff)
+	}
+	wantMissing := []string{}
+	for _, img := range imgs {
+		if wantChecks[img] {
+			wantMissing = append(wantMissing, img)
+		}
+	}
+	if diff := cmp.Diff(wantMissing, missing); diff != "" {
+		t.Errorf("missing images mismatch (-want +got):\n%s", diff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	if _, err := VerifyImagesExistWithProgress(ctx, imgs, "", func(check ImageCheck) {
+		calls++
+		if check.Err == nil {
+			t.Errorf("check of %s with a cancelled context succeeded, want an error", check.Image)
+		}
+	}); err == nil {
+		t.Errorf("VerifyImagesExistWithProgress with a cancelled context succeeded, want an error")
+	}
+	if calls != len(imgs) {
+		t.Errorf("progress called %d times with a cancelled context, want %d", calls, len(imgs))
+	}
+}
+
+// newManifestListRegistry returns a registry serving a manifest list of the manifests of archs at the paths of lists,
+// and the manifests of the digests of manifests
+func newManifestListRegistry(lists map[string][]string, manifests map[string]bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			return
+		}
+		if ref := path.Base(r.URL.Path); manifests[ref] {
+			w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+			w.Header().Set("Docker-Content-Digest", ref)
+			w.Header().Set("Content-Length", "0")
+			return
+		}
+		archs, ok := lists[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		entries := []string{}
+		for _, arch := range archs {
+			entries = append(entries, fmt.Sprintf(`{"mediaType": "application/vnd.docker.distribution.manifest.v2+json", "size": 1, "digest": "%s", "platform": {"architecture": "%s", "os": "linux"}}`, archDigest(arch), arch))
+		}
+		body := fmt.Sprintf(`{"schemaVersion": 2, "mediaType": "application/vnd.docker.distribution.manifest.list.v2+json", "manifests": [%s]}`, strings.Join(entries, ", "))
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.list.v2+json")
+		w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(body))))
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		if r.Method != http.MethodHead {
+			fmt.Fprint(w, body)
+		}
+	}))
+}
+
+// archDigest returns the digest of the manifest of arch served by newManifestListRegistry
+func archDigest(arch string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(arch)))
+}
+
+func TestVerifyImagesExistForArch(t *testing.T) {
+	useRateLimit(t, 1000)
+	server := newManifestListRegistry(map[string][]string{
+		"/v2/pause/manifests/3.7":    {"amd64"},
+		"/v2/etcd/manifests/3.5.3-0": {"amd64", "arm64"},
+	}, map[string]bool{
+		archDigest("amd64"): true,
+	})
+	defer server.Close()
+
+	registry := strings.TrimPrefix(server.URL, "http://")
+	imgs := []string{registry + "/pause:3.7", registry + "/etcd:3.5.3-0"}
+	tests := []struct {
+		arch string
+		want []string
+	}{
+		{"", []string{}},
+		{"amd64", []string{}},
+		// the arm64 manifest of etcd is listed, but missing from the registry
+		{"arm64", []string{registry + "/pause:3.7", registry + "/etcd:3.5.3-0"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.arch, func(t *testing.T) {
+			got, err := VerifyImagesExistForArch(context.Background(), imgs, tc.arch)
+			if err != nil {
+				t.Fatalf("VerifyImagesExistForArch: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("missing images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := VerifyImagesExistForArch(context.Background(), imgs, "mips"); err == nil {
+		t.Errorf("VerifyImagesExistForArch with an unsupported architecture succeeded, want an error")
+	}
+}
+
+// newDigestRegistry returns a registry serving the manifests at the paths of digests, with the given digest
+func newDigestRegistry(digests map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			return
+		}
+		d, ok := digests[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.list.v2+json")
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat(d, 64))
+		w.Header().Set("Content-Length", "0")
+	}))
+}
+
+func TestVerifyMirroredDigests(t *testing.T) {
+	upstream := newDigestRegistry(map[string]string{
+		"/v2/kube-apiserver/manifests/v1.24.0": "a",
+		"/v2/etcd/manifests/3.5.3-0":           "b",
+		"/v2/pause/manifests/3.7":              "c",
+	})
+	defer upstream.Close()
+	mirror := newDigestRegistry(map[string]string{
+		"/v2/kube-apiserver/manifests/v1.24.0": "a",
+		"/v2/etcd/manifests/3.5.3-0":           "d",
+		"/v2/pause/manifests/3.7":              "c",
+	})
+	defer mirror.Close()
+
+	up := strings.TrimPrefix(upstream.URL, "http://")
+	mi := strings.TrimPrefix(mirror.URL, "http://")
+	imgs := []MirroredImage{}
+	for _, img := range []string{"kube-apiserver:v1.24.0", "etcd:3.5.3-0", "pause:3.7"} {
+		imgs = append(imgs, MirroredImage{Image: mi + "/" + img, Upstream: up + "/" + img})
+	}
+
+	got, err := VerifyMirroredDigests(context.Background(), imgs)
+	if err != nil {
+		t.Fatalf("VerifyMirroredDigests: %v", err)
+	}
+	want := []DigestMismatch{{
+		MirroredImage:  imgs[1],
+		Digest:         "sha256:" + strings.Repeat("d", 64),
+		UpstreamDigest: "sha256:" + strings.Repeat("b", 64),
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("digest mismatches (-want +got):\n%s", diff)
+	}
+
+	missing := []MirroredImage{{Image: mi + "/coredns:v1.8.6", Upstream: up + "/coredns:v1.8.6"}}
+	if _, err := VerifyMirroredDigests(context.Background(), missing); err == nil {
+		t.Errorf("VerifyMirroredDigests of a missing image succeeded, want an error")
+	}
+}
+
+func TestMirroredImages(t *testing.T) {
+	useTagCache(t, "")
+	SetOffline(true)
+	defer SetOffline(false)
+
+	got, err := MirroredImages("mirror.example.com", semver.MustParse("1.24.0"), ImageOptions{})
+	if err != nil {
+		t.Fatalf("MirroredImages: %v", err)
+	}
+	if len(got) == 0 {
+		t.Fatalf("MirroredImages returned no images")
+	}
+	for _, img := range got {
+		if !strings.HasPrefix(img.Image, "mirror.example.com/") {
+			t.Errorf("image %s isn't from the mirror", img.Image)
+		}
+		if strings.HasPrefix(img.Upstream, "mirror.example.com/") {
+			t.Errorf("upstream %s of %s is the mirror", img.Upstream, img.Image)
+		}
+	}
+}
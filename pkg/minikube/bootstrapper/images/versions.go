@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	// goembed needs this
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// versionsJSON is the kubeadm images table, mapping Kubernetes minor versions (e.g. "v1.24") to the tags of their images.
+// It is updated by `make update-kubeadm-constants`. Where upstream published coredns with a different tag on an
+// architecture, that tag is listed as "<image>@<arch>" (e.g. "coredns/coredns@arm64") next to the common tag.
+//
+//go:embed versions.json
+var versionsJSON []byte
+
+// kubeadmImages is the kubeadm images table loaded from versionsJSON
+var kubeadmImages = mustLoadVersions(versionsJSON)
+
+// minorVersion is the major.minor of a Kubernetes version
+type minorVersion struct {
+	major uint64
+	minor uint64
+}
+
+// kubeadmImagesByMinor is kubeadmImages keyed by minorVersion, so that the tags of a version are found without formatting its minor
+var kubeadmImagesByMinor = indexByMinor(kubeadmImages)
+
+// indexByMinor returns versions keyed by the minorVersion of their key
+func indexByMinor(versions map[string]map[string]string) map[minorVersion]map[string]string {
+	index := map[minorVersion]map[string]string{}
+	for minor, tags := range versions {
+		var m minorVersion
+		if _, err := fmt.Sscanf(minor, "v%d.%d", &m.major, &m.minor); err != nil {
+			continue
+		}
+		index[m] = tags
+	}
+	return index
+}
+
+// minorVersionRe matches the Kubernetes minor versions keying the kubeadm images table
+var minorVersionRe = regexp.MustCompile(`^v[0-9]+\.[0-9]+$`)
+
+// mustLoadVersions returns the kubeadm images table of data, panicking if it is malformed as minikube can't pick images without it
+func mustLoadVersions(data []byte) map[string]map[string]string {
+	versions, err := loadVersions(data)
+	if err != nil {
+		panic(errors.Wrap(err, "embedded versions.json"))
+	}
+	return versions
+}
+
+// loadVersions parses the kubeadm images table data, returning an error if any minor version or image tag is malformed
+func loadVersions(data []byte) (map[string]map[string]string, error) {
+	versions := map[string]map[string]string{}
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, errors.Wrap(err, "parse")
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no versions")
+	}
+	minors := []string{}
+	for minor := range versions {
+		minors = append(minors, minor)
+	}
+	sort.Strings(minors)
+	for _, minor := range minors {
+		if !minorVersionRe.MatchString(minor) {
+			return nil, fmt.Errorf("invalid version %q: expected v<major>.<minor>", minor)
+		}
+		if len(versions[minor]) == 0 {
+			return nil, fmt.Errorf("version %s has no images", minor)
+		}
+		for imageName, tag := range versions[minor] {
+			if imageName == "" {
+				return nil, fmt.Errorf("version %s has an image without a name", minor)
+			}
+			if base, arch, ok := strings.Cut(imageName, "@"); ok {
+				if err := validateArchTag(versions[minor], base, arch); err != nil {
+					return nil, errors.Wrapf(err, "version %s image %s", minor, imageName)
+				}
+			}
+			if err := ValidateTag(tag); err != nil {
+				return nil, errors.Wrapf(err, "version %s image %s", minor, imageName)
+			}
+		}
+	}
+	return versions, nil
+}
+
+// validateArchTag returns an error if the tag of imageName on arch can't be listed in tags
+func validateArchTag(tags map[string]string, imageName string, arch string) error {
+	if path.Base(imageName) != "coredns" {
+		return fmt.Errorf("only coredns tags can be qualified by architecture")
+	}
+	if !supportedArchs[arch] {
+		return fmt.Errorf("unsupported architecture: %q", arch)
+	}
+	if _, ok := tags[imageName]; !ok {
+		return fmt.Errorf("no common tag for the other architectures to fall back to")
+	}
+	return nil
+}
+
+// archTag returns the tag tags lists for imageName on arch, falling back to the tag common to every architecture
+func archTag(tags map[string]string, imageName string, arch string) (string, bool) {
+	if tag, ok := tags[imageName+"@"+arch]; ok && arch != "" {
+		return tag, true
+	}
+	tag, ok := tags[imageName]
+	return tag, ok
+}
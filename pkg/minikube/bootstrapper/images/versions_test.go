@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEmbeddedVersions(t *testing.T) {
+	versions, err := loadVersions(versionsJSON)
+	if err != nil {
+		t.Fatalf("loadVersions(versions.json): %v", err)
+	}
+	want := map[string]map[string]string{
+		"v1.18": {"coredns": "1.6.7", "etcd": "3.4.3-0", "pause": "3.2"},
+		"v1.19": {"coredns": "1.7.0", "etcd": "3.4.9-1", "pause": "3.2"},
+		"v1.20": {"coredns": "1.7.0", "etcd": "3.4.13-0", "pause": "3.2"},
+		"v1.21": {"coredns/coredns": "v1.8.0", "etcd": "3.4.13-0", "pause": "3.4.1"},
+		"v1.22": {"coredns/coredns": "v1.8.4", "etcd": "3.5.0-0", "pause": "3.5"},
+	}
+	for minor, images := range want {
+		if diff := cmp.Diff(images, versions[minor]); diff != "" {
+			t.Errorf("%s images mismatch (-want +got):\n%s", minor, diff)
+		}
+	}
+}
+
+func TestLoadVersionsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"NotJSON", `{"v1.24": `},
+		{"Empty", `{}`},
+		{"WrongShape", `{"v1.24": ["3.5.3-0"]}`},
+		{"BadMinor", `{"1.24": {"etcd": "3.5.3-0"}}`},
+		{"PatchVersion", `{"v1.24.1": {"etcd": "3.5.3-0"}}`},
+		{"NoImages", `{"v1.24": {}}`},
+		{"NoImageName", `{"v1.24": {"": "3.5.3-0"}}`},
+		{"BadTag", `{"v1.24": {"etcd": "latest"}}`},
+		{"WhitespaceTag", `{"v1.24": {"etcd": "3.5.3-0 "}}`},
+		{"ArchTagNotCoreDNS", `{"v1.24": {"etcd": "3.5.3-0", "etcd@arm64": "3.5.2-0"}}`},
+		{"ArchTagUnsupportedArch", `{"v1.24": {"coredns/coredns": "v1.8.6", "coredns/coredns@mips": "v1.8.5"}}`},
+		{"ArchTagWithoutCommonTag", `{"v1.24": {"coredns/coredns@arm64": "v1.8.5"}}`},
+		{"ArchTagBadTag", `{"v1.24": {"coredns/coredns": "v1.8.6", "coredns/coredns@arm64": "latest"}}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := loadVersions([]byte(tc.data)); err == nil {
+				t.Errorf("loadVersions(%s) expected an error", tc.data)
+			}
+		})
+	}
+}
+
+// useVersions replaces the kubeadm images table by data for the duration of the test
+func useVersions(t *testing.T, data string) {
+	t.Helper()
+	versions, err := loadVersions([]byte(data))
+	if err != nil {
+		t.Fatalf("loadVersions(%s): %v", data, err)
+	}
+	resetTemplates := func() {
+		essentialTemplates.Range(func(key, _ interface{}) bool {
+			essentialTemplates.Delete(key)
+			return true
+		})
+	}
+	saved, savedByMinor := kubeadmImages, kubeadmImagesByMinor
+	kubeadmImages, kubeadmImagesByMinor = versions, indexByMinor(versions)
+	resetTemplates()
+	t.Cleanup(func() {
+		kubeadmImages, kubeadmImagesByMinor = saved, savedByMinor
+		resetTemplates()
+	})
+}
+
+func TestArchTag(t *testing.T) {
+	tags := map[string]string{"coredns/coredns": "v1.8.0", "coredns/coredns@arm64": "v1.7.1", "etcd": "3.4.13-0"}
+	tests := []struct {
+		imageName string
+		arch      string
+		want      string
+		found     bool
+	}{
+		{"coredns/coredns", "arm64", "v1.7.1", true},
+		{"coredns/coredns", "amd64", "v1.8.0", true},
+		{"coredns/coredns", "", "v1.8.0", true},
+		{"etcd", "arm64", "3.4.13-0", true},
+		{"pause", "arm64", "", false},
+	}
+	for _, tc := range tests {
+		got, found := archTag(tags, tc.imageName, tc.arch)
+		if got != tc.want || found != tc.found {
+			t.Errorf("archTag(%s, %q) = %q, %t, want %q, %t", tc.imageName, tc.arch, got, found, tc.want, tc.found)
+		}
+	}
+}
+
+func TestMustLoadVersionsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("mustLoadVersions of malformed data didn't panic")
+		}
+	}()
+	mustLoadVersions([]byte(`{"v1.24": {"etcd": "latest"}}`))
+}
@@ -742,7 +742,7 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 	// This can fail during upgrades if the old pods have not shut down yet
 	addonPhase := func() error {
 		addons := "all"
-		if cfg.KubernetesConfig.ExtraOptions.Exists("kubeadm.skip-phases=addon/kube-proxy") {
+		if config.SkipsKubeProxy(cfg) {
 			addons = "coredns"
 		}
 		_, err := k.c.RunCmd(exec.Command("/bin/bash", "-c", fmt.Sprintf("%s phase addon %s --config %s", baseCmd, addons, conf)))
@@ -897,12 +897,28 @@ func (k *Bootstrapper) SetupCerts(k8s config.ClusterConfig, n config.Node) error
 	return bootstrapper.SetupCerts(k.c, k8s, n)
 }
 
+// checkKubeadmImages warns about the essential images kubeadm names differently from minikube for the image repository of cfg,
+// as kubeadm would then pull images which minikube neither cached nor preloaded
+func checkKubeadmImages(cfg config.ClusterConfig) {
+	essentials, derived, err := images.KubeadmConfigImages(cfg.KubernetesConfig.ImageRepository, cfg.KubernetesConfig.KubernetesVersion)
+	if err != nil {
+		klog.Warningf("unable to check the images kubeadm derives: %v", err)
+		return
+	}
+	for i := 0; i < len(essentials) && i < len(derived); i++ {
+		if essentials[i] != derived[i] {
+			klog.Warningf("kubeadm derives %s for the image repository, while minikube pulls %s", derived[i], essentials[i])
+		}
+	}
+}
+
 // UpdateCluster updates the control plane with cluster-level info.
 func (k *Bootstrapper) UpdateCluster(cfg config.ClusterConfig) error {
-	images, err := images.Kubeadm(cfg.KubernetesConfig.ImageRepository, cfg.KubernetesConfig.KubernetesVersion)
+	images, err := images.KubeadmForCluster(cfg, cfg.KubernetesConfig.KubernetesVersion)
 	if err != nil {
 		return errors.Wrap(err, "kubeadm images")
 	}
+	checkKubeadmImages(cfg)
 
 	version, err := util.ParseKubernetesVersion(cfg.KubernetesConfig.KubernetesVersion)
 	if err != nil {
@@ -68,6 +68,11 @@ func (c Calico) manifest() (assets.CopyableFile, error) {
 	return manifestAsset(b.Bytes()), nil
 }
 
+// Images returns the images of the Calico manifest
+func (c Calico) Images() ([]string, error) {
+	return assetImages(c.manifest())
+}
+
 // Apply enables the CNI
 func (c Calico) Apply(r Runner) error {
 	m, err := c.manifest()
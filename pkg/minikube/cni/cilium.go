@@ -23,7 +23,9 @@ import (
 
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
 )
 
 // From https://raw.githubusercontent.com/cilium/cilium/v1.9/install/kubernetes/quick-install.yaml
@@ -155,7 +157,8 @@ data:
   auto-direct-node-routes: "false"
   enable-bandwidth-manager: "false"
   enable-local-redirect-policy: "false"
-  kube-proxy-replacement:  "probe"
+  # kube-proxy isn't deployed, see ReplacesKubeProxy
+  kube-proxy-replacement:  "strict"
   kube-proxy-replacement-healthz-bind-address: ""
   enable-health-check-nodeport: "true"
   node-port-bind-protection: "true"
@@ -472,6 +475,10 @@ spec:
           successThreshold: 1
           timeoutSeconds: 5
         env:
+        - name: KUBERNETES_SERVICE_HOST
+          value: "{{ .APIServerHost }}"
+        - name: KUBERNETES_SERVICE_PORT
+          value: "{{ .APIServerPort }}"
         - name: K8S_NODE_NAME
           valueFrom:
             fieldRef:
@@ -508,7 +515,7 @@ spec:
               key: custom-cni-conf
               name: cilium-config
               optional: true
-        image: "quay.io/cilium/cilium:v1.9.9@sha256:a85d5cff13f8231c2e267d9fc3c6e43d24be4a75dac9f641c11ec46e7f17624d"
+        image: "{{ .CiliumImage }}"
         imagePullPolicy: IfNotPresent
         lifecycle:
           postStart:
@@ -570,7 +577,7 @@ spec:
           # same directory where we install cilium cni plugin so that exec permissions
           # are available.
           - 'cp /usr/bin/cilium-mount /hostbin/cilium-mount && nsenter --cgroup=/hostproc/1/ns/cgroup --mount=/hostproc/1/ns/mnt "${BIN_PATH}/cilium-mount" $CGROUP_ROOT; rm /hostbin/cilium-mount'
-        image: "quay.io/cilium/cilium:v1.9.9@sha256:a85d5cff13f8231c2e267d9fc3c6e43d24be4a75dac9f641c11ec46e7f17624d"
+        image: "{{ .CiliumImage }}"
         imagePullPolicy: IfNotPresent
         volumeMounts:
           - mountPath: /hostproc
@@ -582,6 +589,10 @@ spec:
       - command:
         - /init-container.sh
         env:
+        - name: KUBERNETES_SERVICE_HOST
+          value: "{{ .APIServerHost }}"
+        - name: KUBERNETES_SERVICE_PORT
+          value: "{{ .APIServerPort }}"
         - name: CILIUM_ALL_STATE
           valueFrom:
             configMapKeyRef:
@@ -600,7 +611,7 @@ spec:
               key: wait-bpf-mount
               name: cilium-config
               optional: true
-        image: "quay.io/cilium/cilium:v1.9.9@sha256:a85d5cff13f8231c2e267d9fc3c6e43d24be4a75dac9f641c11ec46e7f17624d"
+        image: "{{ .CiliumImage }}"
         imagePullPolicy: IfNotPresent
         name: clean-cilium-state
         securityContext:
@@ -746,6 +757,10 @@ spec:
         command:
         - cilium-operator-generic
         env:
+        - name: KUBERNETES_SERVICE_HOST
+          value: "{{ .APIServerHost }}"
+        - name: KUBERNETES_SERVICE_PORT
+          value: "{{ .APIServerPort }}"
         - name: K8S_NODE_NAME
           valueFrom:
             fieldRef:
@@ -762,7 +777,7 @@ spec:
               key: debug
               name: cilium-config
               optional: true
-        image: "quay.io/cilium/operator-generic:v1.9.9@sha256:3726a965cd960295ca3c5e7f2b543c02096c0912c6652eb8bbb9ce54bcaa99d8"
+        image: "{{ .CiliumOperatorImage }}"
         imagePullPolicy: IfNotPresent
         name: cilium-operator
         livenessProbe:
@@ -808,17 +823,46 @@ func (c Cilium) CIDR() string {
 	return DefaultPodCIDR
 }
 
+// ReplacesKubeProxy returns true, as Cilium handles service routing itself.
+// The manifest runs kube-proxy replacement in strict mode, reaching the apiserver through the control plane alias
+// instead of the kubernetes service, which nothing routes until Cilium is up.
+func (c Cilium) ReplacesKubeProxy() bool {
+	return true
+}
+
+// Images returns the images of the Cilium manifest
+func (c Cilium) Images() ([]string, error) {
+	manifest, err := GenerateCiliumYAML(c.cc)
+	if err != nil {
+		return nil, errors.Wrap(err, "manifest")
+	}
+	return workloadImages(manifest)
+}
+
 // GenerateCiliumYAML generates the .yaml file
-func GenerateCiliumYAML() ([]byte, error) {
+func GenerateCiliumYAML(cc config.ClusterConfig) ([]byte, error) {
 
 	podCIDR := DefaultPodCIDR
 
 	klog.Infof("Using pod CIDR: %s", podCIDR)
 
+	apiServerPort := constants.APIServerPort
+	if cp, err := config.PrimaryControlPlane(&cc); err == nil && cp.Port > 0 {
+		apiServerPort = cp.Port
+	}
+
 	opts := struct {
-		PodSubnet string
+		PodSubnet           string
+		CiliumImage         string
+		CiliumOperatorImage string
+		APIServerHost       string
+		APIServerPort       int
 	}{
-		PodSubnet: podCIDR,
+		PodSubnet:           podCIDR,
+		CiliumImage:         images.Cilium(""),
+		CiliumOperatorImage: images.CiliumOperator(""),
+		APIServerHost:       constants.ControlPlaneAlias,
+		APIServerPort:       apiServerPort,
 	}
 
 	b := bytes.Buffer{}
@@ -839,7 +883,7 @@ func (c Cilium) Apply(r Runner) error {
 		return errors.Wrap(err, "bpf mount")
 	}
 
-	ciliumCfg, err := GenerateCiliumYAML()
+	ciliumCfg, err := GenerateCiliumYAML(c.cc)
 	if err != nil {
 		return errors.Wrap(err, "generating cilium cfg")
 	}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os/exec"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/blang/semver/v4"
@@ -59,6 +60,9 @@ var (
 	Network = ""
 )
 
+// kubeProxyPhase is the kubeadm phase deploying kube-proxy, which is skipped for CNIs replacing it
+const kubeProxyPhase = "addon/kube-proxy"
+
 // Runner is the subset of command.Runner this package consumes
 type Runner interface {
 	RunCmd(cmd *exec.Cmd) (*command.RunResult, error)
@@ -77,6 +81,19 @@ type Manager interface {
 	String() string
 }
 
+// ProxyReplacer is implemented by CNIs which provide service routing themselves, in place of kube-proxy
+type ProxyReplacer interface {
+	// ReplacesKubeProxy returns whether kube-proxy should be left out of the cluster
+	ReplacesKubeProxy() bool
+}
+
+// ImageLister is implemented by CNIs which list their images from the manifest they apply, so that the images
+// pulled ahead of time are those deployed
+type ImageLister interface {
+	// Images returns the image of every container and init container of the manifest of the CNI
+	Images() ([]string, error)
+}
+
 // tmplInputs are inputs to CNI templates
 type tmplInput struct {
 	ImageName    string
@@ -119,6 +136,10 @@ func New(cc *config.ClusterConfig) (Manager, error) {
 		klog.Errorf("unable to set CNI Config Directory: %v", err)
 	}
 
+	if err := configureKubeProxy(cc, cnm); err != nil {
+		klog.Errorf("unable to disable kube-proxy: %v", err)
+	}
+
 	return cnm, err
 }
 
@@ -238,3 +259,32 @@ func configureCNI(cc *config.ClusterConfig, cnm Manager) error {
 	}
 	return nil
 }
+
+// configureKubeProxy skips the kube-proxy addon for CNIs replacing it, so that it is neither deployed nor its image pulled.
+// The phase is added to the kubeadm phases the user already skips, as kubeadm only reads the first skip-phases option.
+func configureKubeProxy(cc *config.ClusterConfig, cnm Manager) error {
+	pr, ok := cnm.(ProxyReplacer)
+	if !ok || !pr.ReplacesKubeProxy() {
+		return nil
+	}
+	for i, opt := range cc.KubernetesConfig.ExtraOptions {
+		if opt.Component != "kubeadm" || opt.Key != "skip-phases" {
+			continue
+		}
+		for _, phase := range strings.Split(opt.Value, ",") {
+			if strings.TrimSpace(phase) == kubeProxyPhase {
+				return nil
+			}
+		}
+		opt.Value = strings.Join([]string{opt.Value, kubeProxyPhase}, ",")
+		klog.Infof("auto-setting extra-config to %q", opt.String())
+		cc.KubernetesConfig.ExtraOptions[i] = opt
+		return nil
+	}
+	eo := "kubeadm.skip-phases=" + kubeProxyPhase
+	klog.Infof("auto-setting extra-config to %q", eo)
+	if err := cc.KubernetesConfig.ExtraOptions.Set(eo); err != nil {
+		return fmt.Errorf("failed auto-setting extra-config %q: %v", eo, err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"io"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/config"
+)
+
+// imageExpression matches the image of a container in a manifest
+var imageExpression = regexp.MustCompile(`(?m)^\s*image:\s*"?([^"\s]+)"?\s*$`)
+
+// manifestImages returns the sorted unique images referenced by manifest
+func manifestImages(manifest []byte) []string {
+	seen := map[string]bool{}
+	imgs := []string{}
+	for _, m := range imageExpression.FindAllSubmatch(manifest, -1) {
+		if img := string(m[1]); !seen[img] {
+			seen[img] = true
+			imgs = append(imgs, img)
+		}
+	}
+	sort.Strings(imgs)
+	return imgs
+}
+
+func clusterConfig(repo string) config.ClusterConfig {
+	return config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{ImageRepository: repo}}
+}
+
+func TestManifestImages(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest func(repo string) ([]byte, error)
+	}{
+		{"calico", func(repo string) ([]byte, error) {
+			f, err := Calico{cc: clusterConfig(repo)}.manifest()
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(f)
+		}},
+		{"kindnet", func(repo string) ([]byte, error) {
+			f, err := KindNet{cc: clusterConfig(repo)}.manifest()
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(f)
+		}},
+		{"cilium", func(repo string) ([]byte, error) {
+			return GenerateCiliumYAML(clusterConfig(repo))
+		}},
+	}
+	for _, tc := range tests {
+		for _, repo := range []string{"", "registry.corp/mirror"} {
+			t.Run(tc.name+"/"+repo, func(t *testing.T) {
+				manifest, err := tc.manifest(repo)
+				if err != nil {
+					t.Fatalf("manifest: %v", err)
+				}
+				want, err := images.CNIImages(tc.name, repo)
+				if err != nil {
+					t.Fatalf("CNIImages: %v", err)
+				}
+				sort.Strings(want)
+				if diff := cmp.Diff(want, manifestImages(manifest)); diff != "" {
+					t.Errorf("CNIImages doesn't match the manifest images (-CNIImages +manifest):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestFlannelManifestImages(t *testing.T) {
+	// the flannel manifest holds a DaemonSet per architecture, of which only the host one is scheduled
+	want, err := images.CNIImages("flannel", "")
+	if err != nil {
+		t.Fatalf("CNIImages: %v", err)
+	}
+	for _, img := range manifestImages([]byte(flannelTmpl)) {
+		if strings.HasSuffix(img, "-"+runtime.GOARCH) {
+			if diff := cmp.Diff(want, []string{img}); diff != "" {
+				t.Errorf("CNIImages doesn't match the manifest image (-CNIImages +manifest):\n%s", diff)
+			}
+			return
+		}
+	}
+	t.Errorf("flannel manifest has no %s image", runtime.GOARCH)
+}
+
+func TestConfigureKubeProxy(t *testing.T) {
+	tests := []struct {
+		name  string
+		cnm   Manager
+		extra config.ExtraOptionSlice
+		want  config.ExtraOptionSlice
+	}{
+		{"kube-proxy kept", KindNet{}, nil, nil},
+		{"skipped", Cilium{}, nil, config.ExtraOptionSlice{{Component: "kubeadm", Key: "skip-phases", Value: "addon/kube-proxy"}}},
+		{
+			"merged with skipped phases",
+			Cilium{},
+			config.ExtraOptionSlice{{Component: "kubelet", Key: "max-pods", Value: "20"}, {Component: "kubeadm", Key: "skip-phases", Value: "preflight"}},
+			config.ExtraOptionSlice{{Component: "kubelet", Key: "max-pods", Value: "20"}, {Component: "kubeadm", Key: "skip-phases", Value: "preflight,addon/kube-proxy"}},
+		},
+		{
+			"already skipped",
+			Cilium{},
+			config.ExtraOptionSlice{{Component: "kubeadm", Key: "skip-phases", Value: "preflight, addon/kube-proxy"}},
+			config.ExtraOptionSlice{{Component: "kubeadm", Key: "skip-phases", Value: "preflight, addon/kube-proxy"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{ExtraOptions: tc.extra}}
+			if err := configureKubeProxy(&cc, tc.cnm); err != nil {
+				t.Fatalf("configureKubeProxy: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, cc.KubernetesConfig.ExtraOptions); diff != "" {
+				t.Errorf("extra options mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCiliumKubeProxyReplacement(t *testing.T) {
+	cc := clusterConfig("")
+	cc.Nodes = []config.Node{{Name: "m01", ControlPlane: true, Port: 9443}}
+	manifest, err := GenerateCiliumYAML(cc)
+	if err != nil {
+		t.Fatalf("GenerateCiliumYAML: %v", err)
+	}
+	for _, want := range []string{
+		`kube-proxy-replacement:  "strict"`,
+		`value: "control-plane.minikube.internal"`,
+		`value: "9443"`,
+	} {
+		if !strings.Contains(string(manifest), want) {
+			t.Errorf("cilium manifest doesn't contain %s", want)
+		}
+	}
+}
@@ -27,6 +27,7 @@ import (
 )
 
 // From https://raw.githubusercontent.com/coreos/flannel/master/Documentation/kube-flannel.yml
+// Note: the flannel image version should match images.Flannel
 var flannelTmpl = `---
 apiVersion: policy/v1beta1
 kind: PodSecurityPolicy
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/config"
+)
+
+func init() {
+	images.SetCNIImageLister(listManifestImages)
+}
+
+// manifestImagesCache holds the images of the manifests already parsed, keyed by CNI and mirror, as the bundled manifests don't change
+var manifestImagesCache = struct {
+	sync.Mutex
+	images map[string][]string
+}{images: map[string][]string{}}
+
+// listManifestImages returns the images the manifest of the named CNI deploys, pulled from mirror if set.
+// Each manifest is only generated and parsed once per mirror.
+func listManifestImages(name string, mirror string) ([]string, error) {
+	if name == "true" {
+		name = "kindnet"
+	}
+	key := name + "@" + mirror
+	manifestImagesCache.Lock()
+	defer manifestImagesCache.Unlock()
+	if imgs, ok := manifestImagesCache.images[key]; ok {
+		return append([]string{}, imgs...), nil
+	}
+
+	cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{ImageRepository: mirror}}
+	var l ImageLister
+	switch name {
+	case "kindnet":
+		l = KindNet{cc: cc}
+	case "calico":
+		l = Calico{cc: cc}
+	case "cilium":
+		l = Cilium{cc: cc}
+	default:
+		return nil, fmt.Errorf("unknown CNI: %q", name)
+	}
+	imgs, err := l.Images()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s images", name)
+	}
+	manifestImagesCache.images[key] = imgs
+	return append([]string{}, imgs...), nil
+}
+
+// podSpec is the part of a pod spec naming the images of its containers
+type podSpec struct {
+	InitContainers []struct {
+		Image string `yaml:"image"`
+	} `yaml:"initContainers"`
+	Containers []struct {
+		Image string `yaml:"image"`
+	} `yaml:"containers"`
+}
+
+// workload is the part of a manifest document holding a pod spec, in the spec of a Pod or
+// the pod template of a Deployment, DaemonSet or other controller
+type workload struct {
+	Kind string `yaml:"kind"`
+	Spec struct {
+		podSpec  `yaml:",inline"`
+		Template struct {
+			Spec podSpec `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+// workloadImages returns the unique images of the containers and init containers of every workload of manifest, in order
+func workloadImages(manifest []byte) ([]string, error) {
+	seen := map[string]bool{}
+	imgs := []string{}
+	d := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var w workload
+		if err := d.Decode(&w); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, "decode manifest")
+		}
+		spec := w.Spec.Template.Spec
+		if w.Kind == "Pod" {
+			spec = w.Spec.podSpec
+		}
+		for _, img := range spec.InitContainers {
+			if img.Image != "" && !seen[img.Image] {
+				seen[img.Image] = true
+				imgs = append(imgs, img.Image)
+			}
+		}
+		for _, img := range spec.Containers {
+			if img.Image != "" && !seen[img.Image] {
+				seen[img.Image] = true
+				imgs = append(imgs, img.Image)
+			}
+		}
+	}
+	return imgs, nil
+}
+
+// assetImages returns the images of the manifest f
+func assetImages(f io.Reader, err error) ([]string, error) {
+	if err != nil {
+		return nil, errors.Wrap(err, "manifest")
+	}
+	manifest, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "read manifest")
+	}
+	return workloadImages(manifest)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+)
+
+func TestWorkloadImages(t *testing.T) {
+	manifest := `---
+kind: ConfigMap
+apiVersion: v1
+data:
+  config: |
+    image: example.com/not-deployed:1.0
+---
+kind: Deployment
+apiVersion: apps/v1
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: init
+          image: example.com/init:1.0
+      containers:
+        - name: app
+          image: "example.com/app:1.0"
+        - name: sidecar
+          image: example.com/init:1.0
+---
+kind: DaemonSet
+apiVersion: apps/v1
+spec:
+  template:
+    spec:
+      containers:
+        - name: agent
+          image: example.com/agent:1.0
+---
+kind: Pod
+apiVersion: v1
+spec:
+  containers:
+    - name: standalone
+      image: example.com/pod:1.0
+---
+`
+	got, err := workloadImages([]byte(manifest))
+	if err != nil {
+		t.Fatalf("workloadImages: %v", err)
+	}
+	want := []string{"example.com/init:1.0", "example.com/app:1.0", "example.com/agent:1.0", "example.com/pod:1.0"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("images mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := workloadImages([]byte("kind: Deployment\nspec: [")); err == nil {
+		t.Errorf("workloadImages of a malformed manifest = nil error, want error")
+	}
+}
+
+func TestCNIImageLister(t *testing.T) {
+	tests := []struct {
+		name string
+		want func(repo string) []string
+	}{
+		{"calico", func(repo string) []string {
+			return []string{images.CalicoBin(repo), images.CalicoFelixDriver(repo), images.CalicoDaemonSet(repo), images.CalicoDeployment(repo)}
+		}},
+		{"kindnet", func(repo string) []string { return []string{images.KindNet(repo)} }},
+		// the cilium manifest doesn't pull from the image repository
+		{"cilium", func(repo string) []string { return []string{images.Cilium(""), images.CiliumOperator("")} }},
+	}
+	for _, tc := range tests {
+		for _, repo := range []string{"", "registry.corp/mirror"} {
+			t.Run(tc.name+"/"+repo, func(t *testing.T) {
+				got, err := images.CNIImages(tc.name, repo)
+				if err != nil {
+					t.Fatalf("CNIImages: %v", err)
+				}
+				if diff := cmp.Diff(tc.want(repo), got); diff != "" {
+					t.Errorf("CNIImages mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+
+	if _, err := images.CNIImages("weave", ""); err == nil {
+		t.Errorf("CNIImages of an unknown CNI succeeded, want an error")
+	}
+}
+
+func TestCalicoImages(t *testing.T) {
+	c := Calico{cc: clusterConfig("")}
+	got, err := c.Images()
+	if err != nil {
+		t.Fatalf("Images: %v", err)
+	}
+	// the cni and flexvol images are of init containers of the calico-node DaemonSet
+	want := []string{images.CalicoBin(""), images.CalicoFelixDriver(""), images.CalicoDaemonSet(""), images.CalicoDeployment("")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("images mismatch (-want +got):\n%s", diff)
+	}
+
+	f, err := c.manifest()
+	if err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	manifest, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	sort.Strings(got)
+	if diff := cmp.Diff(manifestImages(manifest), got); diff != "" {
+		t.Errorf("images mismatch with every image: line of the manifest (-manifest +Images):\n%s", diff)
+	}
+}
+
+func TestManifestImagesCache(t *testing.T) {
+	want, err := listManifestImages("calico", "registry.corp/cached")
+	if err != nil {
+		t.Fatalf("listManifestImages: %v", err)
+	}
+	manifestImagesCache.Lock()
+	cached, ok := manifestImagesCache.images["calico@registry.corp/cached"]
+	manifestImagesCache.images["calico@registry.corp/cached"] = []string{"registry.corp/cached/from-cache:1.0"}
+	manifestImagesCache.Unlock()
+	defer func() {
+		manifestImagesCache.Lock()
+		delete(manifestImagesCache.images, "calico@registry.corp/cached")
+		manifestImagesCache.Unlock()
+	}()
+	if !ok {
+		t.Fatalf("calico images weren't cached")
+	}
+	if diff := cmp.Diff(want, cached); diff != "" {
+		t.Errorf("cached images mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := images.CNIImages("calico", "registry.corp/cached")
+	if err != nil {
+		t.Fatalf("CNIImages: %v", err)
+	}
+	if diff := cmp.Diff([]string{"registry.corp/cached/from-cache:1.0"}, got); diff != "" {
+		t.Errorf("CNIImages parsed the manifest again instead of using the cache (-want +got):\n%s", diff)
+	}
+}
@@ -176,6 +176,11 @@ func (c KindNet) manifest() (assets.CopyableFile, error) {
 	return manifestAsset(b.Bytes()), nil
 }
 
+// Images returns the images of the kindnet manifest
+func (c KindNet) Images() ([]string, error) {
+	return assetImages(c.manifest())
+}
+
 // Apply enables the CNI
 func (c KindNet) Apply(r Runner) error {
 	// This is mostly applicable to the 'none' driver
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -46,6 +47,10 @@ const (
 	UserFlag = "user"
 	// Rootless is the key for the global rootless parameter (boolean)
 	Rootless = "rootless"
+	// Offline is the key for the global offline parameter (boolean)
+	Offline = "offline"
+	// PinnedVersionsOnly is the key for the global pinned-versions-only parameter (boolean)
+	PinnedVersionsOnly = "pinned-versions-only"
 	// AddonImages stores custom addon images config
 	AddonImages = "addon-images"
 	// AddonRegistries stores custom addon images config
@@ -235,6 +240,22 @@ func (c *simpleConfigLoader) WriteConfigToFile(profileName string, cc *ClusterCo
 	return os.WriteFile(path, contents, 0644)
 }
 
+// SkipsKubeProxy returns whether kubeadm was configured to not deploy kube-proxy
+func SkipsKubeProxy(cc ClusterConfig) bool {
+	for _, phase := range strings.Split(cc.KubernetesConfig.ExtraOptions.Get("skip-phases", "kubeadm"), ",") {
+		if strings.TrimSpace(phase) == "addon/kube-proxy" {
+			return true
+		}
+	}
+	return false
+}
+
+// AddonDisabled returns whether addon was explicitly disabled, rather than left to its default
+func AddonDisabled(cc ClusterConfig, addon string) bool {
+	enabled, ok := cc.Addons[addon]
+	return ok && !enabled
+}
+
 // MultiNode returns true if the cluster has multiple nodes or if the request is asking for multinode
 func MultiNode(cc ClusterConfig) bool {
 	if len(cc.Nodes) > 1 {
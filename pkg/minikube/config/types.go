@@ -79,6 +79,7 @@ type ClusterConfig struct {
 	CustomAddonImages       map[string]string // Maps image names to the image to use for addons. e.g. Dashboard -> k8s.gcr.io/echoserver:1.4 makes dashboard addon use echoserver for its Dashboard deployment.
 	CustomAddonRegistries   map[string]string // Maps image names to the registry to use for addons. See CustomAddonImages for example.
 	VerifyComponents        map[string]bool   // map of components to verify and wait for after start.
+	ImageDigests            map[string]string // Maps image repositories to the digest their image is pinned to, e.g. k8s.gcr.io/etcd -> sha256:...
 	StartHostTimeout        time.Duration
 	ScheduledStop           *ScheduledStopConfig
 	ExposedPorts            []string // Only used by the docker and podman driver
@@ -98,7 +99,22 @@ type ClusterConfig struct {
 	MountPort               uint16
 	MountType               string
 	MountUID                string
-	BinaryMirror            string // Mirror location for kube binaries (kubectl, kubelet, & kubeadm)
+	BinaryMirror            string        // Mirror location for kube binaries (kubectl, kubelet, & kubeadm)
+	RegistryClientCert      string        // Client certificate presented to the image repository when looking up image tags
+	RegistryClientKey       string        // Key of RegistryClientCert
+	RegistryCACert          string        // CA bundle trusted when looking up image tags
+	RegistryDockerConfig    string        // Docker config file holding the credentials of the image repository, empty uses ~/.docker/config.json
+	StorageProvisionerImage string        // Replaces the storage-provisioner image, including its repository
+	RegistryTimeout         time.Duration // Bounds every image version lookup in the image repository, zero uses the default
+	RegistryRateLimit       float64       // Requests per second sent to each registry host when looking up and checking images, zero uses the default
+	ImageTagCacheTTL        time.Duration // How long looked up image tags are cached for, zero uses the default and a negative TTL disables the cache
+	PreReleaseImageTags     bool          // Allows pre-release tags, such as v1.9.0-beta.1, to be picked as the latest image versions
+	PinnedImageVersions     bool          // Never looks up the latest image versions for this profile, as the pinned-versions-only config does for every profile
+	StrictImages            bool          // Fails start if a required image isn't pinned to a version
+	ImageCompression        string        // Layer compression, gzip or zstd, the image manifests are requested for when checking and caching images. Empty lets the registry choose
+	ImagesFromFile          []string      // Replaces every image minikube computes for the cluster, as listed by --images-from-file
+	AllowedRegistries       []string      // The only registries required images may be hosted on, empty allows every registry
+	DeniedRegistries        []string      // Registries required images may never be hosted on, even if allowed
 	DisableOptimizations    bool
 	DisableMetrics          bool
 	CustomQemuFirmwarePath  string
@@ -106,24 +122,30 @@ type ClusterConfig struct {
 
 // KubernetesConfig contains the parameters used to configure the VM Kubernetes.
 type KubernetesConfig struct {
-	KubernetesVersion   string
-	ClusterName         string
-	Namespace           string
-	APIServerName       string
-	APIServerNames      []string
-	APIServerIPs        []net.IP
-	DNSDomain           string
-	ContainerRuntime    string
-	CRISocket           string
-	NetworkPlugin       string
-	FeatureGates        string // https://kubernetes.io/docs/reference/command-line-tools-reference/feature-gates/
-	ServiceCIDR         string // the subnet which Kubernetes services will be deployed to
-	ImageRepository     string
-	LoadBalancerStartIP string // currently only used by MetalLB addon
-	LoadBalancerEndIP   string // currently only used by MetalLB addon
-	CustomIngressCert   string // used by Ingress addon
-	RegistryAliases     string // currently only used by registry-aliases addon
-	ExtraOptions        ExtraOptionSlice
+	KubernetesVersion    string
+	ClusterName          string
+	Namespace            string
+	APIServerName        string
+	APIServerNames       []string
+	APIServerIPs         []net.IP
+	DNSDomain            string
+	ContainerRuntime     string
+	CRISocket            string
+	NetworkPlugin        string
+	FeatureGates         string // https://kubernetes.io/docs/reference/command-line-tools-reference/feature-gates/
+	ServiceCIDR          string // the subnet which Kubernetes services will be deployed to
+	ImageRepository      string
+	PauseImageVersion    string // overrides the pause image tag derived from KubernetesVersion
+	PauseImageRepository string // overrides the repository of the pause image, whatever ImageRepository
+	EtcdVersion          string // overrides the etcd image tag derived from KubernetesVersion
+	CoreDNSVersion       string // overrides the coredns image tag derived from KubernetesVersion
+	CoreDNSFlatPath      bool   // pulls coredns from <ImageRepository>/coredns, for repositories without nested paths
+	CoreDNSLatestPatch   bool   // pulls the latest coredns patch release of the minor version derived from KubernetesVersion
+	LoadBalancerStartIP  string // currently only used by MetalLB addon
+	LoadBalancerEndIP    string // currently only used by MetalLB addon
+	CustomIngressCert    string // used by Ingress addon
+	RegistryAliases      string // currently only used by registry-aliases addon
+	ExtraOptions         ExtraOptionSlice
 
 	ShouldLoadCachedImages bool
 
@@ -405,7 +405,7 @@ func (r *CRIO) Preload(cc config.ClusterConfig) error {
 	cRuntime := cc.KubernetesConfig.ContainerRuntime
 
 	// If images already exist, return
-	images, err := images.Kubeadm(cc.KubernetesConfig.ImageRepository, k8sVersion)
+	images, err := images.KubeadmForCluster(cc, k8sVersion)
 	if err != nil {
 		return errors.Wrap(err, "getting images")
 	}
@@ -533,7 +533,7 @@ func (r *Docker) Preload(cc config.ClusterConfig) error {
 	cRuntime := cc.KubernetesConfig.ContainerRuntime
 
 	// If images already exist, return
-	images, err := images.Kubeadm(cc.KubernetesConfig.ImageRepository, k8sVersion)
+	images, err := images.KubeadmForCluster(cc, k8sVersion)
 	if err != nil {
 		return errors.Wrap(err, "getting images")
 	}
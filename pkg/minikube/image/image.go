@@ -36,6 +36,7 @@ import (
 
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/detect"
 	"k8s.io/minikube/pkg/minikube/localpath"
 )
@@ -106,10 +107,13 @@ func DigestByGoLib(imgName string) string {
 // eg image:tag@sha256:digest -> image:tag if there is an associated tag
 // if not possible, just return the initial img
 func Tag(img string) string {
-	split := strings.Split(img, ":")
-	if len(split) == 3 {
-		tag := strings.Split(split[1], "@")[0]
-		return fmt.Sprintf("%s:%s", split[0], tag)
+	i := strings.Index(img, "@")
+	if i < 0 {
+		return img
+	}
+	// the tag follows the last path component, a colon before it is a registry port
+	if repo := img[:i]; strings.LastIndex(repo, ":") > strings.LastIndex(repo, "/") {
+		return repo
 	}
 	return img
 }
@@ -138,7 +142,7 @@ func retrieveImage(ref name.Reference, imgName string) (v1.Image, string, error)
 		// lookup unqualified short names
 		if !local && !canonical && useRemote {
 			klog.Infof("checking repository: %+v", ref.Context())
-			_, err := remote.Head(ref)
+			_, err := remote.Head(ref, remote.WithTransport(images.PullTransport()))
 			if err == nil {
 				imgName = canonicalName(ref)
 				klog.Infof("canonical name: %s", imgName)
@@ -182,13 +186,13 @@ func retrieveDaemon(ref name.Reference) (v1.Image, error) {
 }
 
 func retrieveRemote(ref name.Reference, p v1.Platform) (v1.Image, error) {
-	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithPlatform(p))
+	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithPlatform(p), remote.WithTransport(images.PullTransport()))
 	if err == nil {
 		return img, nil
 	}
 
 	klog.Warningf("authn lookup for %+v (trying anon): %+v", ref, err)
-	img, err = remote.Image(ref, remote.WithPlatform(p))
+	img, err = remote.Image(ref, remote.WithPlatform(p), remote.WithTransport(images.PullTransport()))
 	// reference does not exist in the remote registry
 	if err != nil {
 		klog.Infof("remote lookup for %+v: %v", ref, err)
@@ -16,7 +16,20 @@ limitations under the License.
 
 package image
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+)
 
 func TestTag(t *testing.T) {
 	tcs := []struct {
@@ -35,6 +48,15 @@ func TestTag(t *testing.T) {
 		}, {
 			image:    "image",
 			expected: "image",
+		}, {
+			image:    "registry.corp:5000/image:tag@sha256:digest",
+			expected: "registry.corp:5000/image:tag",
+		}, {
+			image:    "registry.corp:5000/image:tag",
+			expected: "registry.corp:5000/image:tag",
+		}, {
+			image:    "registry.corp:5000/image@sha256:digest",
+			expected: "registry.corp:5000/image@sha256:digest",
 		},
 	}
 	for _, tc := range tcs {
@@ -91,3 +113,55 @@ func TestNormalizeImageName(t *testing.T) {
 		})
 	}
 }
+
+func TestRetrieveRemoteCompressionPreference(t *testing.T) {
+	var mu sync.Mutex
+	accepts := []string{}
+	handler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/manifests/") {
+			mu.Lock()
+			accepts = append(accepts, r.Header.Get("Accept"))
+			mu.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	ref, err := name.ParseReference(strings.TrimPrefix(server.URL, "http://") + "/pause:3.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("remote.Write: %v", err)
+	}
+
+	if err := images.SetCompression(images.CompressionZstd); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := images.SetCompression(""); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	mu.Lock()
+	accepts = []string{}
+	mu.Unlock()
+	if _, err := retrieveRemote(ref, defaultPlatform); err != nil {
+		t.Fatalf("retrieveRemote: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(accepts) == 0 {
+		t.Fatalf("no manifest request was sent")
+	}
+	for _, accept := range accepts {
+		if !strings.HasPrefix(accept, "application/vnd.oci.image.index.v1+json, ") {
+			t.Errorf("manifest Accept = %q, want the OCI media types first", accept)
+		}
+	}
+}
@@ -74,6 +74,11 @@ func AuditLog() string {
 	return filepath.Join(MiniPath(), "logs", "audit.json")
 }
 
+// ImageTraceLog returns the path to the image resolution trace, written by starts run with --trace-images.
+func ImageTraceLog() string {
+	return filepath.Join(MiniPath(), "logs", "imageTrace.json")
+}
+
 // LastStartLog returns the path to the last start log.
 func LastStartLog() string {
 	return filepath.Join(MiniPath(), "logs", "lastStart.txt")
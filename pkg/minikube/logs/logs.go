@@ -246,6 +246,22 @@ func outputLastStart() error {
 	return nil
 }
 
+// outputImageTrace outputs the image resolution trace of the last start run with --trace-images, if any.
+func outputImageTrace() error {
+	fp := localpath.ImageTraceLog()
+	data, err := os.ReadFile(fp)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %v", fp, err)
+	}
+	out.Styled(style.Empty, "")
+	out.Styled(style.Empty, "==> Image Trace <==")
+	out.Styled(style.Empty, string(data))
+	return nil
+}
+
 // OutputOffline outputs logs that don't need a running cluster.
 func OutputOffline(lines int, logOutput *os.File) {
 	out.SetOutFile(logOutput)
@@ -258,6 +274,9 @@ func OutputOffline(lines int, logOutput *os.File) {
 	if err := outputLastStart(); err != nil {
 		klog.Errorf("failed to output last start logs: %v", err)
 	}
+	if err := outputImageTrace(); err != nil {
+		klog.Errorf("failed to output the image trace: %v", err)
+	}
 
 	out.Styled(style.Empty, "")
 }
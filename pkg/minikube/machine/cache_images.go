@@ -59,8 +59,8 @@ var loadImageLock sync.Mutex
 var saveRoot = path.Join(vmpath.GuestPersistentDir, "images")
 
 // CacheImagesForBootstrapper will cache images for a bootstrapper
-func CacheImagesForBootstrapper(imageRepository string, version string, clusterBootstrapper string) error {
-	images, err := bootstrapper.GetCachedImageList(imageRepository, version, clusterBootstrapper)
+func CacheImagesForBootstrapper(cc config.ClusterConfig, version string, clusterBootstrapper string) error {
+	images, err := bootstrapper.GetCachedImageList(cc, version, clusterBootstrapper)
 	if err != nil {
 		return errors.Wrap(err, "cached images list")
 	}
@@ -213,6 +213,10 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 	klog.Infof("waiting for startup goroutines ...")
 	wg.Wait()
 
+	if apiServer {
+		recordStartedImages(*starter.Cfg, starter.Node.KubernetesVersion)
+	}
+
 	// Write enabled addons to the config before completion
 	return kcs, config.Write(viper.GetString(config.ProfileName), starter.Cfg)
 }
@@ -340,12 +344,27 @@ func Provision(cc *config.ClusterConfig, n *config.Node, apiServer bool, delOnFa
 
 	}
 
+	ConfigureImages(*cc)
+	logImageRewrites(*cc, n.KubernetesVersion)
+	if cc.StrictImages && cc.KubernetesConfig.KubernetesVersion != constants.NoKubernetesVersion {
+		// checked ahead of the preload, which would otherwise cache the images before kubeadm lists them
+		if _, err := images.KubeadmForCluster(*cc, n.KubernetesVersion); err != nil {
+			exit.Error(reason.Usage, "Unable to verify the required images are pinned to a version", err)
+		}
+	}
+	if len(cc.AllowedRegistries)+len(cc.DeniedRegistries) > 0 && cc.KubernetesConfig.KubernetesVersion != constants.NoKubernetesVersion {
+		// checked ahead of the preload too, so that no image is pulled from a registry the policy rejects
+		if _, err := images.KubeadmForCluster(*cc, n.KubernetesVersion); err != nil {
+			exit.Error(reason.Usage, "Unable to verify the required images are hosted on allowed registries", err)
+		}
+	}
+
 	if driver.IsKIC(cc.Driver) {
 		beginDownloadKicBaseImage(&kicGroup, cc, viper.GetBool("download-only"))
 	}
 
 	if !driver.BareMetal(cc.Driver) {
-		beginCacheKubernetesImages(&cacheGroup, cc.KubernetesConfig.ImageRepository, n.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver)
+		beginCacheKubernetesImages(&cacheGroup, *cc, n.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver)
 	}
 
 	// Abstraction leakage alert: startHost requires the config to be saved, to satistfy pkg/provision/buildroot.
@@ -360,6 +379,53 @@ func Provision(cc *config.ClusterConfig, n *config.Node, apiServer bool, delOnFa
 	return startMachine(cc, n, delOnFail)
 }
 
+// ConfigureImages applies the image overrides stored in the cluster config
+func ConfigureImages(cc config.ClusterConfig) {
+	if err := images.Configure(cc); err != nil {
+		exit.Error(reason.Usage, "Invalid image configuration", err)
+	}
+}
+
+// recordStartedImages keeps the image tags the cluster started with, which later starts fall back on when the latest tags can't be looked up
+func recordStartedImages(cc config.ClusterConfig, k8sVersion string) {
+	v, err := util.ParseKubernetesVersion(k8sVersion)
+	if err != nil {
+		klog.Warningf("unable to parse the Kubernetes version the cluster started with: %v", err)
+		return
+	}
+	imgs, err := images.Kubeadm(cc.KubernetesConfig.ImageRepository, k8sVersion)
+	if err != nil {
+		klog.Warningf("unable to list the images the cluster started with: %v", err)
+		return
+	}
+	if err := images.RecordStartedImages(v, imgs); err != nil {
+		klog.Warningf("unable to record the images the cluster started with: %v", err)
+	}
+}
+
+// logImageRewrites logs the canonical name of the images of the cluster a mirror rewrote, to match them with the upstream docs
+func logImageRewrites(cc config.ClusterConfig, k8sVersion string) {
+	mirror := cc.KubernetesConfig.ImageRepository
+	if !klog.V(2).Enabled() || mirror == "" || k8sVersion == constants.NoKubernetesVersion {
+		return
+	}
+	v, err := util.ParseKubernetesVersion(k8sVersion)
+	if err != nil {
+		klog.Warningf("unable to list image rewrites: %v", err)
+		return
+	}
+	imgs, err := images.Kubeadm(mirror, k8sVersion)
+	if err != nil {
+		klog.Warningf("unable to list image rewrites: %v", err)
+		return
+	}
+	for _, img := range imgs {
+		if canonical := images.CanonicalName(img, mirror, v); canonical != img {
+			klog.Infof("rewrote %s -> %s", canonical, img)
+		}
+	}
+}
+
 // ConfigureRuntimes does what needs to happen to get a runtime going.
 func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, kv semver.Version) cruntime.Manager {
 	co := cruntime.Config{
@@ -391,7 +457,7 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 				klog.Warningf("%s preload failed: %v, falling back to caching images", cr.Name(), err)
 			}
 
-			if err := machine.CacheImagesForBootstrapper(cc.KubernetesConfig.ImageRepository, cc.KubernetesConfig.KubernetesVersion, viper.GetString(cmdcfg.Bootstrapper)); err != nil {
+			if err := machine.CacheImagesForBootstrapper(cc, cc.KubernetesConfig.KubernetesVersion, viper.GetString(cmdcfg.Bootstrapper)); err != nil {
 				exit.Error(reason.RuntimeCache, "Failed to cache images", err)
 			}
 		}